@@ -0,0 +1,162 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlchemy
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/nyl1001/pkg/errors"
+	"github.com/nyl1001/pkg/jsonutils"
+	"github.com/nyl1001/pkg/util/reflectutils"
+)
+
+const (
+	// AUDIT_ACTION_INSERT is the action of the audit log of an insert
+	AUDIT_ACTION_INSERT = "insert"
+	// AUDIT_ACTION_UPDATE is the action of the audit log of an update
+	AUDIT_ACTION_UPDATE = "update"
+	// AUDIT_ACTION_DELETE is the action of the audit log of a delete
+	AUDIT_ACTION_DELETE = "delete"
+)
+
+// SAuditLog is the model of the history table that records the changes of the audited tables
+type SAuditLog struct {
+	Id int64 `primary:"true" auto_increment:"true"`
+
+	// TableName is the name of the audited table
+	TableName string `width:"64" index:"true"`
+	// RecordId is the primary key values of the record, joined by comma for a composite primary key
+	RecordId string `width:"128" index:"true"`
+	// Action is one of insert, update and delete
+	Action string `width:"16"`
+	// Actor is the actor carried by the context of the operation
+	Actor string `width:"128" nullable:"true"`
+	// Data is the JSON of the record for insert and delete, or the JSON of the UpdateDiffs for update
+	Data string `nullable:"true"`
+
+	CreatedAt time.Time `created_at:"true"`
+}
+
+// GetData returns the parsed JSON of Data
+func (l *SAuditLog) GetData() (jsonutils.JSONObject, error) {
+	return jsonutils.ParseString(l.Data)
+}
+
+// NewAuditTableSpec returns the spec of a history table of SAuditLog, which should be synchronized by Sync
+func NewAuditTableSpec(name string) *STableSpec {
+	return NewTableSpecFromStruct(SAuditLog{}, name)
+}
+
+// sAuditActorContextKey is the key of the context value that carries the actor of the audit logs
+type sAuditActorContextKey struct{}
+
+// WithAuditActor returns a context that carries the actor recorded in the audit logs
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, sAuditActorContextKey{}, actor)
+}
+
+// AuditActorFromContext returns the actor carried by the context, empty if none
+func AuditActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(sAuditActorContextKey{}).(string); ok {
+		return actor
+	}
+	return ""
+}
+
+// SAuditHook is the table hook that records the inserts, updates and deletes into the history table
+type SAuditHook struct {
+	SBaseTableHook
+
+	logs *STableSpec
+}
+
+// EnableAudit records the changes of the table into the history table logs,
+// which is created by NewAuditTableSpec and may be shared by several tables.
+// InsertOrUpdate and the bulk and field-level writes, which are not audited, fail with ErrNotSupported on an audited table, see AddHook
+func (ts *STableSpec) EnableAudit(logs *STableSpec) {
+	ts.AddHook(&SAuditHook{logs: logs})
+}
+
+func (h *SAuditHook) AfterInsert(ctx context.Context, ts *STableSpec, dt interface{}) error {
+	return h.log(ctx, ts, dt, AUDIT_ACTION_INSERT, jsonutils.Marshal(dt).String())
+}
+
+func (h *SAuditHook) AfterUpdate(ctx context.Context, ts *STableSpec, dt interface{}, diffs UpdateDiffs) error {
+	return h.log(ctx, ts, dt, AUDIT_ACTION_UPDATE, diffs.String())
+}
+
+func (h *SAuditHook) AfterDelete(ctx context.Context, ts *STableSpec, dt interface{}) error {
+	return h.log(ctx, ts, dt, AUDIT_ACTION_DELETE, jsonutils.Marshal(dt).String())
+}
+
+func (h *SAuditHook) log(ctx context.Context, ts *STableSpec, dt interface{}, action string, data string) error {
+	entry := &SAuditLog{
+		TableName: ts.Name(),
+		RecordId:  ts.recordId(dt),
+		Action:    action,
+		Actor:     AuditActorFromContext(ctx),
+		Data:      data,
+	}
+	err := h.logs.InsertContext(ctx, entry)
+	if err != nil {
+		return errors.Wrapf(err, "insert audit log of %s", ts.Name())
+	}
+	return nil
+}
+
+// recordId returns the primary key values of dt joined by comma
+func (ts *STableSpec) recordId(dt interface{}) string {
+	fields := reflectutils.FetchStructFieldValueSet(reflect.Indirect(reflect.ValueOf(dt)))
+	keys := make([]string, 0)
+	for _, c := range ts.PrimaryColumns() {
+		v, _ := fields.GetInterface(c.Name())
+		keys = append(keys, GetStringValue(v))
+	}
+	return strings.Join(keys, ",")
+}
+
+// auditLogs returns the history table of the table, nil if the table is not audited
+func (ts *STableSpec) auditLogs() *STableSpec {
+	for _, hook := range ts.hooks {
+		if h, ok := hook.(*SAuditHook); ok {
+			return h.logs
+		}
+	}
+	return nil
+}
+
+// AuditHistory returns the audit logs of the record whose primary key values have been set in dt,
+// in the order of the changes
+func (ts *STableSpec) AuditHistory(dt interface{}) ([]SAuditLog, error) {
+	return ts.AuditHistoryContext(context.Background(), dt)
+}
+
+// AuditHistoryContext is the context-aware version of AuditHistory
+func (ts *STableSpec) AuditHistoryContext(ctx context.Context, dt interface{}) ([]SAuditLog, error) {
+	logs := ts.auditLogs()
+	if logs == nil {
+		return nil, errors.Wrapf(errors.ErrNotFound, "table %s is not audited", ts.Name())
+	}
+	q := logs.Query().WithContext(ctx).Equals("table_name", ts.Name()).Equals("record_id", ts.recordId(dt)).Asc("id")
+	ret := make([]SAuditLog, 0)
+	err := q.All(&ret)
+	if err != nil {
+		return nil, errors.Wrap(err, "query audit logs")
+	}
+	return ret, nil
+}
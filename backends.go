@@ -27,7 +27,8 @@ const (
 	ClickhouseBackend = DBBackendName("Clickhouse")
 	// SQLiteBackend is the backend name of Sqlite3
 	SQLiteBackend = DBBackendName("SQLite")
-	// PostgreSQLBackend = DBBackendName("PostgreSQL")
+	// PostgreSQLBackend is the backend name of PostgreSQL
+	PostgreSQLBackend = DBBackendName("PostgreSQL")
 )
 
 // IBackend is the interface for all kinds of sql backends, e.g. MySQL, ClickHouse, Sqlite, PostgreSQL, etc.
@@ -81,6 +82,23 @@ type IBackend interface {
 	UpdateSQLTemplate() string
 	// InsertOrUpdateSQLTemplate returns the template of insert or update SQL
 	InsertOrUpdateSQLTemplate() string
+	// InsertOrUpdateFromQuerySQLTemplate returns the template of insert or update SQL whose rows come from a query,
+	// empty if the backend does not support it
+	InsertOrUpdateFromQuerySQLTemplate() string
+	// InsertOrIgnoreSQLTemplate returns the template of insert SQL which skips the rows with duplicate primary keys,
+	// empty if the backend does not support it
+	InsertOrIgnoreSQLTemplate() string
+	// InsertOrIgnoreFromQuerySQLTemplate returns the template of insert SQL whose rows come from a query, which skips
+	// the rows with duplicate primary keys, empty if the backend does not support it
+	InsertOrIgnoreFromQuerySQLTemplate() string
+	// DeleteSQLTemplate returns the template of delete SQL
+	DeleteSQLTemplate() string
+	// InsertOrUpdateValueRef returns the reference to the value proposed for insertion of a column,
+	// which is used in the update clause of a multi-row insert or update SQL
+	//     MySQL: VALUES(`name`)
+	//     Sqlite: excluded.`name`
+	//     PostgreSQL: excluded."name"
+	InsertOrUpdateValueRef(name string) string
 
 	// CanSupportRowAffected returns wether the backend support RowAffected method after update
 	//     MySQL: true
@@ -88,6 +106,68 @@ type IBackend interface {
 	//     Clickhouse: false
 	CanSupportRowAffected() bool
 
+	// CanSupportReturning returns wether the backend supports INSERT ... RETURNING to read back the inserted row
+	//     MySQL: false
+	//     Sqlite: false
+	//     Clickhouse: false
+	//     PostgreSQL: true
+	CanSupportReturning() bool
+
+	// QuoteIdentifier quotes the name of a table, column or alias, the embedded quote characters are escaped
+	//     MySQL, Sqlite, Clickhouse: `name`
+	//     PostgreSQL: "name"
+	QuoteIdentifier(name string) string
+
+	// Placeholder returns the placeholder of the index-th variable of a SQL statement, index starts from 1
+	//     MySQL, Sqlite, Clickhouse: ?
+	//     PostgreSQL: $1
+	//     SQL Server: @p1
+	Placeholder(index int) string
+
+	// LockingClause returns the row locking clause appended to a SELECT statement, e.g. FOR UPDATE SKIP LOCKED,
+	// ErrNotSupported is returned if the backend does not support row locking
+	//     MySQL(8.0+), PostgreSQL: supported
+	//     Sqlite, Clickhouse: not supported
+	LockingClause(lockType QueryLockType, waitType QueryLockWaitType) (string, error)
+
+	// CanSupportRecursiveCTE returns whether the backend supports the recursive common table expression, i.e. WITH RECURSIVE
+	//     MySQL(8.0+), PostgreSQL, Sqlite: true
+	//     Clickhouse: false
+	CanSupportRecursiveCTE() bool
+
+	// BooleanLiteral returns the constant condition of the boolean value
+	//     MySQL, Sqlite, Clickhouse: 1, 0
+	//     PostgreSQL: true, false
+	BooleanLiteral(v bool) string
+
+	// MaxInsertPlaceholders returns the max number of placeholders of a multi-row INSERT statement,
+	// 0 means the rows are inserted one by one with a prepared statement, e.g. as a native block insert
+	//     Sqlite: 999
+	//     MySQL, PostgreSQL: 65535
+	//     Clickhouse: 0
+	MaxInsertPlaceholders() int
+
+	// MaxInsertPacketSize returns the max size in bytes of a multi-row INSERT statement with its values, 0 means unlimited
+	//     MySQL: max_allowed_packet, 4MB by default, which is overridden by SetMaxInsertPacketSize of the database
+	MaxInsertPacketSize() int
+
+	// UpdateJoinStyle returns the syntax of an update with joined tables
+	//     MySQL: UPDATE_JOIN_ON
+	//     Sqlite(3.33+), PostgreSQL: UPDATE_FROM
+	//     Clickhouse: UPDATE_JOIN_NOT_SUPPORTED
+	UpdateJoinStyle() UpdateJoinStyle
+
+	// CanSupportRowValueIn returns wether the backend supports the row value IN condition, e.g. (a, b) IN ((?, ?), (?, ?)),
+	// and the row value comparison, e.g. (a, b) > (?, ?), otherwise the condition is expanded to OR of ANDs
+	//     MySQL, PostgreSQL, Sqlite(3.15+), Clickhouse: true
+	CanSupportRowValueIn() bool
+
+	// IsNullsFirst returns whether the NULLs are sorted before the other values in the order
+	//     MySQL, Sqlite: true for ASC, false for DESC
+	//     PostgreSQL: false for ASC, true for DESC
+	//     Clickhouse: false
+	IsNullsFirst(order QueryOrderType) bool
+
 	// CommitTableChangeSQL outputs the SQLs to alter a table
 	CommitTableChangeSQL(ts ITableSpec, changes STableChanges) []string
 
@@ -133,6 +213,21 @@ type IBackend interface {
 	UPPER(name string, field IQueryField) IQueryField
 	// DATEDIFF
 	DATEDIFF(unit string, field1, field2 IQueryField) IQueryField
+
+	// ROW_NUMBER
+	ROW_NUMBER(name string, over *SWindow) IQueryField
+	// RANK
+	RANK(name string, over *SWindow) IQueryField
+	// DENSE_RANK
+	DENSE_RANK(name string, over *SWindow) IQueryField
+	// LAG
+	LAG(name string, field IQueryField, offset int, over *SWindow) IQueryField
+	// LEAD
+	LEAD(name string, field IQueryField, offset int, over *SWindow) IQueryField
+	// FIRST_VALUE
+	FIRST_VALUE(name string, field IQueryField, over *SWindow) IQueryField
+	// SUM_OVER
+	SUM_OVER(name string, field IQueryField, over *SWindow) IQueryField
 }
 
 var _driver_tbl = make(map[DBBackendName]IBackend)
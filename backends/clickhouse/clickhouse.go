@@ -100,8 +100,33 @@ func (click *SClickhouseBackend) SupportMixedInsertVariables() bool {
 	return false
 }
 
+// MaxInsertPlaceholders returns 0, rows are inserted with a prepared statement in a transaction,
+// which is sent to the server as a native block insert
+func (click *SClickhouseBackend) MaxInsertPlaceholders() int {
+	return 0
+}
+
+// CanSupportRowValueIn returns true, i.e. (a, b) IN ((?, ?), (?, ?)) of tuples
+func (click *SClickhouseBackend) CanSupportRowValueIn() bool {
+	return true
+}
+
+// CanSupportRecursiveCTE returns false, clickhouse does not support WITH RECURSIVE
+func (click *SClickhouseBackend) CanSupportRecursiveCTE() bool {
+	return false
+}
+
+// IsNullsFirst returns false, the NULLs are sorted last in both orders by default
+func (click *SClickhouseBackend) IsNullsFirst(order sqlchemy.QueryOrderType) bool {
+	return false
+}
+
 func (click *SClickhouseBackend) UpdateSQLTemplate() string {
-	return "ALTER TABLE `{{ .Table }}` UPDATE {{ .Columns }} WHERE {{ .Conditions }}"
+	return "ALTER TABLE {{ .Table }} UPDATE {{ .Columns }} WHERE {{ .Conditions }}"
+}
+
+func (click *SClickhouseBackend) DeleteSQLTemplate() string {
+	return "ALTER TABLE {{ .Table }} DELETE WHERE {{ .Conditions }}"
 }
 
 func MySQLExtraOptions(hostport, database, table, user, passwd string) sqlchemy.TableExtraOptions {
@@ -24,3 +24,26 @@ import (
 func (click *SClickhouseBackend) GROUP_CONCAT2(name string, sep string, field sqlchemy.IQueryField) sqlchemy.IQueryField {
 	return sqlchemy.NewFunctionField(name, fmt.Sprintf("arrayStringConcat(groupUniqArray(%%s), '%s')", sep), field)
 }
+
+// clickhouse has no LAG/LEAD, lagInFrame/leadInFrame respect the frame, so the whole partition is used as frame by default
+const windowWholePartitionFrame = "ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING"
+
+func wholePartitionWindow(over *sqlchemy.SWindow) *sqlchemy.SWindow {
+	if over == nil {
+		over = sqlchemy.Over(nil, nil, "")
+	}
+	if len(over.Frame()) == 0 {
+		over = over.WithFrame(windowWholePartitionFrame)
+	}
+	return over
+}
+
+// LAG represents the window function lagInFrame
+func (click *SClickhouseBackend) LAG(name string, field sqlchemy.IQueryField, offset int, over *sqlchemy.SWindow) sqlchemy.IQueryField {
+	return sqlchemy.NewWindowFunctionField(name, fmt.Sprintf("lagInFrame(%%s, %d)", offset), wholePartitionWindow(over), field)
+}
+
+// LEAD represents the window function leadInFrame
+func (click *SClickhouseBackend) LEAD(name string, field sqlchemy.IQueryField, offset int, over *sqlchemy.SWindow) sqlchemy.IQueryField {
+	return sqlchemy.NewWindowFunctionField(name, fmt.Sprintf("leadInFrame(%%s, %d)", offset), wholePartitionWindow(over), field)
+}
@@ -91,3 +91,32 @@ func TestInsertWithPointerValue(t *testing.T) {
 	}
 	t.Logf("%s values: %v", sql, vals)
 }
+
+func TestInsertFromQuery(t *testing.T) {
+	sqlchemy.SetDBWithNameBackend(nil, sqlchemy.DefaultDB, sqlchemy.ClickhouseBackend)
+	sqlchemy.ResetTableID()
+
+	type vv struct {
+		RowId int    `primary:"true"`
+		Name  string `width:"24"`
+	}
+	src := sqlchemy.NewTableSpecFromStruct(&vv{}, "src")
+	ts := sqlchemy.NewTableSpecFromStruct(&vv{}, "vv")
+	q := src.Query().GT("row_id", 10)
+	sql, vars, err := ts.InsertFromQuerySql(q, false)
+	if err != nil {
+		t.Fatalf("InsertFromQuerySql fail: %s", err)
+	}
+	wantSQL := "INSERT INTO `vv` (`row_id`, `name`) SELECT `t1`.`row_id`, `t1`.`name` FROM `src` AS `t1` WHERE `t1`.`row_id` >  ? "
+	if sql != wantSQL {
+		t.Errorf("sql want %s got %s", wantSQL, sql)
+	}
+	if len(vars) != 1 {
+		t.Errorf("vars want 1 got %d", len(vars))
+	}
+
+	_, err = ts.InsertOrUpdateFromQuery(q)
+	if errors.Cause(err) != errors.ErrNotSupported {
+		t.Errorf("InsertOrUpdateFromQuery: want %s got %v", errors.ErrNotSupported, err)
+	}
+}
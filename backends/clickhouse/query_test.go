@@ -3,6 +3,8 @@ package clickhouse
 import (
 	"testing"
 
+	"github.com/nyl1001/pkg/errors"
+
 	"github.com/nyl1001/sqlchemy"
 	"github.com/nyl1001/sqlchemy/backends/tests"
 )
@@ -22,4 +24,32 @@ func TestQuery(t *testing.T) {
 		want := "SELECT `t1`.`col0` FROM `test` AS `t1` WHERE match(`t1`.`col1`,  ? )"
 		tests.AssertGotWant(t, q.String(), want)
 	})
+	t.Run("query window function", func(t *testing.T) {
+		tests.BackendTestReset(sqlchemy.ClickhouseBackend)
+		testTable := tests.GetTestTable()
+		over := sqlchemy.Over([]sqlchemy.IQueryField{testTable.Field("col1")}, []sqlchemy.SWindowOrder{sqlchemy.WindowAsc(testTable.Field("col0"))}, "")
+		q := testTable.Query(sqlchemy.ROW_NUMBER("rn", over), sqlchemy.LAG("prev", testTable.Field("col0"), 1, over))
+		want := "SELECT ROW_NUMBER() OVER (PARTITION BY `t1`.`col1` ORDER BY `t1`.`col0` ASC) AS `rn`, lagInFrame(`t1`.`col0`, 1) OVER (PARTITION BY `t1`.`col1` ORDER BY `t1`.`col0` ASC ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING) AS `prev` FROM `test` AS `t1`"
+		tests.AssertGotWant(t, q.String(), want)
+	})
+	t.Run("query cte", func(t *testing.T) {
+		tests.BackendTestReset(sqlchemy.ClickhouseBackend)
+		testTable := tests.GetTestTable()
+		q := testTable.Query(testTable.Field("col0"))
+		cte := q.With("sub", testTable.Query(testTable.Field("col0")).Equals("col1", 1))
+		q = q.Join(cte, sqlchemy.Equals(testTable.Field("col0"), cte.Field("col0")))
+		want := "WITH `sub` AS (SELECT `t1`.`col0` FROM `test` AS `t1` WHERE `t1`.`col1` =  ? ) SELECT `t1`.`col0` FROM `test` AS `t1` JOIN `sub` AS `t2` ON `t1`.`col0` = `t2`.`col0`"
+		tests.AssertGotWant(t, q.String(), want)
+	})
+	t.Run("query recursive cte", func(t *testing.T) {
+		tests.BackendTestReset(sqlchemy.ClickhouseBackend)
+		testTable := tests.GetTestTable()
+		tree := sqlchemy.NewRecursiveCTE("tree", testTable.Query(testTable.Field("col0")), func(self *sqlchemy.SCTE) sqlchemy.IQuery {
+			return testTable.Query(testTable.Field("col0")).Join(self, sqlchemy.Equals(testTable.Field("col1"), self.Field("col0")))
+		})
+		_, err := tree.Query().AllStringMap()
+		if errors.Cause(err) != sqlchemy.ErrNotSupported {
+			t.Errorf("want ErrNotSupported, got %v", err)
+		}
+	})
 }
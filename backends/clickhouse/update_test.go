@@ -133,3 +133,34 @@ func TestUpdatePrimaryKey(t *testing.T) {
 		t.Fatalf("Vars want %d got %d", wantVars, len(result.Vars))
 	}
 }
+
+func TestUpdateDeleteWhereSQL(t *testing.T) {
+	sqlchemy.SetDBWithNameBackend(nil, sqlchemy.DefaultDB, sqlchemy.ClickhouseBackend)
+
+	table := sqlchemy.NewTableSpecFromStruct(TableStruct{}, "testtable")
+	target := table.Target()
+	cond := sqlchemy.LT(target.Field("age"), 10)
+
+	result, err := table.UpdateWhereSql(cond, map[string]interface{}{
+		"name": "john",
+	})
+	if err != nil {
+		t.Fatalf("UpdateWhereSql fail %s", err)
+	}
+	want := "ALTER TABLE `testtable` UPDATE `name` = ?, `updated_at` = NOW('UTC'), `version` = `version` + 1 WHERE `testtable`.`age` <  ? "
+	if want != result.Sql {
+		t.Errorf("SQL: want %s got %s", want, result.Sql)
+	}
+	if len(result.Vars) != 2 {
+		t.Errorf("Vars want 2 got %d", len(result.Vars))
+	}
+
+	sql, vars := table.DeleteWhereSql(cond)
+	want = "ALTER TABLE `testtable` DELETE WHERE `testtable`.`age` <  ? "
+	if want != sql {
+		t.Errorf("SQL: want %s got %s", want, sql)
+	}
+	if len(vars) != 1 {
+		t.Errorf("Vars want 1 got %d", len(vars))
+	}
+}
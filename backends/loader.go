@@ -17,5 +17,6 @@ package backends
 import (
 	_ "github.com/nyl1001/sqlchemy/backends/clickhouse"
 	_ "github.com/nyl1001/sqlchemy/backends/mysql"
+	_ "github.com/nyl1001/sqlchemy/backends/postgres"
 	_ "github.com/nyl1001/sqlchemy/backends/sqlite"
 )
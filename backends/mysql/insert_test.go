@@ -15,6 +15,7 @@
 package mysql
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/nyl1001/pkg/errors"
@@ -103,3 +104,37 @@ func TestInsertWithPointerValue(t *testing.T) {
 	}
 	t.Logf("%s values: %v", sql, vals)
 }
+
+func TestInsertBatchPacketSize(t *testing.T) {
+	sqlchemy.SetDBWithNameBackend(nil, sqlchemy.DefaultDB, sqlchemy.MySQLBackend)
+	ts := sqlchemy.NewTableSpecFromStruct(&struct {
+		RowId int    `primary:"true"`
+		Name  string `width:"1024"`
+	}{}, "vv")
+
+	ts.Database().SetMaxInsertPacketSize(4096)
+
+	name := strings.Repeat("a", 1000)
+	dataList := make([]interface{}, 10)
+	for i := range dataList {
+		dataList[i] = &struct {
+			RowId int    `primary:"true"`
+			Name  string `width:"1024"`
+		}{RowId: i + 1, Name: name}
+	}
+	results := ts.InsertBatchSqlPrep(dataList)
+	if len(results) != 4 {
+		t.Fatalf("want 4 statements got %d", len(results))
+	}
+	for _, result := range results {
+		size := len(result.Sql)
+		for _, v := range result.Values {
+			if s, ok := v.(string); ok {
+				size += len(s)
+			}
+		}
+		if size > ts.Database().MaxInsertPacketSize() {
+			t.Errorf("statement size %d exceeds %d", size, ts.Database().MaxInsertPacketSize())
+		}
+	}
+}
@@ -22,6 +22,7 @@ import (
 
 	_ "github.com/go-sql-driver/mysql"
 
+	"github.com/nyl1001/pkg/errors"
 	"github.com/nyl1001/pkg/gotypes"
 	"github.com/nyl1001/pkg/tristate"
 	"github.com/nyl1001/pkg/util/regutils"
@@ -29,6 +30,35 @@ import (
 	"github.com/nyl1001/sqlchemy"
 )
 
+// DefaultMaxAllowedPacket is the default max_allowed_packet of MySQL 5.7, which limits the size of a multi-row INSERT statement.
+// Call SetMaxInsertPacketSize of the database if the server is configured with another max_allowed_packet
+const DefaultMaxAllowedPacket = 4 * 1024 * 1024
+
+// minLockWaitVersion is the version number of MySQL 8.0, which introduces FOR SHARE, NOWAIT and SKIP LOCKED
+const minLockWaitVersion = 80000
+
+// serverVersion is the version number of the MySQL server, e.g. 80032 for 8.0.32, see SetServerVersion
+var serverVersion = 50700
+
+// SetServerVersion sets the version of the MySQL server, e.g. 8.0.32, which enables the row locking options
+// NOWAIT and SKIP LOCKED of MySQL 8.0. The server is taken as MySQL 5.7 by default
+func SetServerVersion(version string) error {
+	parts := strings.SplitN(strings.SplitN(version, "-", 2)[0], ".", 3)
+	num := 0
+	for i := 0; i < 3; i++ {
+		num *= 100
+		if i < len(parts) {
+			v, err := strconv.Atoi(parts[i])
+			if err != nil {
+				return errors.Wrapf(errors.ErrInvalidFormat, "version %s", version)
+			}
+			num += v
+		}
+	}
+	serverVersion = num
+	return nil
+}
+
 func init() {
 	sqlchemy.RegisterBackend(&SMySQLBackend{})
 }
@@ -56,8 +86,64 @@ func (mysql *SMySQLBackend) CanInsertOrUpdate() bool {
 	return true
 }
 
+// LockingClause returns the row locking clause, e.g. FOR UPDATE NOWAIT. Before MySQL 8.0, i.e. unless SetServerVersion
+// is called with 8.0+, the share lock is LOCK IN SHARE MODE and NOWAIT and SKIP LOCKED are not supported
+func (mysql *SMySQLBackend) LockingClause(lockType sqlchemy.QueryLockType, waitType sqlchemy.QueryLockWaitType) (string, error) {
+	return lockingClause(lockType, waitType, serverVersion)
+}
+
+func lockingClause(lockType sqlchemy.QueryLockType, waitType sqlchemy.QueryLockWaitType, version int) (string, error) {
+	if version < minLockWaitVersion {
+		if len(waitType) > 0 {
+			return "", errors.Wrapf(sqlchemy.ErrNotSupported, "%s before MySQL 8.0", waitType)
+		}
+		if lockType == sqlchemy.SQL_LOCK_FOR_SHARE {
+			return "LOCK IN SHARE MODE", nil
+		}
+	}
+	clause := "FOR " + string(lockType)
+	if len(waitType) > 0 {
+		clause += " " + string(waitType)
+	}
+	return clause, nil
+}
+
+// MaxInsertPlaceholders returns the max number of placeholders of a prepared statement
+func (mysql *SMySQLBackend) MaxInsertPlaceholders() int {
+	return 65535
+}
+
+// MaxInsertPacketSize returns DefaultMaxAllowedPacket, the size of a multi-row INSERT statement is limited by max_allowed_packet
+func (mysql *SMySQLBackend) MaxInsertPacketSize() int {
+	return DefaultMaxAllowedPacket
+}
+
+// UpdateJoinStyle returns UPDATE_JOIN_ON, i.e. UPDATE a JOIN b ON ... SET ...
+func (mysql *SMySQLBackend) UpdateJoinStyle() sqlchemy.UpdateJoinStyle {
+	return sqlchemy.UPDATE_JOIN_ON
+}
+
+// CanSupportRowValueIn returns true, i.e. (a, b) IN ((?, ?), (?, ?))
+func (mysql *SMySQLBackend) CanSupportRowValueIn() bool {
+	return true
+}
+
 func (mysql *SMySQLBackend) InsertOrUpdateSQLTemplate() string {
-	return "INSERT INTO `{{ .Table }}` ({{ .Columns }}) VALUES ({{ .Values }}) ON DUPLICATE KEY UPDATE {{ .SetValues }}"
+	return "INSERT INTO {{ .Table }} ({{ .Columns }}) VALUES ({{ .Values }}) ON DUPLICATE KEY UPDATE {{ .SetValues }}"
+}
+
+// InsertOrIgnoreSQLTemplate returns INSERT IGNORE, which skips the rows with duplicate keys
+func (mysql *SMySQLBackend) InsertOrIgnoreSQLTemplate() string {
+	return "INSERT IGNORE INTO {{ .Table }} ({{ .Columns }}) VALUES ({{ .Values }})"
+}
+
+// InsertOrIgnoreFromQuerySQLTemplate returns INSERT IGNORE ... SELECT, which skips the rows with duplicate keys
+func (mysql *SMySQLBackend) InsertOrIgnoreFromQuerySQLTemplate() string {
+	return "INSERT IGNORE INTO {{ .Table }} ({{ .Columns }}) {{ .Query }}"
+}
+
+func (mysql *SMySQLBackend) InsertOrUpdateFromQuerySQLTemplate() string {
+	return "INSERT INTO {{ .Table }} ({{ .Columns }}) {{ .Query }} ON DUPLICATE KEY UPDATE {{ .SetValues }}"
 }
 
 func (mysql *SMySQLBackend) CurrentUTCTimeStampString() string {
@@ -17,6 +17,8 @@ package mysql
 import (
 	"testing"
 
+	"github.com/nyl1001/pkg/errors"
+
 	"github.com/nyl1001/sqlchemy"
 	"github.com/nyl1001/sqlchemy/backends/tests"
 )
@@ -70,6 +72,36 @@ func TestQuery(t *testing.T) {
 		testGotWant(t, q.String(), want)
 	})
 
+	t.Run("query row values in", func(t *testing.T) {
+		testReset()
+		q := testTable.Query().Filter(sqlchemy.InTuples([]sqlchemy.IQueryField{testTable.Field("col0"), testTable.Field("col1")}, [][]interface{}{{"a", 1}, {"b", 2}}))
+		want := "SELECT `t1`.`col0`, `t1`.`col1` FROM `test` AS `t1` WHERE (`t1`.`col0`, `t1`.`col1`) IN (( ?, ? ), ( ?, ? ))"
+		testGotWant(t, q.String(), want)
+	})
+
+	t.Run("query page after", func(t *testing.T) {
+		testReset()
+		q, err := testTable.Query().Desc("col1").PageAfter("", "col0")
+		if err != nil {
+			t.Fatalf("PageAfter fail %s", err)
+		}
+		// col1 is nullable, so the row is a struct rather than a map
+		col1 := 1
+		cursor, err := q.PageCursor(&struct {
+			Col0 string
+			Col1 *int
+		}{Col0: "a", Col1: &col1})
+		if err != nil {
+			t.Fatalf("PageCursor fail %s", err)
+		}
+		q, err = testTable.Query().Desc("col1").PageAfter(cursor, "col0")
+		if err != nil {
+			t.Fatalf("PageAfter fail %s", err)
+		}
+		want := "SELECT `t1`.`col0`, `t1`.`col1` FROM `test` AS `t1` WHERE (`t1`.`col1` <  ? ) OR (`t1`.`col1` IS NULL) OR ((`t1`.`col1` =  ? ) AND (`t1`.`col0` >  ? )) ORDER BY `t1`.`col1` DESC, `t1`.`col0` ASC"
+		testGotWant(t, q.String(), want)
+	})
+
 	t.Run("query order by SUM func", func(t *testing.T) {
 		testReset()
 		q := testTable.Query(sqlchemy.SUM("total", testTable.Field("col1")), testTable.Field("col0")).GroupBy(testTable.Field("col0"))
@@ -90,3 +122,93 @@ func TestCountQuery(t *testing.T) {
 	want := "SELECT COUNT(*) AS `count` FROM (SELECT `t1`.`col0`, `t1`.`col1` FROM `test` AS `t1` GROUP BY `t1`.`col0`) AS `t2`"
 	testGotWant(t, cq.String(), want)
 }
+
+func TestLockingQuery(t *testing.T) {
+	t.Run("for update", func(t *testing.T) {
+		testReset()
+		q := testTable.Query().Equals("col1", 1).Limit(1).ForUpdate()
+		want := "SELECT `t1`.`col0`, `t1`.`col1` FROM `test` AS `t1` WHERE `t1`.`col1` =  ?  LIMIT 1 FOR UPDATE"
+		testGotWant(t, q.String(), want)
+	})
+
+	t.Run("lock in share mode", func(t *testing.T) {
+		testReset()
+		q := testTable.Query().ForShare()
+		want := "SELECT `t1`.`col0`, `t1`.`col1` FROM `test` AS `t1` LOCK IN SHARE MODE"
+		testGotWant(t, q.String(), want)
+	})
+
+	t.Run("skip locked before 8.0", func(t *testing.T) {
+		testReset()
+		_, err := testTable.Query().ForUpdate().SkipLocked().RowWithError()
+		if errors.Cause(err) != sqlchemy.ErrNotSupported {
+			t.Errorf("want %s got %v", sqlchemy.ErrNotSupported, err)
+		}
+	})
+
+	t.Run("for update skip locked", func(t *testing.T) {
+		testReset()
+		setServerVersion(t, "8.0.32")
+		q := testTable.Query().Asc("col0").Limit(10).ForUpdate().SkipLocked()
+		want := "SELECT `t1`.`col0`, `t1`.`col1` FROM `test` AS `t1` ORDER BY `t1`.`col0` ASC LIMIT 10 FOR UPDATE SKIP LOCKED"
+		testGotWant(t, q.String(), want)
+	})
+
+	t.Run("for share nowait", func(t *testing.T) {
+		testReset()
+		setServerVersion(t, "8.0.32")
+		q := testTable.Query().ForShare().NoWait()
+		want := "SELECT `t1`.`col0`, `t1`.`col1` FROM `test` AS `t1` FOR SHARE NOWAIT"
+		testGotWant(t, q.String(), want)
+	})
+
+	t.Run("count query drops locking", func(t *testing.T) {
+		testReset()
+		cq := testTable.Query().ForUpdate().CountQuery()
+		want := "SELECT COUNT(*) AS `count` FROM (SELECT `t1`.`col0`, `t1`.`col1` FROM `test` AS `t1`) AS `t2`"
+		testGotWant(t, cq.String(), want)
+	})
+}
+
+func setServerVersion(t *testing.T, version string) {
+	saved := serverVersion
+	t.Cleanup(func() { serverVersion = saved })
+	err := SetServerVersion(version)
+	if err != nil {
+		t.Fatalf("SetServerVersion %s fail: %s", version, err)
+	}
+}
+
+func TestLockingClause(t *testing.T) {
+	cases := []struct {
+		version  string
+		lockType sqlchemy.QueryLockType
+		waitType sqlchemy.QueryLockWaitType
+		want     string
+		wantErr  bool
+	}{
+		{"5.7.40-log", sqlchemy.SQL_LOCK_FOR_UPDATE, "", "FOR UPDATE", false},
+		{"5.7.40-log", sqlchemy.SQL_LOCK_FOR_SHARE, "", "LOCK IN SHARE MODE", false},
+		{"5.7.40-log", sqlchemy.SQL_LOCK_FOR_UPDATE, sqlchemy.SQL_LOCK_NOWAIT, "", true},
+		{"5.7.40-log", sqlchemy.SQL_LOCK_FOR_SHARE, sqlchemy.SQL_LOCK_SKIP_LOCKED, "", true},
+		{"8.0.32-0ubuntu0.22.04.2", sqlchemy.SQL_LOCK_FOR_SHARE, "", "FOR SHARE", false},
+		{"8.0.32", sqlchemy.SQL_LOCK_FOR_UPDATE, sqlchemy.SQL_LOCK_SKIP_LOCKED, "FOR UPDATE SKIP LOCKED", false},
+		{"8", sqlchemy.SQL_LOCK_FOR_SHARE, sqlchemy.SQL_LOCK_NOWAIT, "FOR SHARE NOWAIT", false},
+	}
+	for _, c := range cases {
+		setServerVersion(t, c.version)
+		got, err := (&SMySQLBackend{}).LockingClause(c.lockType, c.waitType)
+		if c.wantErr {
+			if errors.Cause(err) != sqlchemy.ErrNotSupported {
+				t.Errorf("%s %s %s: want %s got %v", c.version, c.lockType, c.waitType, sqlchemy.ErrNotSupported, err)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("%s %s %s: want %q got %q %v", c.version, c.lockType, c.waitType, c.want, got, err)
+		}
+	}
+	if err := SetServerVersion("latest"); err == nil {
+		t.Errorf("SetServerVersion latest: want error")
+	}
+}
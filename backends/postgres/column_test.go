@@ -0,0 +1,252 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"database/sql"
+	"math"
+	"testing"
+
+	"github.com/nyl1001/pkg/jsonutils"
+	"github.com/nyl1001/pkg/tristate"
+	"github.com/nyl1001/sqlchemy"
+)
+
+func TestBadColumns(t *testing.T) {
+	wantPanic := func(t *testing.T, msgFmt string, msgVals ...interface{}) {
+		if msg := recover(); msg == nil {
+			t.Errorf(msgFmt, msgVals...)
+		}
+	}
+	isPtr := false
+
+	t.Run("bool default true", func(t *testing.T) {
+		defer wantPanic(t, "non-pointer boolean must not have default value")
+		NewBooleanColumn(
+			"bad_column",
+			map[string]string{
+				"default": "1",
+			},
+			isPtr,
+		)
+	})
+}
+
+var (
+	triCol         = NewTristateColumn("", "field", nil, false)
+	boolCol        = NewBooleanColumn("field", nil, false)
+	notNullBoolCol = NewBooleanColumn("field", map[string]string{sqlchemy.TAG_NULLABLE: "false", sqlchemy.TAG_DEFAULT: "false"}, false)
+	smallIntCol    = NewIntegerColumn("field", "SMALLINT", nil, false)
+	intCol         = NewIntegerColumn("field", "INTEGER", map[string]string{sqlchemy.TAG_WIDTH: "11", sqlchemy.TAG_DEFAULT: "12"}, false)
+	serialCol      = NewIntegerColumn("field", "INTEGER", map[string]string{sqlchemy.TAG_AUTOINCREMENT: "true"}, false)
+	bigSerialCol   = NewIntegerColumn("field", "BIGINT", map[string]string{sqlchemy.TAG_AUTOINCREMENT: "100"}, false)
+	floatCol       = NewFloatColumn("field", "REAL", nil, false)
+	doubleCol      = NewFloatColumn("field", "DOUBLE PRECISION", nil, false)
+	decimalCol     = NewDecimalColumn("field", map[string]string{sqlchemy.TAG_WIDTH: "10", sqlchemy.TAG_PRECISION: "2"}, false)
+	textCol        = NewTextColumn("field", "TEXT", map[string]string{sqlchemy.TAG_CHARSET: "utf8"}, false)
+	charCol        = NewTextColumn("field", "VARCHAR", map[string]string{sqlchemy.TAG_WIDTH: "16"}, false)
+	notNullTextCol = NewTextColumn("field", "VARCHAR", map[string]string{sqlchemy.TAG_WIDTH: "16", sqlchemy.TAG_NULLABLE: "false"}, false)
+	defTextCol     = NewTextColumn("field", "VARCHAR", map[string]string{sqlchemy.TAG_WIDTH: "16", sqlchemy.TAG_DEFAULT: "it's new!"}, false)
+	dateCol        = NewDateTimeColumn("field", nil, false)
+	notNullDateCol = NewDateTimeColumn("field", map[string]string{sqlchemy.TAG_NULLABLE: "false"}, false)
+	compCol        = NewCompoundColumn("field", "TEXT", nil, false)
+	uint64Col      = NewIntegerColumn("field", UNSIGNED_BIGINT_TYPE, nil, false)
+)
+
+func TestColumns(t *testing.T) {
+	cases := []struct {
+		in   sqlchemy.IColumnSpec
+		want string
+	}{
+		{
+			in:   &triCol,
+			want: `"field" BOOLEAN`,
+		},
+		{
+			in:   &boolCol,
+			want: `"field" BOOLEAN`,
+		},
+		{
+			in:   &notNullBoolCol,
+			want: `"field" BOOLEAN NOT NULL DEFAULT false`,
+		},
+		{
+			in:   &smallIntCol,
+			want: `"field" SMALLINT`,
+		},
+		{
+			in:   &intCol,
+			want: `"field" INTEGER DEFAULT 12`,
+		},
+		{
+			in:   &serialCol,
+			want: `"field" SERIAL NOT NULL`,
+		},
+		{
+			in:   &bigSerialCol,
+			want: `"field" BIGSERIAL NOT NULL`,
+		},
+		{
+			in:   &floatCol,
+			want: `"field" REAL`,
+		},
+		{
+			in:   &doubleCol,
+			want: `"field" DOUBLE PRECISION`,
+		},
+		{
+			in:   &decimalCol,
+			want: `"field" NUMERIC(10, 2)`,
+		},
+		{
+			in:   &textCol,
+			want: `"field" TEXT`,
+		},
+		{
+			in:   &charCol,
+			want: `"field" VARCHAR(16)`,
+		},
+		{
+			in:   &notNullTextCol,
+			want: `"field" VARCHAR(16) NOT NULL`,
+		},
+		{
+			in:   &defTextCol,
+			want: `"field" VARCHAR(16) DEFAULT 'it''s new!'`,
+		},
+		{
+			in:   &dateCol,
+			want: `"field" TIMESTAMP`,
+		},
+		{
+			in:   &notNullDateCol,
+			want: `"field" TIMESTAMP NOT NULL`,
+		},
+		{
+			in:   &compCol,
+			want: `"field" TEXT`,
+		},
+		{
+			in:   &uint64Col,
+			want: `"field" NUMERIC(20)`,
+		},
+	}
+	for _, c := range cases {
+		got := c.in.DefinitionString()
+		if got != c.want {
+			t.Errorf("got %s want %s", got, c.want)
+		}
+	}
+}
+
+func TestConvertValue(t *testing.T) {
+	cases := []struct {
+		in   interface{}
+		want interface{}
+		col  sqlchemy.IColumnSpec
+	}{
+		{
+			in:   true,
+			want: true,
+			col:  &boolCol,
+		},
+		{
+			in:   false,
+			want: false,
+			col:  &boolCol,
+		},
+		{
+			in:   tristate.True,
+			want: true,
+			col:  &triCol,
+		},
+		{
+			in:   tristate.False,
+			want: false,
+			col:  &triCol,
+		},
+		{
+			in:   tristate.None,
+			want: sql.NullBool{},
+			col:  &triCol,
+		},
+		{
+			in:   23,
+			want: 23,
+			col:  &intCol,
+		},
+		{
+			in:   jsonutils.NewDict(),
+			want: `{}`,
+			col:  &compCol,
+		},
+		{
+			in:   uint64(math.MaxUint64),
+			want: "18446744073709551615",
+			col:  &uint64Col,
+		},
+	}
+	for _, c := range cases {
+		got := c.col.ConvertFromValue(c.in)
+		if got != c.want {
+			t.Errorf("%s [%#v] want: %#v got: %#v", c.col.DefinitionString(), c.in, c.want, got)
+		}
+	}
+}
+
+func TestConvertString(t *testing.T) {
+	cases := []struct {
+		in   string
+		want interface{}
+		col  sqlchemy.IColumnSpec
+	}{
+		{
+			in:   "true",
+			want: true,
+			col:  &boolCol,
+		},
+		{
+			in:   "0",
+			want: false,
+			col:  &boolCol,
+		},
+		{
+			in:   "none",
+			want: sql.NullBool{},
+			col:  &triCol,
+		},
+		{
+			in:   "23",
+			want: int64(23),
+			col:  &intCol,
+		},
+		{
+			in:   "0.01",
+			want: 0.01,
+			col:  &floatCol,
+		},
+		{
+			in:   "18446744073709551615",
+			want: "18446744073709551615",
+			col:  &uint64Col,
+		},
+	}
+	for _, c := range cases {
+		got := c.col.ConvertFromString(c.in)
+		if got != c.want {
+			t.Errorf("%s [%s] want: %#v got: %#v", c.col.DefinitionString(), c.in, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"yunion.io/x/log"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+type sSqlColumnInfo struct {
+	ColumnName             string
+	DataType               string
+	CharacterMaximumLength int
+	NumericPrecision       int
+	NumericScale           int
+	IsNullable             string
+	ColumnDefault          string
+	IsPrimary              string
+}
+
+func fetchColumnsSQL(table string) string {
+	return fmt.Sprintf("SELECT c.column_name, c.data_type, c.character_maximum_length, c.numeric_precision, c.numeric_scale, c.is_nullable, c.column_default, "+
+		"CASE WHEN pk.column_name IS NULL THEN 'NO' ELSE 'YES' END AS is_primary "+
+		"FROM information_schema.columns AS c LEFT JOIN ("+
+		"SELECT kcu.column_name FROM information_schema.table_constraints AS tc JOIN information_schema.key_column_usage AS kcu "+
+		"ON tc.constraint_name = kcu.constraint_name AND tc.table_schema = kcu.table_schema AND tc.table_name = kcu.table_name "+
+		"WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = current_schema() AND tc.table_name = %s"+
+		") AS pk ON c.column_name = pk.column_name "+
+		"WHERE c.table_schema = current_schema() AND c.table_name = %s ORDER BY c.ordinal_position", quoteString(table), quoteString(table))
+}
+
+// parseDefault extracts the default value from the column_default of information_schema,
+// e.g. 'abc'::character varying, 0, false, nextval('tbl_id_seq'::regclass)
+func parseDefault(def string) (string, bool) {
+	if strings.HasPrefix(def, "nextval(") {
+		return "", true
+	}
+	if strings.HasPrefix(def, "'") {
+		end := strings.LastIndex(def, "'")
+		if end > 0 {
+			def = def[1:end]
+		}
+		return strings.ReplaceAll(def, "''", "'"), false
+	}
+	if idx := strings.Index(def, "::"); idx > 0 {
+		def = def[:idx]
+	}
+	return strings.Trim(def, "()"), false
+}
+
+func (info *sSqlColumnInfo) getTagmap() (map[string]string, bool) {
+	tagmap := make(map[string]string)
+	if info.IsNullable == "YES" {
+		tagmap[sqlchemy.TAG_NULLABLE] = "true"
+	} else {
+		tagmap[sqlchemy.TAG_NULLABLE] = "false"
+	}
+	if info.IsPrimary == "YES" {
+		tagmap[sqlchemy.TAG_PRIMARY] = "true"
+	} else {
+		tagmap[sqlchemy.TAG_PRIMARY] = "false"
+	}
+	autoinc := false
+	if len(info.ColumnDefault) > 0 && strings.ToUpper(info.ColumnDefault) != "NULL" {
+		var def string
+		def, autoinc = parseDefault(info.ColumnDefault)
+		if autoinc {
+			tagmap[sqlchemy.TAG_AUTOINCREMENT] = "true"
+		} else {
+			tagmap[sqlchemy.TAG_DEFAULT] = def
+		}
+	}
+	return tagmap, autoinc
+}
+
+func (info *sSqlColumnInfo) toColumnSpec() sqlchemy.IColumnSpec {
+	tagmap, _ := info.getTagmap()
+	switch info.DataType {
+	case "character varying", "character":
+		if info.CharacterMaximumLength > 0 {
+			tagmap[sqlchemy.TAG_WIDTH] = fmt.Sprintf("%d", info.CharacterMaximumLength)
+		}
+		c := NewTextColumn(info.ColumnName, "VARCHAR", tagmap, false)
+		return &c
+	case "text":
+		c := NewTextColumn(info.ColumnName, "TEXT", tagmap, false)
+		return &c
+	case "smallint", "integer", "bigint":
+		c := NewIntegerColumn(info.ColumnName, strings.ToUpper(info.DataType), tagmap, false)
+		return &c
+	case "boolean":
+		c := NewBooleanColumn(info.ColumnName, tagmap, true)
+		return &c
+	case "real", "double precision":
+		c := NewFloatColumn(info.ColumnName, strings.ToUpper(info.DataType), tagmap, false)
+		return &c
+	case "numeric":
+		if info.NumericPrecision == 20 && info.NumericScale == 0 {
+			c := NewIntegerColumn(info.ColumnName, UNSIGNED_BIGINT_TYPE, tagmap, false)
+			return &c
+		}
+		tagmap[sqlchemy.TAG_WIDTH] = fmt.Sprintf("%d", info.NumericPrecision)
+		tagmap[sqlchemy.TAG_PRECISION] = fmt.Sprintf("%d", info.NumericScale)
+		c := NewDecimalColumn(info.ColumnName, tagmap, false)
+		return &c
+	case "timestamp without time zone":
+		c := NewDateTimeColumn(info.ColumnName, tagmap, false)
+		return &c
+	case "timestamp with time zone":
+		c := NewTimeTypeColumn(info.ColumnName, "TIMESTAMPTZ", tagmap, false)
+		return &c
+	case "date":
+		c := NewTimeTypeColumn(info.ColumnName, "DATE", tagmap, false)
+		return &c
+	default:
+		log.Errorf("unsupported type %s", info.DataType)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"testing"
+)
+
+func TestParseDefault(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    string
+		autoinc bool
+	}{
+		{
+			in:   "'male'::character varying",
+			want: "male",
+		},
+		{
+			in:   "'it''s'::text",
+			want: "it's",
+		},
+		{
+			in:   "0",
+			want: "0",
+		},
+		{
+			in:   "'-1'::integer",
+			want: "-1",
+		},
+		{
+			in:   "false",
+			want: "false",
+		},
+		{
+			in:      "nextval('table1_id_seq'::regclass)",
+			want:    "",
+			autoinc: true,
+		},
+	}
+	for _, c := range cases {
+		got, autoinc := parseDefault(c.in)
+		if got != c.want || autoinc != c.autoinc {
+			t.Errorf("parseDefault(%s) want %q %v got %q %v", c.in, c.want, c.autoinc, got, autoinc)
+		}
+	}
+}
+
+func TestToColumnSpec(t *testing.T) {
+	cases := []struct {
+		info sSqlColumnInfo
+		want string
+	}{
+		{
+			info: sSqlColumnInfo{
+				ColumnName:    "id",
+				DataType:      "integer",
+				IsNullable:    "NO",
+				ColumnDefault: "nextval('table1_id_seq'::regclass)",
+				IsPrimary:     "YES",
+			},
+			want: `"id" SERIAL NOT NULL`,
+		},
+		{
+			info: sSqlColumnInfo{
+				ColumnName:             "name",
+				DataType:               "character varying",
+				CharacterMaximumLength: 64,
+				IsNullable:             "YES",
+				ColumnDefault:          "'abc'::character varying",
+			},
+			want: `"name" VARCHAR(64) DEFAULT 'abc'`,
+		},
+		{
+			info: sSqlColumnInfo{
+				ColumnName:    "is_male",
+				DataType:      "boolean",
+				IsNullable:    "NO",
+				ColumnDefault: "true",
+			},
+			want: `"is_male" BOOLEAN NOT NULL DEFAULT true`,
+		},
+		{
+			info: sSqlColumnInfo{
+				ColumnName:       "price",
+				DataType:         "numeric",
+				NumericPrecision: 10,
+				NumericScale:     2,
+				IsNullable:       "YES",
+			},
+			want: `"price" NUMERIC(10, 2)`,
+		},
+		{
+			info: sSqlColumnInfo{
+				ColumnName: "created_at",
+				DataType:   "timestamp without time zone",
+				IsNullable: "NO",
+			},
+			want: `"created_at" TIMESTAMP NOT NULL`,
+		},
+	}
+	for _, c := range cases {
+		got := c.info.toColumnSpec().DefinitionString()
+		if got != c.want {
+			t.Errorf("want %s got %s", c.want, got)
+		}
+	}
+}
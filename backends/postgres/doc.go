@@ -0,0 +1,19 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package postgres implements the PostgreSQL backend of sqlchemy.
+//
+// The package does not import a database/sql driver, the application should
+// import one by itself, e.g. github.com/lib/pq or github.com/jackc/pgx/v5/stdlib.
+package postgres // import "github.com/nyl1001/sqlchemy/backends/postgres"
@@ -0,0 +1,148 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+// mysqlDateFormats maps the specifiers of MySQL DATE_FORMAT to the template patterns of postgres TO_CHAR
+var mysqlDateFormats = map[byte]string{
+	'Y': "YYYY",
+	'y': "YY",
+	'm': "MM",
+	'c': "FMMM",
+	'M': "FMMonth",
+	'b': "Mon",
+	'd': "DD",
+	'e': "FMDD",
+	'j': "DDD",
+	'H': "HH24",
+	'k': "FMHH24",
+	'h': "HH12",
+	'I': "HH12",
+	'l': "FMHH12",
+	'i': "MI",
+	's': "SS",
+	'S': "SS",
+	'f': "US",
+	'p': "AM",
+	'W': "FMDay",
+	'a': "Dy",
+	'T': "HH24:MI:SS",
+	'r': "HH12:MI:SS AM",
+	'%': "%",
+}
+
+// convertDateFormat converts a MySQL DATE_FORMAT format string to a postgres TO_CHAR template
+func convertDateFormat(format string) string {
+	var buf strings.Builder
+	literal := false
+	for i := 0; i < len(format); i++ {
+		if format[i] == '%' && i+1 < len(format) {
+			if pattern, ok := mysqlDateFormats[format[i+1]]; ok {
+				if literal {
+					buf.WriteByte('"')
+					literal = false
+				}
+				buf.WriteString(pattern)
+				i++
+				continue
+			}
+		}
+		ch := format[i]
+		if (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') {
+			// letters are quoted to avoid being taken as template patterns
+			if !literal {
+				buf.WriteByte('"')
+				literal = true
+			}
+		} else if literal {
+			buf.WriteByte('"')
+			literal = false
+		}
+		buf.WriteByte(ch)
+	}
+	if literal {
+		buf.WriteByte('"')
+	}
+	return buf.String()
+}
+
+// CAST represents the SQL function CAST
+func (pg *SPostgreSQLBackend) CAST(field sqlchemy.IQueryField, typeStr string, fieldname string) sqlchemy.IQueryField {
+	switch strings.ToUpper(typeStr) {
+	case "SIGNED", "UNSIGNED", "SIGNED INTEGER", "UNSIGNED INTEGER":
+		typeStr = "BIGINT"
+	case "CHAR":
+		typeStr = "TEXT"
+	case "DATETIME":
+		typeStr = "TIMESTAMP"
+	case "DOUBLE":
+		typeStr = "DOUBLE PRECISION"
+	}
+	return sqlchemy.NewFunctionField(fieldname, `CAST(%s AS `+typeStr+`)`, field)
+}
+
+// TIMESTAMPADD represents the SQL function that adds seconds to a timestamp
+func (pg *SPostgreSQLBackend) TIMESTAMPADD(name string, field sqlchemy.IQueryField, offsetSeconds int) sqlchemy.IQueryField {
+	return sqlchemy.NewFunctionField(name, fmt.Sprintf("(%%s + INTERVAL '%d seconds')", offsetSeconds), field)
+}
+
+// DATE_FORMAT represents the SQL function DATE_FORMAT, which is TO_CHAR in postgres
+func (pg *SPostgreSQLBackend) DATE_FORMAT(name string, field sqlchemy.IQueryField, format string) sqlchemy.IQueryField {
+	pgFormat := strings.ReplaceAll(convertDateFormat(format), "%", "%%")
+	return sqlchemy.NewFunctionField(name, `TO_CHAR(%s, `+quoteString(pgFormat)+`)`, field)
+}
+
+// INET_ATON represents the SQL function INET_ATON
+func (pg *SPostgreSQLBackend) INET_ATON(field sqlchemy.IQueryField) sqlchemy.IQueryField {
+	return sqlchemy.NewFunctionField("", `(%s::inet - '0.0.0.0'::inet)`, field)
+}
+
+// REPLACE represents the SQL function REPLACE
+func (pg *SPostgreSQLBackend) REPLACE(name string, field sqlchemy.IQueryField, old string, new string) sqlchemy.IQueryField {
+	return sqlchemy.NewFunctionField(name, fmt.Sprintf(`REPLACE(%s, %s, %s)`, "%s", strings.ReplaceAll(quoteString(old), "%", "%%"), strings.ReplaceAll(quoteString(new), "%", "%%")), field)
+}
+
+// GROUP_CONCAT2 represents the SQL function GROUP_CONCAT, which is string_agg in postgres
+func (pg *SPostgreSQLBackend) GROUP_CONCAT2(name string, sep string, field sqlchemy.IQueryField) sqlchemy.IQueryField {
+	return sqlchemy.NewFunctionField(name, fmt.Sprintf("string_agg(%%s::text, %s)", strings.ReplaceAll(quoteString(sep), "%", "%%")), field)
+}
+
+// DATEDIFF represents the SQL function that returns the difference between two timestamps in the given unit
+func (pg *SPostgreSQLBackend) DATEDIFF(unit string, field1, field2 sqlchemy.IQueryField) sqlchemy.IQueryField {
+	switch strings.ToLower(unit) {
+	case "year":
+		return sqlchemy.NewFunctionField("", "DATE_PART('year', AGE(%[2]s, %[1]s))", field1, field2)
+	case "month":
+		return sqlchemy.NewFunctionField("", "(DATE_PART('year', AGE(%[2]s, %[1]s)) * 12 + DATE_PART('month', AGE(%[2]s, %[1]s)))", field1, field2)
+	}
+	seconds := 1
+	switch strings.ToLower(unit) {
+	case "minute":
+		seconds = 60
+	case "hour":
+		seconds = 3600
+	case "day":
+		seconds = 86400
+	case "week":
+		seconds = 604800
+	}
+	return sqlchemy.NewFunctionField("", fmt.Sprintf("FLOOR(EXTRACT(EPOCH FROM (%%[2]s - %%[1]s)) / %d)", seconds), field1, field2)
+}
@@ -0,0 +1,118 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"testing"
+
+	"github.com/nyl1001/pkg/errors"
+	"github.com/nyl1001/sqlchemy"
+)
+
+func insertSqlPrep(v interface{}, update bool) (string, []interface{}, error) {
+	sqlchemy.SetDBWithNameBackend(nil, sqlchemy.DefaultDB, sqlchemy.PostgreSQLBackend)
+	ts := sqlchemy.NewTableSpecFromStruct(v, "vv")
+	results, err := ts.InsertSqlPrep(v, update)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "InsertSqlPrep")
+	}
+	return results.Sql, results.Values, nil
+}
+
+func TestInsert(t *testing.T) {
+	cases := []struct {
+		value   interface{}
+		update  bool
+		wantSQL string
+		wantVar int
+	}{
+		{
+			value: &struct {
+				RowId int    `auto_increment:"true"`
+				Name  string `width:"24"`
+			}{
+				Name: "a",
+			},
+			update:  false,
+			wantSQL: "INSERT INTO \"vv\" (\"name\") VALUES (?) RETURNING \"row_id\", \"name\"",
+			wantVar: 1,
+		},
+		{
+			value: &struct {
+				RowId int    `primary:"true"`
+				Name  string `width:"24"`
+			}{
+				RowId: 1,
+				Name:  "a",
+			},
+			update:  true,
+			wantSQL: "INSERT INTO \"vv\" (\"row_id\", \"name\") VALUES (?, ?) ON CONFLICT(\"row_id\") DO UPDATE SET \"name\" = ? RETURNING \"row_id\", \"name\"",
+			wantVar: 3,
+		},
+	}
+	for _, c := range cases {
+		sql, vals, err := insertSqlPrep(c.value, c.update)
+		if err != nil {
+			t.Errorf("prepare sql failed: %s", err)
+		} else {
+			if sql != c.wantSQL {
+				t.Errorf("sql want %s got %s", c.wantSQL, sql)
+			} else {
+				if len(vals) != c.wantVar {
+					t.Errorf("vars want %d got %d", c.wantVar, len(vals))
+				}
+			}
+		}
+	}
+}
+
+func TestInsertOrUpdateBatch(t *testing.T) {
+	type vv struct {
+		RowId int    `primary:"true"`
+		Name  string `width:"24"`
+	}
+	sqlchemy.SetDBWithNameBackend(nil, sqlchemy.DefaultDB, sqlchemy.PostgreSQLBackend)
+	ts := sqlchemy.NewTableSpecFromStruct(&vv{}, "vv")
+	results, err := ts.InsertOrUpdateBatchSqlPrep([]interface{}{
+		&vv{RowId: 1, Name: "a"},
+		&vv{RowId: 2, Name: "b"},
+	})
+	if err != nil {
+		t.Fatalf("prepare sql failed: %s", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("want 1 statement got %d", len(results))
+	}
+	wantSQL := "INSERT INTO \"vv\" (\"row_id\", \"name\") VALUES (?, ?), (?, ?) ON CONFLICT(\"row_id\") DO UPDATE SET \"name\" = excluded.\"name\""
+	if results[0].Sql != wantSQL {
+		t.Errorf("sql want %s got %s", wantSQL, results[0].Sql)
+	}
+	if len(results[0].Values) != 4 {
+		t.Errorf("vars want 4 got %d", len(results[0].Values))
+	}
+
+	type kv struct {
+		RowId int `primary:"true"`
+	}
+	kts := sqlchemy.NewTableSpecFromStruct(&kv{}, "kv")
+	results, err = kts.InsertOrUpdateBatchSqlPrep([]interface{}{&kv{RowId: 1}, &kv{RowId: 2}})
+	if err != nil {
+		t.Fatalf("prepare sql failed: %s", err)
+	}
+	wantSQL = "INSERT INTO \"kv\" (\"row_id\") VALUES (?), (?) ON CONFLICT(\"row_id\") DO NOTHING"
+	if results[0].Sql != wantSQL {
+		t.Errorf("sql want %s got %s", wantSQL, results[0].Sql)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nyl1001/pkg/errors"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+const (
+	indexPattern = `CREATE\s+(?P<unique>UNIQUE\s+)?INDEX\s+(?P<name>"[^"]+"|\S+)\s+ON\s+(ONLY\s+)?\S+\s+(USING\s+\w+\s*)?\((?P<cols>[^)]+)\)`
+)
+
+var (
+	indexRegexp = regexp.MustCompile(indexPattern)
+)
+
+type sPostgresIndexInfo struct {
+	Indexname string
+	Indexdef  string
+}
+
+func fetchIndexesSQL(table string) string {
+	return fmt.Sprintf("SELECT i.relname AS indexname, pg_get_indexdef(ix.indexrelid) AS indexdef "+
+		"FROM pg_index AS ix JOIN pg_class AS t ON t.oid = ix.indrelid JOIN pg_class AS i ON i.oid = ix.indexrelid "+
+		"JOIN pg_namespace AS n ON n.oid = t.relnamespace "+
+		"WHERE n.nspname = current_schema() AND t.relname = %s AND NOT ix.indisprimary", quoteString(table))
+}
+
+func (ii *sPostgresIndexInfo) parseTableIndex(ts sqlchemy.ITableSpec) (sqlchemy.STableIndex, error) {
+	matches := indexRegexp.FindStringSubmatch(ii.Indexdef)
+	if len(matches) > 0 {
+		unique := len(matches[1]) > 0
+		name := strings.Trim(matches[2], `"`)
+		cols := make([]string, 0)
+		for _, col := range strings.Split(matches[5], ",") {
+			col = strings.Trim(strings.TrimSpace(col), `"`)
+			if len(col) > 0 {
+				cols = append(cols, col)
+			}
+		}
+		return sqlchemy.NewTableIndex(ts, name, cols, unique), nil
+	}
+	return sqlchemy.STableIndex{}, errors.ErrNotFound
+}
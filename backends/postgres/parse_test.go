@@ -0,0 +1,54 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"testing"
+)
+
+func TestParseIndex(t *testing.T) {
+	cases := []struct {
+		in   string
+		name string
+		want []string
+	}{
+		{
+			in:   "CREATE INDEX ix_testtable_name ON public.testtable USING btree (name)",
+			name: "ix_testtable_name",
+			want: []string{"name"},
+		},
+		{
+			in:   `CREATE UNIQUE INDEX "ix_testtable_name_type" ON public.testtable USING btree (name, "type")`,
+			name: "ix_testtable_name_type",
+			want: []string{"name", "type"},
+		},
+	}
+	for _, c := range cases {
+		ii := sPostgresIndexInfo{
+			Indexname: c.name,
+			Indexdef:  c.in,
+		}
+		index, err := ii.parseTableIndex(nil)
+		if err != nil {
+			t.Errorf("parseTableIndex fail %s", err)
+		} else {
+			if index.Name() != c.name {
+				t.Errorf("want name: %s != got %s", c.name, index.Name())
+			} else if !index.IsIdentical(c.want...) {
+				t.Errorf("want: %s != got: %s", c.want, index.Columns())
+			}
+		}
+	}
+}
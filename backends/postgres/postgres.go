@@ -0,0 +1,296 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/nyl1001/pkg/errors"
+	"github.com/nyl1001/pkg/gotypes"
+	"github.com/nyl1001/pkg/tristate"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+func init() {
+	sqlchemy.RegisterBackend(&SPostgreSQLBackend{})
+}
+
+type SPostgreSQLBackend struct {
+	sqlchemy.SBaseBackend
+}
+
+func (pg *SPostgreSQLBackend) Name() sqlchemy.DBBackendName {
+	return sqlchemy.PostgreSQLBackend
+}
+
+// CanUpdate returns wether the backend supports update
+func (pg *SPostgreSQLBackend) CanUpdate() bool {
+	return true
+}
+
+// CanInsert returns wether the backend supports Insert
+func (pg *SPostgreSQLBackend) CanInsert() bool {
+	return true
+}
+
+// CanInsertOrUpdate returns weather the backend supports InsertOrUpdate
+func (pg *SPostgreSQLBackend) CanInsertOrUpdate() bool {
+	return true
+}
+
+// CanSupportReturning returns wether the backend supports INSERT ... RETURNING
+func (pg *SPostgreSQLBackend) CanSupportReturning() bool {
+	return true
+}
+
+// LockingClause returns the row locking clause, e.g. FOR UPDATE NOWAIT
+func (pg *SPostgreSQLBackend) LockingClause(lockType sqlchemy.QueryLockType, waitType sqlchemy.QueryLockWaitType) (string, error) {
+	clause := "FOR " + string(lockType)
+	if len(waitType) > 0 {
+		clause += " " + string(waitType)
+	}
+	return clause, nil
+}
+
+// QuoteIdentifier quotes an identifier with double quotes
+func (pg *SPostgreSQLBackend) QuoteIdentifier(name string) string {
+	return quoteIdent(name)
+}
+
+// Placeholder returns the positional placeholder $n
+func (pg *SPostgreSQLBackend) Placeholder(index int) string {
+	return fmt.Sprintf("$%d", index)
+}
+
+// MaxInsertPlaceholders returns the max number of bind parameters of a statement
+func (pg *SPostgreSQLBackend) MaxInsertPlaceholders() int {
+	return 65535
+}
+
+// BooleanLiteral returns true or false, postgres does not take integers as conditions
+func (pg *SPostgreSQLBackend) BooleanLiteral(v bool) string {
+	if v {
+		return "true"
+	}
+	return "false"
+}
+
+func (pg *SPostgreSQLBackend) InsertSQLTemplate() string {
+	return `INSERT INTO {{ .Table }} ({{ .Columns }}) VALUES ({{ .Values }})`
+}
+
+func (pg *SPostgreSQLBackend) UpdateSQLTemplate() string {
+	return `UPDATE {{ .Table }} SET {{ .Columns }} WHERE {{ .Conditions }}`
+}
+
+func (pg *SPostgreSQLBackend) InsertOrUpdateSQLTemplate() string {
+	return `INSERT INTO {{ .Table }} ({{ .Columns }}) VALUES ({{ .Values }}) ON CONFLICT({{ .PrimaryKeys }}) DO UPDATE SET {{ .SetValues }}`
+}
+
+// InsertOrIgnoreSQLTemplate returns ON CONFLICT DO NOTHING, which skips the rows with duplicate primary keys
+func (pg *SPostgreSQLBackend) InsertOrIgnoreSQLTemplate() string {
+	return `INSERT INTO {{ .Table }} ({{ .Columns }}) VALUES ({{ .Values }}) ON CONFLICT({{ .PrimaryKeys }}) DO NOTHING`
+}
+
+// InsertOrIgnoreFromQuerySQLTemplate returns INSERT ... SELECT ... ON CONFLICT DO NOTHING
+func (pg *SPostgreSQLBackend) InsertOrIgnoreFromQuerySQLTemplate() string {
+	return `INSERT INTO {{ .Table }} ({{ .Columns }}) {{ .Query }} ON CONFLICT({{ .PrimaryKeys }}) DO NOTHING`
+}
+
+// UpdateJoinStyle returns UPDATE_FROM, i.e. UPDATE a SET ... FROM b WHERE ...
+func (pg *SPostgreSQLBackend) UpdateJoinStyle() sqlchemy.UpdateJoinStyle {
+	return sqlchemy.UPDATE_FROM
+}
+
+// CanSupportRowValueIn returns true, i.e. (a, b) IN ((?, ?), (?, ?))
+func (pg *SPostgreSQLBackend) CanSupportRowValueIn() bool {
+	return true
+}
+
+// IsNullsFirst returns true for DESC, the NULLs are larger than the other values
+func (pg *SPostgreSQLBackend) IsNullsFirst(order sqlchemy.QueryOrderType) bool {
+	return order == sqlchemy.SQL_ORDER_DESC
+}
+
+func (pg *SPostgreSQLBackend) InsertOrUpdateFromQuerySQLTemplate() string {
+	return `INSERT INTO {{ .Table }} ({{ .Columns }}) {{ .Query }} ON CONFLICT({{ .PrimaryKeys }}) DO UPDATE SET {{ .SetValues }}`
+}
+
+// InsertOrUpdateValueRef returns the column of the excluded row of ON CONFLICT DO UPDATE
+func (pg *SPostgreSQLBackend) InsertOrUpdateValueRef(name string) string {
+	return "excluded." + pg.QuoteIdentifier(name)
+}
+
+func (pg *SPostgreSQLBackend) DropIndexSQLTemplate() string {
+	return "DROP INDEX IF EXISTS {{ .Index }}"
+}
+
+func (pg *SPostgreSQLBackend) DropTableSQL(table string) string {
+	return fmt.Sprintf("DROP TABLE %s", quoteIdent(table))
+}
+
+func (pg *SPostgreSQLBackend) CurrentUTCTimeStampString() string {
+	return "(NOW() AT TIME ZONE 'UTC')"
+}
+
+func (pg *SPostgreSQLBackend) CurrentTimeStampString() string {
+	return "LOCALTIMESTAMP"
+}
+
+func (pg *SPostgreSQLBackend) CaseInsensitiveLikeString() string {
+	return "ILIKE"
+}
+
+func (pg *SPostgreSQLBackend) RegexpWhereClause(cond *sqlchemy.SRegexpConition) string {
+	return cond.GetLeft().Reference() + " ~ " + sqlchemy.VarConditionWhereClause(cond.GetRight())
+}
+
+func (pg *SPostgreSQLBackend) GetTableSQL() string {
+	return "SELECT tablename AS name FROM pg_tables WHERE schemaname = current_schema()"
+}
+
+func (pg *SPostgreSQLBackend) IsSupportIndexAndContraints() bool {
+	return true
+}
+
+func (pg *SPostgreSQLBackend) GetCreateSQLs(ts sqlchemy.ITableSpec) []string {
+	cols := make([]string, 0)
+	primaries := make([]string, 0)
+	autoIncs := make([]sqlchemy.IColumnSpec, 0)
+	for _, c := range ts.Columns() {
+		cols = append(cols, c.DefinitionString())
+		if c.IsPrimary() {
+			primaries = append(primaries, quoteIdent(c.Name()))
+		}
+		if intC, ok := c.(*SIntegerColumn); ok && intC.IsAutoIncrement() && intC.AutoIncrementOffset() > 0 {
+			autoIncs = append(autoIncs, c)
+		}
+	}
+	if len(primaries) > 0 {
+		cols = append(cols, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(primaries, ", ")))
+	}
+	sqls := []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n%s\n)", quoteIdent(ts.Name()), strings.Join(cols, ",\n")),
+	}
+	for _, c := range autoIncs {
+		// the sequence of a serial column starts from the autoincrement offset
+		sqls = append(sqls, fmt.Sprintf("SELECT setval(pg_get_serial_sequence(%s, %s), %d, false)", quoteString(ts.Name()), quoteString(c.Name()), c.AutoIncrementOffset()))
+	}
+	for _, idx := range ts.Indexes() {
+		sqls = append(sqls, createIndexSQL(ts, idx))
+	}
+	return sqls
+}
+
+func (pg *SPostgreSQLBackend) FetchTableColumnSpecs(ts sqlchemy.ITableSpec) ([]sqlchemy.IColumnSpec, error) {
+	sql := fetchColumnsSQL(ts.Name())
+	query := ts.Database().NewRawQuery(sql, "column_name", "data_type", "character_maximum_length", "numeric_precision", "numeric_scale", "is_nullable", "column_default", "is_primary")
+	infos := make([]sSqlColumnInfo, 0)
+	err := query.All(&infos)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Raw Query Scan %s", sql)
+	}
+	specs := make([]sqlchemy.IColumnSpec, 0)
+	for _, info := range infos {
+		spec := info.toColumnSpec()
+		if spec != nil {
+			specs = append(specs, spec)
+		}
+	}
+	return specs, nil
+}
+
+func (pg *SPostgreSQLBackend) FetchIndexesAndConstraints(ts sqlchemy.ITableSpec) ([]sqlchemy.STableIndex, []sqlchemy.STableConstraint, error) {
+	sql := fetchIndexesSQL(ts.Name())
+	query := ts.Database().NewRawQuery(sql, "indexname", "indexdef")
+	results := make([]sPostgresIndexInfo, 0)
+	err := query.All(&results)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "Raw Query Scan %s", sql)
+	}
+	indexes := make([]sqlchemy.STableIndex, 0)
+	for i := range results {
+		ti, err := results[i].parseTableIndex(ts)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "parseTableIndex fail %s", results[i].Indexdef)
+		}
+		indexes = append(indexes, ti)
+	}
+	// foreign key constraints are not managed by sqlchemy for postgres
+	return indexes, nil, nil
+}
+
+func getTextSqlType(tagmap map[string]string) string {
+	widthStr := tagmap[sqlchemy.TAG_WIDTH]
+	if len(widthStr) > 0 && widthStr != "0" {
+		return "VARCHAR"
+	}
+	return "TEXT"
+}
+
+func (pg *SPostgreSQLBackend) GetColumnSpecByFieldType(table *sqlchemy.STableSpec, fieldType reflect.Type, fieldname string, tagmap map[string]string, isPointer bool) sqlchemy.IColumnSpec {
+	switch fieldType {
+	case tristate.TriStateType:
+		col := NewTristateColumn(table.Name(), fieldname, tagmap, isPointer)
+		return &col
+	case gotypes.TimeType:
+		col := NewDateTimeColumn(fieldname, tagmap, isPointer)
+		return &col
+	}
+	switch fieldType.Kind() {
+	case reflect.String:
+		col := NewTextColumn(fieldname, getTextSqlType(tagmap), tagmap, isPointer)
+		return &col
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		col := NewIntegerColumn(fieldname, "SMALLINT", tagmap, isPointer)
+		return &col
+	case reflect.Int32, reflect.Uint16:
+		col := NewIntegerColumn(fieldname, "INTEGER", tagmap, isPointer)
+		return &col
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		// int is 64-bit, and postgres has no unsigned integers, BIGINT holds all the values of uint32
+		col := NewIntegerColumn(fieldname, "BIGINT", tagmap, isPointer)
+		return &col
+	case reflect.Uint, reflect.Uint64:
+		// NUMERIC(20) holds all the values of uint64, which overflow BIGINT
+		col := NewIntegerColumn(fieldname, UNSIGNED_BIGINT_TYPE, tagmap, isPointer)
+		return &col
+	case reflect.Bool:
+		col := NewBooleanColumn(fieldname, tagmap, isPointer)
+		return &col
+	case reflect.Float32, reflect.Float64:
+		if _, ok := tagmap[sqlchemy.TAG_WIDTH]; ok {
+			col := NewDecimalColumn(fieldname, tagmap, isPointer)
+			return &col
+		}
+		colType := "REAL"
+		if fieldType == gotypes.Float64Type {
+			colType = "DOUBLE PRECISION"
+		}
+		col := NewFloatColumn(fieldname, colType, tagmap, isPointer)
+		return &col
+	case reflect.Map, reflect.Slice:
+		col := NewCompoundColumn(fieldname, getTextSqlType(tagmap), tagmap, isPointer)
+		return &col
+	}
+	if fieldType.Implements(gotypes.ISerializableType) {
+		col := NewCompoundColumn(fieldname, getTextSqlType(tagmap), tagmap, isPointer)
+		return &col
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nyl1001/sqlchemy"
+	"github.com/nyl1001/sqlchemy/backends/tests"
+)
+
+var (
+	testTable   *sqlchemy.STable
+	testGotWant = tests.AssertGotWant
+)
+
+func testReset() {
+	tests.BackendTestReset(sqlchemy.PostgreSQLBackend)
+	testTable = tests.GetTestTable()
+}
+
+func TestQuery(t *testing.T) {
+	t.Run("query regexp field", func(t *testing.T) {
+		testReset()
+		q := testTable.Query(testTable.Field("col0")).Regexp("col1", "^ab$")
+		want := "SELECT \"t1\".\"col0\" FROM \"test\" AS \"t1\" WHERE \"t1\".\"col1\" ~  ? "
+		testGotWant(t, q.String(), want)
+	})
+
+	t.Run("query case insensitive like", func(t *testing.T) {
+		testReset()
+		q := testTable.Query(testTable.Field("col0")).Contains("col0", "abc")
+		want := "SELECT \"t1\".\"col0\" FROM \"test\" AS \"t1\" WHERE \"t1\".\"col0\" ILIKE  ? "
+		testGotWant(t, q.String(), want)
+	})
+
+	t.Run("query group_concat", func(t *testing.T) {
+		testReset()
+		q := testTable.Query(sqlchemy.GROUP_CONCAT("cols", testTable.Field("col0")))
+		want := "SELECT string_agg(\"t1\".\"col0\"::text, ',') AS \"cols\" FROM \"test\" AS \"t1\""
+		testGotWant(t, q.String(), want)
+	})
+
+	t.Run("query cast", func(t *testing.T) {
+		testReset()
+		q := testTable.Query(sqlchemy.CAST(testTable.Field("col1"), "CHAR", "col1_str"))
+		want := "SELECT CAST(\"t1\".\"col1\" AS TEXT) AS \"col1_str\" FROM \"test\" AS \"t1\""
+		testGotWant(t, q.String(), want)
+	})
+
+	t.Run("query date_format", func(t *testing.T) {
+		testReset()
+		q := testTable.Query(sqlchemy.DATE_FORMAT("date", testTable.Field("col0"), "%Y-%m-%dT%H:%i:%s"))
+		want := "SELECT TO_CHAR(\"t1\".\"col0\", 'YYYY-MM-DD\"T\"HH24:MI:SS') AS \"date\" FROM \"test\" AS \"t1\""
+		testGotWant(t, q.String(), want)
+	})
+
+	t.Run("query timestampadd", func(t *testing.T) {
+		testReset()
+		q := testTable.Query(sqlchemy.TimestampAdd("next", testTable.Field("col0"), 3600))
+		want := "SELECT (\"t1\".\"col0\" + INTERVAL '3600 seconds') AS \"next\" FROM \"test\" AS \"t1\""
+		testGotWant(t, q.String(), want)
+	})
+
+	t.Run("query datediff", func(t *testing.T) {
+		testReset()
+		q := testTable.Query(testTable.Field("col0")).Filter(sqlchemy.GE(sqlchemy.DATEDIFF("day", testTable.Field("col0"), testTable.Field("col1")), 1))
+		want := "SELECT \"t1\".\"col0\" FROM \"test\" AS \"t1\" WHERE FLOOR(EXTRACT(EPOCH FROM (\"t1\".\"col1\" - \"t1\".\"col0\")) / 86400) >=  ? "
+		testGotWant(t, q.String(), want)
+	})
+
+	t.Run("query having labelled aggregate", func(t *testing.T) {
+		testReset()
+		q := testTable.Query(testTable.Field("col0"), sqlchemy.COUNT("cnt", testTable.Field("col1"))).GroupBy(testTable.Field("col0"))
+		q = q.HavingGT("cnt", 5).HavingContains("col0", "abc")
+		want := "SELECT \"t1\".\"col0\", COUNT(\"t1\".\"col1\") AS \"cnt\" FROM \"test\" AS \"t1\" GROUP BY \"t1\".\"col0\" HAVING (COUNT(\"t1\".\"col1\") >  ? ) AND (\"t1\".\"col0\" ILIKE  ? )"
+		testGotWant(t, q.String(), want)
+	})
+}
+
+func TestBooleanConditions(t *testing.T) {
+	t.Run("filter by true", func(t *testing.T) {
+		testReset()
+		q := testTable.Query(testTable.Field("col0")).FilterByTrue()
+		want := "SELECT \"t1\".\"col0\" FROM \"test\" AS \"t1\" WHERE true"
+		testGotWant(t, q.String(), want)
+	})
+
+	t.Run("in empty values", func(t *testing.T) {
+		testReset()
+		q := testTable.Query(testTable.Field("col0")).In("col0", []string{})
+		want := "SELECT \"t1\".\"col0\" FROM \"test\" AS \"t1\" WHERE false"
+		testGotWant(t, q.String(), want)
+	})
+
+	t.Run("delete without conditions", func(t *testing.T) {
+		testReset()
+		sql, _ := tests.GetTestTableSpec().DeleteWhereSql(nil)
+		testGotWant(t, sql, "DELETE FROM \"test\" WHERE true")
+	})
+}
+
+func TestRebind(t *testing.T) {
+	testReset()
+	db := tests.GetTestTableSpec().Database()
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{
+			in:   "SELECT * FROM \"t\" WHERE \"a\" = ? AND \"b\" IN (?, ?)",
+			want: "SELECT * FROM \"t\" WHERE \"a\" = $1 AND \"b\" IN ($2, $3)",
+		},
+		{
+			in:   "SELECT '?', \"a?\" FROM \"t\" WHERE \"a\" = ? AND \"b\" = E'it\\'s?'",
+			want: "SELECT '?', \"a?\" FROM \"t\" WHERE \"a\" = $1 AND \"b\" = E'it\\'s?'",
+		},
+		{
+			in:   "SELECT * FROM \"t\" WHERE \"a\" = 'a\\' AND \"b\" = ? AND \"c\" = 'it''s?'",
+			want: "SELECT * FROM \"t\" WHERE \"a\" = 'a\\' AND \"b\" = $1 AND \"c\" = 'it''s?'",
+		},
+	}
+	for _, c := range cases {
+		testGotWant(t, db.Rebind(c.in), c.want)
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	backend := &SPostgreSQLBackend{}
+	testGotWant(t, backend.QuoteIdentifier("col"), "\"col\"")
+	testGotWant(t, backend.QuoteIdentifier("a\"b"), "\"a\"\"b\"")
+	testGotWant(t, backend.Placeholder(2), "$2")
+}
+
+func TestQuotedColumns(t *testing.T) {
+	testReset()
+	idx := sqlchemy.NewTableIndex(tests.GetTestTableSpec(), "ix_test_col0_col1", []string{"col0", "col1"}, false)
+	testGotWant(t, strings.Join(idx.QuotedColumns(), ", "), "\"col0\", \"col1\"")
+}
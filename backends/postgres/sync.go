@@ -0,0 +1,180 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nyl1001/sqlchemy"
+	"yunion.io/x/log"
+)
+
+// baseColType returns the type of a column that can be used in ALTER COLUMN TYPE,
+// i.e. the integer type of a serial column
+func baseColType(c sqlchemy.IColumnSpec) string {
+	if intC, ok := c.(*SIntegerColumn); ok {
+		return intC.SBaseColumn.ColType()
+	}
+	return c.ColType()
+}
+
+func (pg *SPostgreSQLBackend) CommitTableChangeSQL(ts sqlchemy.ITableSpec, changes sqlchemy.STableChanges) []string {
+	ret := make([]string, 0)
+
+	for _, idx := range changes.RemoveIndexes {
+		sql := fmt.Sprintf("DROP INDEX IF EXISTS %s", quoteIdent(idx.Name()))
+		ret = append(ret, sql)
+		log.Infof("%s;", sql)
+	}
+
+	alters := make([]string, 0)
+
+	// first check if primary key is modifed
+	changePrimary := false
+	for _, col := range changes.RemoveColumns {
+		if col.IsPrimary() {
+			changePrimary = true
+			break
+		}
+	}
+	if !changePrimary {
+		for _, cols := range changes.UpdatedColumns {
+			if cols.OldCol.IsPrimary() != cols.NewCol.IsPrimary() {
+				changePrimary = true
+				break
+			}
+		}
+	}
+	if !changePrimary {
+		for _, col := range changes.AddColumns {
+			if col.IsPrimary() {
+				changePrimary = true
+				break
+			}
+		}
+	}
+	// in case of a primary key change, we first need to drop the primary key constraint,
+	// which is named as <table>_pkey by default
+	if changePrimary {
+		oldHasPrimary := false
+		for _, col := range changes.OldColumns {
+			if col.IsPrimary() {
+				oldHasPrimary = true
+				break
+			}
+		}
+		if oldHasPrimary {
+			alters = append(alters, fmt.Sprintf("DROP CONSTRAINT IF EXISTS %s", quoteIdent(ts.Name()+"_pkey")))
+		}
+	}
+
+	/* IGNORE DROP STATEMENT */
+	for _, col := range changes.RemoveColumns {
+		sql := fmt.Sprintf("DROP COLUMN %s", quoteIdent(col.Name()))
+		log.Debugf("skip ALTER TABLE %s %s;", ts.Name(), sql)
+		// ignore drop statement
+		// if the column is auto_increment integer column,
+		// then need to drop the sequence default
+		if col.IsAutoIncrement() {
+			log.Errorf("column %s is auto_increment, drop auto_inrement attribute", col.Name())
+			alters = append(alters, fmt.Sprintf("ALTER COLUMN %s DROP DEFAULT", quoteIdent(col.Name())))
+		}
+		// if the column is not nullable but no default
+		// then need to drop the not-nullable attribute
+		if !col.IsNullable() && col.Default() == "" {
+			alters = append(alters, fmt.Sprintf("ALTER COLUMN %s DROP NOT NULL", quoteIdent(col.Name())))
+			log.Errorf("column %s is not nullable but no default, drop not nullable attribute", col.Name())
+		}
+	}
+	for _, cols := range changes.UpdatedColumns {
+		alters = append(alters, alterColumnSQLs(cols.OldCol, cols.NewCol)...)
+	}
+	for _, col := range changes.AddColumns {
+		sql := fmt.Sprintf("ADD COLUMN %s", col.DefinitionString())
+		alters = append(alters, sql)
+	}
+	if changePrimary {
+		primaries := make([]string, 0)
+		for _, c := range ts.Columns() {
+			if c.IsPrimary() {
+				primaries = append(primaries, quoteIdent(c.Name()))
+			}
+		}
+		if len(primaries) > 0 {
+			sql := fmt.Sprintf("ADD PRIMARY KEY (%s)", strings.Join(primaries, ", "))
+			alters = append(alters, sql)
+		}
+	}
+
+	if len(alters) > 0 {
+		sql := fmt.Sprintf("ALTER TABLE %s %s", quoteIdent(ts.Name()), strings.Join(alters, ", "))
+		ret = append(ret, sql)
+	}
+
+	for _, idx := range changes.AddIndexes {
+		sql := createIndexSQL(ts, idx)
+		ret = append(ret, sql)
+		log.Infof("%s;", sql)
+	}
+
+	return ret
+}
+
+// alterColumnSQLs returns the ALTER COLUMN actions that change oldCol into newCol,
+// postgres alters the type, nullability and default of a column separately
+func alterColumnSQLs(oldCol, newCol sqlchemy.IColumnSpec) []string {
+	ret := make([]string, 0)
+	name := quoteIdent(newCol.Name())
+	newType := baseColType(newCol)
+	if baseColType(oldCol) != newType {
+		ret = append(ret, fmt.Sprintf("ALTER COLUMN %s TYPE %s USING %s::%s", name, newType, name, newType))
+	}
+	if oldCol.IsNullable() != newCol.IsNullable() {
+		if newCol.IsNullable() {
+			ret = append(ret, fmt.Sprintf("ALTER COLUMN %s DROP NOT NULL", name))
+		} else {
+			ret = append(ret, fmt.Sprintf("ALTER COLUMN %s SET NOT NULL", name))
+		}
+	}
+	if oldCol.IsAutoIncrement() != newCol.IsAutoIncrement() {
+		if newCol.IsAutoIncrement() {
+			log.Errorf("column %s cannot be altered to a serial column", newCol.Name())
+		} else {
+			ret = append(ret, fmt.Sprintf("ALTER COLUMN %s DROP DEFAULT", name))
+		}
+	}
+	if !newCol.IsAutoIncrement() && oldCol.Default() != newCol.Default() {
+		if len(newCol.Default()) > 0 {
+			ret = append(ret, fmt.Sprintf("ALTER COLUMN %s SET DEFAULT %s", name, defaultString(newCol)))
+		} else if !oldCol.IsAutoIncrement() {
+			ret = append(ret, fmt.Sprintf("ALTER COLUMN %s DROP DEFAULT", name))
+		}
+	}
+	return ret
+}
+
+func createIndexSQL(ts sqlchemy.ITableSpec, idx sqlchemy.STableIndex) string {
+	return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s)", quoteIdent(idx.Name()), quoteIdent(ts.Name()), strings.Join(quotedColumns(idx), ", "))
+}
+
+// quotedColumns returns the column names of an index quoted with double quotes
+func quotedColumns(idx sqlchemy.STableIndex) []string {
+	cols := make([]string, 0, len(idx.Columns()))
+	for _, col := range idx.Columns() {
+		cols = append(cols, quoteIdent(col))
+	}
+	return cols
+}
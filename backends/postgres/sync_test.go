@@ -0,0 +1,82 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+func TestSync(t *testing.T) {
+	type TableStruct1 struct {
+		Id     uint64 `auto_increment:"true"`
+		Name   string `width:"64" charset:"utf8"`
+		Age    int32  `nullable:"true" default:"12"`
+		IsMale *bool  `nullable:"false" default:"true"`
+	}
+	type TableStruct2 struct {
+		Id     uint64 `auto_increment:"true"`
+		Name   string `width:"128" charset:"utf8" index:"true"`
+		Age    int64  `nullable:"false" default:"10"`
+		Gender string `width:"8" nullable:"false" default:"male"`
+	}
+
+	sqlchemy.SetDBWithNameBackend(nil, sqlchemy.DefaultDB, sqlchemy.PostgreSQLBackend)
+	ts1 := sqlchemy.NewTableSpecFromStruct(TableStruct1{}, "table1")
+	ts2 := sqlchemy.NewTableSpecFromStruct(TableStruct2{}, "table1")
+
+	changes := sqlchemy.STableChanges{}
+	changes.RemoveColumns, changes.UpdatedColumns, changes.AddColumns = sqlchemy.DiffCols(ts2.Name(), ts1.Columns(), ts2.Columns())
+	changes.AddIndexes = ts2.Indexes()
+	backend := &SPostgreSQLBackend{}
+	sqls := backend.CommitTableChangeSQL(ts2, changes)
+	want := []string{
+		`ALTER TABLE "table1" ALTER COLUMN "age" TYPE BIGINT USING "age"::BIGINT, ALTER COLUMN "age" SET NOT NULL, ALTER COLUMN "age" SET DEFAULT 10, ALTER COLUMN "name" TYPE VARCHAR(128) USING "name"::VARCHAR(128), ADD COLUMN "gender" VARCHAR(8) NOT NULL DEFAULT 'male'`,
+		`CREATE INDEX IF NOT EXISTS "ix_table1_name" ON "table1" ("name")`,
+	}
+	if !reflect.DeepEqual(sqls, want) {
+		t.Errorf("Expect: %s", want)
+		t.Errorf("Got: %s", sqls)
+	}
+}
+
+func TestCreateSQLs(t *testing.T) {
+	type TableStruct struct {
+		Id        uint64 `auto_increment:"1000"`
+		Name      string `width:"64" charset:"utf8" index:"true"`
+		IsMale    *bool  `nullable:"false" default:"true"`
+		Info      string `charset:"utf8"`
+		Count     int    `nullable:"false"`
+		Flags     uint32
+		Level     int32
+		CreatedAt time.Time `nullable:"false" created_at:"true"`
+	}
+	sqlchemy.SetDBWithNameBackend(nil, sqlchemy.DefaultDB, sqlchemy.PostgreSQLBackend)
+	ts := sqlchemy.NewTableSpecFromStruct(TableStruct{}, "table1")
+	backend := &SPostgreSQLBackend{}
+	sqls := backend.GetCreateSQLs(ts)
+	want := []string{
+		"CREATE TABLE IF NOT EXISTS \"table1\" (\n\"id\" BIGSERIAL NOT NULL,\n\"name\" VARCHAR(64),\n\"is_male\" BOOLEAN NOT NULL DEFAULT true,\n\"info\" TEXT,\n\"count\" BIGINT NOT NULL,\n\"flags\" BIGINT,\n\"level\" INTEGER,\n\"created_at\" TIMESTAMP NOT NULL,\nPRIMARY KEY (\"id\")\n)",
+		"SELECT setval(pg_get_serial_sequence('table1', 'id'), 1000, false)",
+		`CREATE INDEX IF NOT EXISTS "ix_table1_name" ON "table1" ("name")`,
+	}
+	if !reflect.DeepEqual(sqls, want) {
+		t.Errorf("Expect: %s", want)
+		t.Errorf("Got: %s", sqls)
+	}
+}
@@ -0,0 +1,124 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nyl1001/pkg/errors"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+type auditTestTable struct {
+	Id   string `primary:"true" width:"32"`
+	Name string `width:"64"`
+	Age  int    `default:"0"`
+}
+
+func TestAudit(t *testing.T) {
+	openTestDB(t, "audittest")
+	ts := syncTestTable(t, auditTestTable{}, "audit_test_tbl")
+	logs := sqlchemy.NewAuditTableSpec("audit_log_tbl")
+	err := logs.Sync()
+	if err != nil {
+		t.Fatalf("sync table fail: %s", err)
+	}
+
+	row := &auditTestTable{Id: "1", Name: "one"}
+	_, err = ts.AuditHistory(row)
+	if err == nil {
+		t.Errorf("AuditHistory of a table not audited should fail")
+	}
+	ts.EnableAudit(logs)
+
+	ctx := sqlchemy.WithAuditActor(context.Background(), "alice")
+	err = ts.InsertContext(ctx, row)
+	if err != nil {
+		t.Fatalf("Insert fail: %s", err)
+	}
+	err = ts.Insert(&auditTestTable{Id: "2", Name: "two"})
+	if err != nil {
+		t.Fatalf("Insert fail: %s", err)
+	}
+	_, err = ts.UpdateContext(ctx, row, func() error {
+		row.Age = 10
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update fail: %s", err)
+	}
+	err = ts.DeleteContext(sqlchemy.WithAuditActor(context.Background(), "bob"), row)
+	if err != nil {
+		t.Fatalf("Delete fail: %s", err)
+	}
+
+	history, err := ts.AuditHistory(&auditTestTable{Id: "1"})
+	if err != nil {
+		t.Fatalf("AuditHistory fail: %s", err)
+	}
+	want := []struct {
+		action string
+		actor  string
+	}{
+		{sqlchemy.AUDIT_ACTION_INSERT, "alice"},
+		{sqlchemy.AUDIT_ACTION_UPDATE, "alice"},
+		{sqlchemy.AUDIT_ACTION_DELETE, "bob"},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("history: want %d logs got %#v", len(want), history)
+	}
+	for i := range want {
+		if history[i].Action != want[i].action || history[i].Actor != want[i].actor || history[i].TableName != "audit_test_tbl" || history[i].CreatedAt.IsZero() {
+			t.Errorf("log %d: want %s by %s got %#v", i, want[i].action, want[i].actor, history[i])
+		}
+	}
+	data, err := history[0].GetData()
+	if err != nil {
+		t.Fatalf("GetData fail: %s", err)
+	}
+	if name, _ := data.GetString("name"); name != "one" {
+		t.Errorf("insert data: want name one got %s", data)
+	}
+	data, err = history[1].GetData()
+	if err != nil {
+		t.Fatalf("GetData fail: %s", err)
+	}
+	if age, _ := data.Int("age", "new"); age != 10 {
+		t.Errorf("update diffs: want new age 10 got %s", data)
+	}
+
+	// the writes bypassing the audit are refused, and nothing is changed or logged
+	two := &auditTestTable{Id: "2"}
+	err = ts.UpdateFields(two, map[string]interface{}{"age": 20})
+	if errors.Cause(err) != sqlchemy.ErrNotSupported {
+		t.Errorf("UpdateFields: want ErrNotSupported got %v", err)
+	}
+	_, err = ts.UpdateWhere(sqlchemy.Equals(ts.Target().Field("id"), "2"), map[string]interface{}{"age": 20})
+	if errors.Cause(err) != sqlchemy.ErrNotSupported {
+		t.Errorf("UpdateWhere: want ErrNotSupported got %v", err)
+	}
+	_, err = ts.DeleteWhere(sqlchemy.Equals(ts.Target().Field("id"), "2"))
+	if errors.Cause(err) != sqlchemy.ErrNotSupported {
+		t.Errorf("DeleteWhere: want ErrNotSupported got %v", err)
+	}
+	err = ts.InsertOrUpdate(&auditTestTable{Id: "2", Age: 20})
+	if errors.Cause(err) != sqlchemy.ErrNotSupported {
+		t.Errorf("InsertOrUpdate: want ErrNotSupported got %v", err)
+	}
+	err = ts.InsertBatch([]interface{}{&auditTestTable{Id: "3", Name: "three"}})
+	if errors.Cause(err) != sqlchemy.ErrNotSupported {
+		t.Errorf("InsertBatch: want ErrNotSupported got %v", err)
+	}
+	err = ts.Query().Equals("id", "2").First(two)
+	if err != nil || two.Age != 0 {
+		t.Errorf("want record 2 unchanged got %#v %v", two, err)
+	}
+	cnt, err := ts.Query().CountWithError()
+	if err != nil || cnt != 1 {
+		t.Errorf("want 1 record got %d %v", cnt, err)
+	}
+	cnt, err = logs.Query().CountWithError()
+	if err != nil || cnt != 4 {
+		t.Errorf("want 4 logs got %d %v", cnt, err)
+	}
+}
@@ -0,0 +1,101 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/nyl1001/pkg/errors"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+type cteTestTable struct {
+	Id       string `primary:"true" width:"32"`
+	Name     string `width:"64"`
+	ParentId string `width:"32"`
+}
+
+func TestRecursiveCTE(t *testing.T) {
+	openTestDB(t, "ctetest")
+	ts := syncTestTable(t, cteTestTable{}, "cte_test_tbl")
+	// 1 -> 2 -> 3, 1 -> 4, 5
+	rows := []cteTestTable{
+		{Id: "1", Name: "root"},
+		{Id: "2", Name: "child", ParentId: "1"},
+		{Id: "3", Name: "grandchild", ParentId: "2"},
+		{Id: "4", Name: "child2", ParentId: "1"},
+		{Id: "5", Name: "other"},
+	}
+	for i := range rows {
+		err := ts.Insert(&rows[i])
+		if err != nil {
+			t.Fatalf("insert fail: %s", err)
+		}
+	}
+
+	t1 := ts.Instance()
+	anchor := t1.Query(t1.Field("id"), t1.Field("name")).Equals("id", "2")
+	tree := sqlchemy.NewRecursiveCTE("tree", anchor, func(self *sqlchemy.SCTE) sqlchemy.IQuery {
+		t2 := ts.Instance()
+		return t2.Query(t2.Field("id"), t2.Field("name")).Join(self, sqlchemy.Equals(t2.Field("parent_id"), self.Field("id")))
+	})
+	descendants := make([]cteTestTable, 0)
+	err := tree.Query().Asc("id").All(&descendants)
+	if err != nil {
+		t.Fatalf("query recursive cte fail: %s", err)
+	}
+	got := ""
+	for i := range descendants {
+		got += descendants[i].Id
+	}
+	if got != "23" {
+		t.Errorf("want descendants 23, got %s", got)
+	}
+
+	t3 := ts.Instance()
+	q := t3.Query(t3.Field("name"))
+	t4 := ts.Instance()
+	children := q.With("children", t4.Query(t4.Field("id")).Equals("parent_id", "1"))
+	q = q.Join(children, sqlchemy.Equals(t3.Field("id"), children.Field("id"))).Asc(t3.Field("name"))
+	names, err := q.AllStringMap()
+	if err != nil {
+		t.Fatalf("query cte fail: %s", err)
+	}
+	if len(names) != 2 || names[0]["name"] != "child" || names[1]["name"] != "child2" {
+		t.Errorf("want children child, child2, got %v", names)
+	}
+}
+
+func TestCTENameConflict(t *testing.T) {
+	openTestDB(t, "ctetest")
+	ts := syncTestTable(t, cteTestTable{}, "cte_test_tbl")
+
+	t1 := ts.Instance()
+	q := t1.Query(t1.Field("name"))
+	t2 := ts.Instance()
+	c1 := q.With("c", t2.Query(t2.Field("id")).Equals("parent_id", "1"))
+	t3 := ts.Instance()
+	c2 := sqlchemy.NewCTE("c", t3.Query(t3.Field("id")).Equals("parent_id", "2"))
+	q = q.Join(c1, sqlchemy.Equals(t1.Field("id"), c1.Field("id")))
+	q = q.Join(c2, sqlchemy.Equals(t1.Field("id"), c2.Field("id")))
+	_, err := q.AllStringMap()
+	if errors.Cause(err) != sqlchemy.ErrDuplicateCTE {
+		t.Errorf("want ErrDuplicateCTE, got %v", err)
+	}
+	_, err = q.RowWithError()
+	if errors.Cause(err) != sqlchemy.ErrDuplicateCTE {
+		t.Errorf("RowWithError: want ErrDuplicateCTE, got %v", err)
+	}
+
+	// the same CTE joined twice is defined once
+	t4 := ts.Instance()
+	q = t4.Query(t4.Field("name"))
+	t5 := ts.Instance()
+	c3 := q.With("c", t5.Query(t5.Field("id")))
+	c4 := c3.Instance()
+	q = q.Join(c3, sqlchemy.Equals(t4.Field("id"), c3.Field("id")))
+	q = q.Join(c4, sqlchemy.Equals(t4.Field("id"), c4.Field("id")))
+	_, err = q.AllStringMap()
+	if err != nil {
+		t.Errorf("query cte twice fail: %s", err)
+	}
+}
@@ -0,0 +1,45 @@
+package sqlite
+
+import (
+	"fmt"
+	"testing"
+)
+
+type fetchTestTable struct {
+	Id   int    `primary:"true"`
+	Name string `primary:"true" width:"32"`
+	Desc string `width:"64"`
+}
+
+func TestFetchAllCompositeKeys(t *testing.T) {
+	openTestDB(t, "fetchtest")
+	ts := syncTestTable(t, fetchTestTable{}, "fetch_test_tbl")
+	// 600 keys of 2 columns exceed the 999 placeholders of a query, which are fetched in 2 chunks
+	const count = 600
+	rows := make([]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		rows = append(rows, &fetchTestTable{Id: i/2 + 1, Name: fmt.Sprintf("name%d", i%2), Desc: fmt.Sprintf("desc%d", i)})
+	}
+	err := ts.InsertBatch(rows)
+	if err != nil {
+		t.Fatalf("insert fail: %s", err)
+	}
+
+	dest := make([]fetchTestTable, 0, count+1)
+	for i := count - 1; i >= 0; i-- {
+		dest = append(dest, fetchTestTable{Id: i/2 + 1, Name: fmt.Sprintf("name%d", i%2)})
+	}
+	dest = append(dest, fetchTestTable{Id: 1, Name: "name2"})
+	err = ts.FetchAll(&dest)
+	if err != nil {
+		t.Fatalf("FetchAll fail: %s", err)
+	}
+	for i := 0; i < count; i++ {
+		if want := fmt.Sprintf("desc%d", count-1-i); dest[i].Desc != want {
+			t.Errorf("element %d: want %s got %s", i, want, dest[i].Desc)
+		}
+	}
+	if dest[count].Desc != "" {
+		t.Errorf("element not exists: want empty got %s", dest[count].Desc)
+	}
+}
@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+type genericTestTable struct {
+	Id   string `primary:"true" width:"32"`
+	Name string `width:"64"`
+	Age  int    `default:"0"`
+}
+
+func TestGenericTable(t *testing.T) {
+	openTestDB(t, "generictest")
+	tbl := sqlchemy.NewTable[genericTestTable]("generic_test_tbl")
+	err := tbl.Sync()
+	if err != nil {
+		t.Fatalf("sync table fail: %s", err)
+	}
+	for _, row := range []genericTestTable{{Id: "1", Name: "one", Age: 10}, {Id: "2", Name: "two", Age: 20}, {Id: "3", Name: "three", Age: 30}} {
+		row := row
+		err = tbl.Insert(&row)
+		if err != nil {
+			t.Fatalf("Insert fail: %s", err)
+		}
+	}
+
+	row, err := tbl.Query().Equals("id", "2").First()
+	if err != nil {
+		t.Fatalf("First fail: %s", err)
+	}
+	if row.Name != "two" {
+		t.Errorf("First: want two got %s", row.Name)
+	}
+	diffs, err := tbl.Update(row, func(r *genericTestTable) error {
+		r.Age = 21
+		return nil
+	})
+	if err != nil || len(diffs) != 1 {
+		t.Fatalf("Update fail: %v %s", diffs, err)
+	}
+
+	q := tbl.Query()
+	rows, err := q.Filter(sqlchemy.GE(q.Field("age"), 20)).Desc("age").All()
+	if err != nil {
+		t.Fatalf("All fail: %s", err)
+	}
+	if len(rows) != 2 || rows[0].Id != "3" || rows[1].Age != 21 {
+		t.Errorf("All: got %#v", rows)
+	}
+	_, err = tbl.Query().Equals("id", "4").First()
+	if err != sql.ErrNoRows {
+		t.Errorf("First: want %s got %v", sql.ErrNoRows, err)
+	}
+
+	err = tbl.Delete(&genericTestTable{Id: "1"})
+	if err != nil {
+		t.Fatalf("Delete fail: %s", err)
+	}
+	cnt, err := tbl.Query().Count()
+	if err != nil || cnt != 2 {
+		t.Errorf("Count: want 2 got %d %v", cnt, err)
+	}
+	ids := make([]string, 0)
+	err = tbl.Query().IterateByKeyset(1, func(r *genericTestTable) error {
+		ids = append(ids, r.Id)
+		return nil
+	})
+	if err != nil || len(ids) != 2 || ids[0] != "2" {
+		t.Errorf("IterateByKeyset: got %v %v", ids, err)
+	}
+}
@@ -0,0 +1,30 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+// openTestDB opens the shared in-memory database of the name as the default database, which is closed when the test ends
+func openTestDB(tb testing.TB, name string) {
+	dbConn, err := sql.Open("sqlite3", "file:"+name+"?mode=memory&cache=shared")
+	if err != nil {
+		tb.Fatalf("open sqlite memory db fail: %s", err)
+	}
+	tb.Cleanup(func() { dbConn.Close() })
+	sqlchemy.SetDBWithNameBackend(dbConn, sqlchemy.DefaultDB, sqlchemy.SQLiteBackend)
+}
+
+// syncTestTable creates the table of the name for the struct in the default database
+func syncTestTable(tb testing.TB, dt interface{}, name string) *sqlchemy.STableSpec {
+	ts := sqlchemy.NewTableSpecFromStruct(dt, name)
+	err := ts.Sync()
+	if err != nil {
+		tb.Fatalf("sync table %s fail: %s", name, err)
+	}
+	return ts
+}
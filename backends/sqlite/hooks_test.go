@@ -0,0 +1,221 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nyl1001/pkg/errors"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+type hookTestTable struct {
+	Id   string `primary:"true" width:"32"`
+	Name string `width:"64"`
+
+	events []string
+}
+
+func (h *hookTestTable) AfterInsert(ctx context.Context) error {
+	h.events = append(h.events, "AfterInsert")
+	return nil
+}
+
+func (h *hookTestTable) AfterUpdate(ctx context.Context, diffs sqlchemy.UpdateDiffs) error {
+	for k := range diffs {
+		h.events = append(h.events, "AfterUpdate:"+k)
+	}
+	return nil
+}
+
+func (h *hookTestTable) BeforeDelete(ctx context.Context) error {
+	h.events = append(h.events, "BeforeDelete")
+	if h.Name == "protected" {
+		return errors.Error("protected")
+	}
+	return nil
+}
+
+func (h *hookTestTable) AfterDelete(ctx context.Context) error {
+	h.events = append(h.events, "AfterDelete")
+	return nil
+}
+
+type hookLogTable struct {
+	Id     int    `primary:"true" auto_increment:"true"`
+	Action string `width:"32"`
+	Target string `width:"32"`
+}
+
+// hookLogger logs the operations into the log table within the transaction of the operation
+type hookLogger struct {
+	sqlchemy.SBaseTableHook
+
+	logs *sqlchemy.STableSpec
+	fail string
+}
+
+func (l *hookLogger) log(ctx context.Context, action string, dt interface{}) error {
+	if action == l.fail {
+		return errors.Error("fail " + action)
+	}
+	return l.logs.InsertContext(ctx, &hookLogTable{Action: action, Target: dt.(*hookTestTable).Id})
+}
+
+func (l *hookLogger) AfterInsert(ctx context.Context, ts *sqlchemy.STableSpec, dt interface{}) error {
+	return l.log(ctx, "insert", dt)
+}
+
+func (l *hookLogger) AfterUpdate(ctx context.Context, ts *sqlchemy.STableSpec, dt interface{}, diffs sqlchemy.UpdateDiffs) error {
+	return l.log(ctx, "update", dt)
+}
+
+func (l *hookLogger) AfterDelete(ctx context.Context, ts *sqlchemy.STableSpec, dt interface{}) error {
+	return l.log(ctx, "delete", dt)
+}
+
+func TestHooks(t *testing.T) {
+	openTestDB(t, "hookstest")
+	ts := syncTestTable(t, hookTestTable{}, "hook_test_tbl")
+	logs := syncTestTable(t, hookLogTable{}, "hook_log_tbl")
+	logger := &hookLogger{logs: logs}
+	ts.AddHook(logger)
+
+	row := &hookTestTable{Id: "1", Name: "one"}
+	err := ts.Insert(row)
+	if err != nil {
+		t.Fatalf("Insert fail: %s", err)
+	}
+	_, err = ts.Update(row, func() error {
+		row.Name = "protected"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update fail: %s", err)
+	}
+	err = ts.Delete(row)
+	if err == nil {
+		t.Errorf("Delete should be aborted by BeforeDelete")
+	}
+	want := []string{"AfterInsert", "AfterUpdate:name", "BeforeDelete"}
+	if len(row.events) != len(want) {
+		t.Fatalf("events: want %v got %v", want, row.events)
+	}
+	for i := range want {
+		if row.events[i] != want[i] {
+			t.Errorf("event %d: want %s got %s", i, want[i], row.events[i])
+		}
+	}
+
+	// the failure of the table hook rolls back the statement
+	logger.fail = "update"
+	_, err = ts.Update(row, func() error {
+		row.Name = "two"
+		return nil
+	})
+	if err == nil {
+		t.Errorf("Update should fail by the table hook")
+	}
+	got := hookTestTable{}
+	err = ts.Query().Equals("id", "1").First(&got)
+	if err != nil || got.Name != "protected" {
+		t.Errorf("Update should be rolled back, got %s %v", got.Name, err)
+	}
+
+	logger.fail = ""
+	row.Name = "deletable"
+	err = ts.Delete(row)
+	if err != nil {
+		t.Fatalf("Delete fail: %s", err)
+	}
+	if cnt, _ := ts.Query().CountWithError(); cnt != 0 {
+		t.Errorf("Delete: want 0 rows got %d", cnt)
+	}
+	actions := make([]hookLogTable, 0)
+	err = logs.Query().Asc("id").All(&actions)
+	if err != nil {
+		t.Fatalf("query logs fail: %s", err)
+	}
+	wantActions := []string{"insert", "update", "delete"}
+	if len(actions) != len(wantActions) {
+		t.Fatalf("logs: want %v got %#v", wantActions, actions)
+	}
+	for i := range wantActions {
+		if actions[i].Action != wantActions[i] || actions[i].Target != "1" {
+			t.Errorf("log %d: want %s got %#v", i, wantActions[i], actions[i])
+		}
+	}
+}
+
+type hookPlainTable struct {
+	Id    string `primary:"true" width:"32"`
+	Count int    `default:"0"`
+}
+
+func TestHooksBulkWrites(t *testing.T) {
+	openTestDB(t, "hooksbulktest")
+	ts := syncTestTable(t, hookPlainTable{}, "hook_plain_tbl")
+	logs := syncTestTable(t, hookLogTable{}, "hook_bulk_log_tbl")
+	row := &hookPlainTable{Id: "1"}
+	err := ts.Insert(row)
+	if err != nil {
+		t.Fatalf("Insert fail: %s", err)
+	}
+	ts.AddHook(&hookLogger{logs: logs})
+
+	// the bulk and field-level writes do not run the hooks, so they are refused
+	target := ts.Target()
+	cond := sqlchemy.Equals(target.Field("id"), "1")
+	writes := map[string]func() error{
+		"InsertOrUpdate": func() error {
+			return ts.InsertOrUpdate(&hookPlainTable{Id: "1", Count: 2})
+		},
+		"InsertBatch": func() error {
+			return ts.InsertBatch([]interface{}{&hookPlainTable{Id: "2"}})
+		},
+		"InsertOrUpdateBatch": func() error {
+			return ts.InsertOrUpdateBatch([]interface{}{&hookPlainTable{Id: "2"}})
+		},
+		"InsertFromQuery": func() error {
+			_, err := ts.InsertFromQuery(ts.Query().Equals("id", "1"))
+			return err
+		},
+		"UpdateWhere": func() error {
+			_, err := ts.UpdateWhere(cond, map[string]interface{}{"count": 1})
+			return err
+		},
+		"UpdateFields": func() error {
+			return ts.UpdateFields(row, map[string]interface{}{"count": 1})
+		},
+		"Increment": func() error {
+			return ts.Increment(&hookPlainTable{Id: "1", Count: 1}, nil)
+		},
+		"DeleteFrom": func() error {
+			return ts.DeleteFrom(map[string]interface{}{"id": "1"})
+		},
+		"DeleteWhere": func() error {
+			_, err := ts.DeleteWhere(cond)
+			return err
+		},
+		"HardDeleteWhere": func() error {
+			_, err := ts.HardDeleteWhere(cond)
+			return err
+		},
+	}
+	for name, write := range writes {
+		if err := write(); errors.Cause(err) != sqlchemy.ErrNotSupported {
+			t.Errorf("%s: want ErrNotSupported got %v", name, err)
+		}
+	}
+	cnt, err := ts.Query().CountWithError()
+	if err != nil || cnt != 1 {
+		t.Errorf("want the row untouched, got %d rows %v", cnt, err)
+	}
+
+	// the bulk writes of the records implementing the hook interfaces are refused as well
+	hooked := syncTestTable(t, hookTestTable{}, "hook_model_tbl")
+	err = hooked.InsertBatch([]interface{}{&hookTestTable{Id: "1"}})
+	if errors.Cause(err) != sqlchemy.ErrNotSupported {
+		t.Errorf("InsertBatch of hooked records: want ErrNotSupported got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package sqlite
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nyl1001/pkg/errors"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+type batchTestTable struct {
+	Id   string `primary:"true" width:"32"`
+	Name string `width:"64"`
+	Age  int    `default:"0"`
+}
+
+func TestInsertBatch(t *testing.T) {
+	openTestDB(t, "batchtest")
+	ts := syncTestTable(t, batchTestTable{}, "batch_test_tbl")
+
+	dataList := make([]interface{}, 1000)
+	for i := range dataList {
+		dataList[i] = &batchTestTable{Id: fmt.Sprintf("id%d", i), Name: fmt.Sprintf("name%d", i), Age: i}
+	}
+	// 3 placeholders per row, 333 rows per statement
+	if cnt := len(ts.InsertBatchSqlPrep(dataList)); cnt != 4 {
+		t.Errorf("want 4 statements got %d", cnt)
+	}
+	err := ts.InsertBatch(dataList)
+	if err != nil {
+		t.Fatalf("InsertBatch fail: %s", err)
+	}
+	cnt, err := ts.Query().CountWithError()
+	if err != nil || cnt != 1000 {
+		t.Fatalf("count want 1000 got %d %v", cnt, err)
+	}
+
+	// the statement containing duplicates fails, the other rows are inserted row by row
+	err = ts.InsertBatch([]interface{}{
+		&batchTestTable{Id: "new1", Name: "new1"},
+		&batchTestTable{Id: "id1", Name: "dup1"},
+		&batchTestTable{Id: "new2", Name: "new2"},
+		&batchTestTable{Id: "id2", Name: "dup2"},
+	})
+	if err == nil {
+		t.Fatalf("InsertBatch with duplicates should fail")
+	}
+	if errs, ok := err.(interface{ Errors() []error }); !ok || len(errs.Errors()) != 2 {
+		t.Errorf("want 2 row errors got %s", err)
+	}
+	cnt, err = ts.Query().In("id", []string{"new1", "new2"}).CountWithError()
+	if err != nil || cnt != 2 {
+		t.Errorf("count want 2 got %d %v", cnt, err)
+	}
+
+	// the rows are not retried in a transaction, the error of the statement is returned as is
+	err = ts.Database().RunInTx(func(tx *sqlchemy.STx) error {
+		return ts.InsertBatchContext(tx.Context(), []interface{}{
+			&batchTestTable{Id: "new3", Name: "new3"},
+			&batchTestTable{Id: "id3", Name: "dup3"},
+		})
+	})
+	if err == nil {
+		t.Fatalf("InsertBatch with duplicates in transaction should fail")
+	}
+	if _, ok := err.(interface{ Errors() []error }); ok {
+		t.Errorf("want the error of the multi-row statement got %s", err)
+	}
+	cnt, err = ts.Query().Equals("id", "new3").CountWithError()
+	if err != nil || cnt != 0 {
+		t.Errorf("count want 0 got %d %v", cnt, err)
+	}
+}
+
+type upsertTestTable struct {
+	Id        string    `primary:"true" width:"32"`
+	Name      string    `width:"64"`
+	Age       int       `default:"0"`
+	UpdatedAt time.Time `updated_at:"true" nullable:"false"`
+	Version   int       `auto_version:"true" default:"0"`
+}
+
+func TestInsertOrUpdateBatch(t *testing.T) {
+	openTestDB(t, "upserttest")
+	ts := syncTestTable(t, upsertTestTable{}, "upsert_test_tbl")
+
+	err := ts.InsertOrUpdateBatch([]interface{}{
+		&upsertTestTable{Id: "1", Name: "a", Age: 10},
+		&upsertTestTable{Id: "2", Name: "b", Age: 20},
+	})
+	if err != nil {
+		t.Fatalf("insert fail: %s", err)
+	}
+	// only name is overwritten on conflict
+	err = ts.InsertOrUpdateBatch([]interface{}{
+		&upsertTestTable{Id: "2", Name: "bb", Age: 21},
+		&upsertTestTable{Id: "3", Name: "c", Age: 30},
+	}, "name")
+	if err != nil {
+		t.Fatalf("upsert fail: %s", err)
+	}
+
+	rows := make([]upsertTestTable, 0)
+	err = ts.Query().Asc("id").All(&rows)
+	if err != nil {
+		t.Fatalf("query fail: %s", err)
+	}
+	want := []struct {
+		name    string
+		age     int
+		version int
+	}{
+		{"a", 10, 0},
+		{"bb", 20, 1},
+		{"c", 30, 0},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("want %d rows got %d", len(want), len(rows))
+	}
+	for i := range want {
+		if rows[i].Name != want[i].name || rows[i].Age != want[i].age || rows[i].Version != want[i].version {
+			t.Errorf("row %d: want %v got %#v", i, want[i], rows[i])
+		}
+		if rows[i].UpdatedAt.IsZero() {
+			t.Errorf("row %d: updated_at not set", i)
+		}
+	}
+
+	err = ts.InsertOrUpdateBatch([]interface{}{&upsertTestTable{Id: "1"}}, "id")
+	if errors.Cause(err) != errors.ErrInvalidFormat {
+		t.Errorf("want ErrInvalidFormat for primary key update field got %v", err)
+	}
+
+	// a table of primary keys only has nothing to update, the duplicate rows are skipped
+	type upsertKeyTable struct {
+		Id string `primary:"true" width:"32"`
+	}
+	kts := syncTestTable(t, upsertKeyTable{}, "upsert_key_tbl")
+	err = kts.InsertOrUpdateBatch([]interface{}{&upsertKeyTable{Id: "1"}, &upsertKeyTable{Id: "2"}})
+	if err != nil {
+		t.Fatalf("insert keys fail: %s", err)
+	}
+	err = kts.InsertOrUpdateBatch([]interface{}{&upsertKeyTable{Id: "2"}, &upsertKeyTable{Id: "3"}})
+	if err != nil {
+		t.Fatalf("upsert keys fail: %s", err)
+	}
+	cnt, err := kts.Query().CountWithError()
+	if err != nil || cnt != 3 {
+		t.Errorf("count want 3 got %d %v", cnt, err)
+	}
+}
@@ -0,0 +1,167 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+type metricTestTable struct {
+	Id     int    `primary:"true"`
+	Host   string `width:"32"`
+	Hour   int    `default:"0"`
+	Metric int    `default:"0"`
+}
+
+type hourlyTestTable struct {
+	Host   string `primary:"true" width:"32"`
+	Hour   int    `primary:"true"`
+	Metric int    `default:"0"`
+}
+
+func TestInsertFromQuery(t *testing.T) {
+	openTestDB(t, "insertquerytest")
+	raw := syncTestTable(t, metricTestTable{}, "metric_test_tbl")
+	hourly := syncTestTable(t, hourlyTestTable{}, "hourly_test_tbl")
+	insertMetrics := func(start int, metrics []metricTestTable) {
+		for i := range metrics {
+			metrics[i].Id = start + i
+			err := raw.Insert(&metrics[i])
+			if err != nil {
+				t.Fatalf("insert fail: %s", err)
+			}
+		}
+	}
+	rollup := func(minId int) *sqlchemy.SQuery {
+		r := raw.Instance()
+		return r.Query(r.Field("host"), r.Field("hour"), sqlchemy.SUM("metric", r.Field("metric"))).
+			GE("id", minId).GroupBy(r.Field("host"), r.Field("hour"))
+	}
+	sumOf := func(host string, hour int) int {
+		row := hourlyTestTable{}
+		err := hourly.Query().Equals("host", host).Equals("hour", hour).First(&row)
+		if err != nil {
+			t.Fatalf("query %s %d fail: %s", host, hour, err)
+		}
+		return row.Metric
+	}
+
+	insertMetrics(1, []metricTestTable{
+		{Host: "a", Hour: 1, Metric: 1},
+		{Host: "a", Hour: 1, Metric: 2},
+		{Host: "b", Hour: 1, Metric: 5},
+	})
+	cnt, err := hourly.InsertFromQuery(rollup(1))
+	if err != nil {
+		t.Fatalf("InsertFromQuery fail: %s", err)
+	}
+	if cnt != 2 {
+		t.Errorf("InsertFromQuery: want 2 got %d", cnt)
+	}
+	if sum := sumOf("a", 1); sum != 3 {
+		t.Errorf("want 3 got %d", sum)
+	}
+
+	insertMetrics(4, []metricTestTable{
+		{Host: "a", Hour: 1, Metric: 10},
+		{Host: "a", Hour: 2, Metric: 7},
+	})
+	_, err = hourly.InsertOrUpdateFromQuery(rollup(4), "host", "hour", "metric")
+	if err != nil {
+		t.Fatalf("InsertOrUpdateFromQuery fail: %s", err)
+	}
+	if sum := sumOf("a", 1); sum != 10 {
+		t.Errorf("want 10 got %d", sum)
+	}
+	if sum := sumOf("a", 2); sum != 7 {
+		t.Errorf("want 7 got %d", sum)
+	}
+	if sum := sumOf("b", 1); sum != 5 {
+		t.Errorf("want 5 got %d", sum)
+	}
+}
+
+type hostHourTestTable struct {
+	Host string `primary:"true" width:"32"`
+	Hour int    `primary:"true"`
+}
+
+func TestInsertOrUpdateFromQueryPrimaryKeysOnly(t *testing.T) {
+	openTestDB(t, "insertquerypktest")
+	raw := syncTestTable(t, metricTestTable{}, "metric_pk_test_tbl")
+	hours := syncTestTable(t, hostHourTestTable{}, "host_hour_test_tbl")
+	metrics := []metricTestTable{
+		{Id: 1, Host: "a", Hour: 1},
+		{Id: 2, Host: "a", Hour: 2},
+		{Id: 3, Host: "b", Hour: 1},
+	}
+	for i := range metrics {
+		err := raw.Insert(&metrics[i])
+		if err != nil {
+			t.Fatalf("insert fail: %s", err)
+		}
+	}
+	query := func(maxId int) *sqlchemy.SQuery {
+		r := raw.Instance()
+		return r.Query(r.Field("host"), r.Field("hour")).LE("id", maxId)
+	}
+	for _, maxId := range []int{2, 3} {
+		_, err := hours.InsertOrUpdateFromQuery(query(maxId))
+		if err != nil {
+			t.Fatalf("InsertOrUpdateFromQuery fail: %s", err)
+		}
+	}
+	cnt, err := hours.Query().CountWithError()
+	if err != nil {
+		t.Fatalf("count fail: %s", err)
+	}
+	if cnt != 3 {
+		t.Errorf("want 3 got %d", cnt)
+	}
+}
+
+type rollupTestTable struct {
+	Id        int       `primary:"true"`
+	Metric    int       `default:"0"`
+	CreatedAt time.Time `nullable:"false" created_at:"true"`
+	UpdatedAt time.Time `nullable:"false" updated_at:"true"`
+	Version   int       `auto_version:"true" default:"0"`
+}
+
+func TestInsertFromQueryTimestamps(t *testing.T) {
+	openTestDB(t, "insertquerytimetest")
+	raw := syncTestTable(t, metricTestTable{}, "metric_time_test_tbl")
+	rollups := syncTestTable(t, rollupTestTable{}, "rollup_test_tbl")
+	err := raw.Insert(&metricTestTable{Id: 1, Metric: 5})
+	if err != nil {
+		t.Fatalf("insert fail: %s", err)
+	}
+	r := raw.Instance()
+	q := r.Query(r.Field("id"), r.Field("metric"))
+	fetch := func() rollupTestTable {
+		row := rollupTestTable{}
+		err := rollups.Query().Equals("id", 1).First(&row)
+		if err != nil {
+			t.Fatalf("query fail: %s", err)
+		}
+		return row
+	}
+
+	_, err = rollups.InsertFromQuery(q, "id", "metric")
+	if err != nil {
+		t.Fatalf("InsertFromQuery fail: %s", err)
+	}
+	row := fetch()
+	if row.Metric != 5 || row.CreatedAt.IsZero() || row.UpdatedAt.IsZero() || row.Version != 1 {
+		t.Errorf("InsertFromQuery: want metric 5, timestamps and version 1, got %#v", row)
+	}
+
+	_, err = rollups.InsertOrUpdateFromQuery(q, "id", "metric")
+	if err != nil {
+		t.Fatalf("InsertOrUpdateFromQuery fail: %s", err)
+	}
+	if row = fetch(); row.Version != 2 {
+		t.Errorf("InsertOrUpdateFromQuery: want version 2 got %d", row.Version)
+	}
+}
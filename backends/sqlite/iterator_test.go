@@ -0,0 +1,109 @@
+package sqlite
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nyl1001/pkg/errors"
+)
+
+type iteratorTestTable struct {
+	Zone string `primary:"true" width:"16"`
+	Id   int    `primary:"true"`
+	Name string `width:"64"`
+
+	Queried bool `ignore:"true"`
+}
+
+func (t *iteratorTestTable) AfterQuery() {
+	t.Queried = true
+}
+
+func TestIterate(t *testing.T) {
+	openTestDB(t, "iteratortest")
+	ts := syncTestTable(t, iteratorTestTable{}, "iterator_test_tbl")
+	rows := make([]interface{}, 0)
+	for _, zone := range []string{"z2", "z1"} {
+		for i := 5; i > 0; i-- {
+			rows = append(rows, &iteratorTestTable{Zone: zone, Id: i, Name: fmt.Sprintf("%s-%d", zone, i)})
+		}
+	}
+	err := ts.InsertBatch(rows)
+	if err != nil {
+		t.Fatalf("insert fail: %s", err)
+	}
+
+	t.Run("iterator", func(t *testing.T) {
+		it, err := ts.Query().Equals("zone", "z1").Asc("id").Iterator()
+		if err != nil {
+			t.Fatalf("Iterator fail: %s", err)
+		}
+		defer it.Close()
+		cnt := 0
+		for it.Next() {
+			row := iteratorTestTable{}
+			err = it.Scan(&row)
+			if err != nil {
+				t.Fatalf("Scan fail: %s", err)
+			}
+			cnt++
+			if row.Id != cnt || !row.Queried {
+				t.Errorf("row %d: got %#v", cnt, row)
+			}
+		}
+		if it.Err() != nil || cnt != 5 {
+			t.Errorf("want 5 rows got %d %v", cnt, it.Err())
+		}
+	})
+
+	t.Run("iterate", func(t *testing.T) {
+		errStop := errors.Error("stop")
+		names := make([]string, 0)
+		err := ts.Query().Asc("name").Iterate(func(row *iteratorTestTable) error {
+			names = append(names, row.Name)
+			if len(names) == 3 {
+				return errStop
+			}
+			return nil
+		})
+		if err != errStop {
+			t.Errorf("Iterate: want %s got %v", errStop, err)
+		}
+		if len(names) != 3 || names[2] != "z1-3" {
+			t.Errorf("Iterate: got %v", names)
+		}
+		err = ts.Query().Iterate(func(row iteratorTestTable) error { return nil })
+		if err == nil {
+			t.Errorf("Iterate with invalid func should fail")
+		}
+	})
+
+	t.Run("keyset", func(t *testing.T) {
+		names := make([]string, 0)
+		err := ts.Query().NotEquals("id", 3).IterateByKeyset(3, func(row *iteratorTestTable) error {
+			if !row.Queried {
+				t.Errorf("AfterQuery not called for %s", row.Name)
+			}
+			names = append(names, row.Name)
+			// no cursor is held by the keyset iteration
+			return ts.InsertOrUpdate(&iteratorTestTable{Zone: row.Zone, Id: row.Id, Name: row.Name + "!"})
+		})
+		if err != nil {
+			t.Fatalf("IterateByKeyset fail: %s", err)
+		}
+		want := []string{"z1-1", "z1-2", "z1-4", "z1-5", "z2-1", "z2-2", "z2-4", "z2-5"}
+		if fmt.Sprintf("%v", names) != fmt.Sprintf("%v", want) {
+			t.Errorf("IterateByKeyset: want %v got %v", want, names)
+		}
+		for _, size := range []int{0, -1} {
+			err = ts.Query().IterateByKeyset(size, func(row *iteratorTestTable) error { return nil })
+			if errors.Cause(err) != errors.ErrInvalidFormat {
+				t.Errorf("IterateByKeyset of chunk size %d: want %s got %v", size, errors.ErrInvalidFormat, err)
+			}
+		}
+		err = ts.Query().Asc("id").IterateByKeyset(3, func(row *iteratorTestTable) error { return nil })
+		if errors.Cause(err) != errors.ErrNotSupported {
+			t.Errorf("IterateByKeyset of ordered query: want %s got %v", errors.ErrNotSupported, err)
+		}
+	})
+}
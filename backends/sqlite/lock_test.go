@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/nyl1001/pkg/errors"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+type lockTestTable struct {
+	Id   string `primary:"true" width:"32"`
+	Name string `width:"64"`
+}
+
+func TestLockingNotSupported(t *testing.T) {
+	openTestDB(t, "locktest")
+	ts := syncTestTable(t, lockTestTable{}, "lock_test_tbl")
+
+	sqlchemy.ResetTableID()
+	q := ts.Query().ForUpdate().SkipLocked()
+	// the unsupported lock is kept in the statement, so that it is never executed without lock
+	want := "SELECT `t1`.`id`, `t1`.`name` FROM `lock_test_tbl` AS `t1` FOR UPDATE SKIP LOCKED"
+	if got := q.String(); got != want {
+		t.Errorf("want %s got %s", want, got)
+	}
+	_, err := q.Rows()
+	if errors.Cause(err) != sqlchemy.ErrNotSupported {
+		t.Errorf("Rows: want %s got %v", sqlchemy.ErrNotSupported, err)
+	}
+	rows := make([]lockTestTable, 0)
+	err = q.All(&rows)
+	if errors.Cause(err) != sqlchemy.ErrNotSupported {
+		t.Errorf("All: want %s got %v", sqlchemy.ErrNotSupported, err)
+	}
+	row := lockTestTable{}
+	err = q.First(&row)
+	if errors.Cause(err) != sqlchemy.ErrNotSupported {
+		t.Errorf("First: want %s got %v", sqlchemy.ErrNotSupported, err)
+	}
+	_, err = q.FirstStringMap()
+	if errors.Cause(err) != sqlchemy.ErrNotSupported {
+		t.Errorf("FirstStringMap: want %s got %v", sqlchemy.ErrNotSupported, err)
+	}
+	_, err = q.RowWithError()
+	if errors.Cause(err) != sqlchemy.ErrNotSupported {
+		t.Errorf("RowWithError: want %s got %v", sqlchemy.ErrNotSupported, err)
+	}
+	var id string
+	err = q.Row().Scan(&id)
+	if err == nil {
+		t.Errorf("Row: want error of the statement rejected by sqlite")
+	}
+	cnt, err := q.CountWithError()
+	if err != nil || cnt != 0 {
+		t.Errorf("CountWithError: want 0 got %d %v", cnt, err)
+	}
+}
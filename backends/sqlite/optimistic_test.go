@@ -0,0 +1,71 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/nyl1001/pkg/errors"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+type optimisticTestTable struct {
+	Id      string `primary:"true" width:"32"`
+	Name    string `width:"64"`
+	Version int    `auto_version:"true" optimistic_lock:"true" default:"0"`
+}
+
+func TestOptimisticLock(t *testing.T) {
+	openTestDB(t, "optimistictest")
+	ts := syncTestTable(t, optimisticTestTable{}, "optimistic_test_tbl")
+
+	err := ts.Insert(&optimisticTestTable{Id: "1", Name: "origin"})
+	if err != nil {
+		t.Fatalf("insert fail: %s", err)
+	}
+
+	first := optimisticTestTable{}
+	second := optimisticTestTable{}
+	err = ts.Query().Equals("id", "1").First(&first)
+	if err != nil {
+		t.Fatalf("query first fail: %s", err)
+	}
+	err = ts.Query().Equals("id", "1").First(&second)
+	if err != nil {
+		t.Fatalf("query second fail: %s", err)
+	}
+
+	_, err = ts.Update(&first, func() error {
+		first.Name = "first"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("first update fail: %s", err)
+	}
+	if first.Version != second.Version+1 {
+		t.Errorf("version want %d got %d", second.Version+1, first.Version)
+	}
+
+	_, err = ts.Update(&second, func() error {
+		second.Name = "second"
+		return nil
+	})
+	if errors.Cause(err) != sqlchemy.ErrConcurrentModification {
+		t.Fatalf("second update: want %s got %v", sqlchemy.ErrConcurrentModification, err)
+	}
+
+	// retry on a fresh copy
+	err = ts.Query().Equals("id", "1").First(&second)
+	if err != nil {
+		t.Fatalf("reload fail: %s", err)
+	}
+	_, err = ts.Update(&second, func() error {
+		second.Name = "second"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("retry update fail: %s", err)
+	}
+	if second.Name != "second" || second.Version != first.Version+1 {
+		t.Errorf("unexpected record after retry: %#v", second)
+	}
+}
@@ -0,0 +1,275 @@
+package sqlite
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/nyl1001/pkg/errors"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+type pageTestTable struct {
+	Id      int    `primary:"true"`
+	Name    string `width:"32"`
+	Score   *int   `nullable:"true"`
+	GroupId int    `nullable:"false"`
+}
+
+type pageTestGroup struct {
+	Id    int    `primary:"true"`
+	Title string `width:"32" nullable:"false"`
+}
+
+func TestPageAfter(t *testing.T) {
+	openTestDB(t, "pagetest")
+	ts := syncTestTable(t, pageTestTable{}, "page_test_tbl")
+	groups := syncTestTable(t, pageTestGroup{}, "page_test_groups_tbl")
+	rows := make([]interface{}, 0)
+	for i := 1; i <= 20; i++ {
+		row := &pageTestTable{Id: i, Name: fmt.Sprintf("name%d", i%4), GroupId: i%3 + 1}
+		if i%5 != 0 {
+			score := i % 7
+			row.Score = &score
+		}
+		rows = append(rows, row)
+	}
+	err := ts.InsertBatch(rows)
+	if err != nil {
+		t.Fatalf("insert fail: %s", err)
+	}
+	err = groups.InsertBatch([]interface{}{&pageTestGroup{Id: 1, Title: "c"}, &pageTestGroup{Id: 2, Title: "a"}, &pageTestGroup{Id: 3, Title: "b"}})
+	if err != nil {
+		t.Fatalf("insert groups fail: %s", err)
+	}
+
+	// pages walks the pages of 3 rows of the query and returns the ids of the rows
+	pages := func(t *testing.T, query func() *sqlchemy.SQuery, orderFields ...interface{}) []int {
+		ids := make([]int, 0)
+		cursor := ""
+		for {
+			q, err := query().PageAfter(cursor, orderFields...)
+			if err != nil {
+				t.Fatalf("PageAfter fail: %s", err)
+			}
+			results := make([]pageTestTable, 0)
+			err = q.Limit(3).All(&results)
+			if err != nil {
+				t.Fatalf("All fail: %s", err)
+			}
+			for _, r := range results {
+				ids = append(ids, r.Id)
+			}
+			if len(results) < 3 {
+				return ids
+			}
+			cursor, err = q.PageCursor(&results[len(results)-1])
+			if err != nil {
+				t.Fatalf("PageCursor fail: %s", err)
+			}
+		}
+	}
+	all := func(t *testing.T, q *sqlchemy.SQuery) []int {
+		results := make([]pageTestTable, 0)
+		err := q.All(&results)
+		if err != nil {
+			t.Fatalf("All fail: %s", err)
+		}
+		ids := make([]int, len(results))
+		for i, r := range results {
+			ids[i] = r.Id
+		}
+		return ids
+	}
+
+	t.Run("primary keys", func(t *testing.T) {
+		got := pages(t, func() *sqlchemy.SQuery { return ts.Query() })
+		want := all(t, ts.Query().Asc("id"))
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("want %v got %v", want, got)
+		}
+	})
+
+	t.Run("mixed orders and nulls", func(t *testing.T) {
+		got := pages(t, func() *sqlchemy.SQuery { return ts.Query().Desc("score").Asc("name") }, "id")
+		want := all(t, ts.Query().Desc("score").Asc("name").Asc("id"))
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("want %v got %v", want, got)
+		}
+	})
+
+	t.Run("row values", func(t *testing.T) {
+		got := pages(t, func() *sqlchemy.SQuery { return ts.Query().Desc("group_id").Desc("id") })
+		want := all(t, ts.Query().Desc("group_id").Desc("id"))
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("want %v got %v", want, got)
+		}
+	})
+
+	t.Run("nulls ascending", func(t *testing.T) {
+		got := pages(t, func() *sqlchemy.SQuery { return ts.Query().Asc("score") }, "id")
+		want := all(t, ts.Query().Asc("score").Asc("id"))
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("want %v got %v", want, got)
+		}
+	})
+
+	t.Run("join", func(t *testing.T) {
+		query := func() *sqlchemy.SQuery {
+			ti := ts.Instance()
+			gi := groups.Instance()
+			q := ti.Query(ti.Field("id"), ti.Field("name"), gi.Field("title"))
+			q = q.Join(gi, sqlchemy.Equals(ti.Field("group_id"), gi.Field("id")))
+			return q.Desc(gi.Field("title")).Asc(ti.Field("id"))
+		}
+		got := make([]string, 0)
+		cursor := ""
+		for {
+			q, err := query().PageAfter(cursor)
+			if err != nil {
+				t.Fatalf("PageAfter fail: %s", err)
+			}
+			results, err := q.Limit(3).AllStringMap()
+			if err != nil {
+				t.Fatalf("AllStringMap fail: %s", err)
+			}
+			for _, r := range results {
+				got = append(got, r["id"])
+			}
+			if len(results) < 3 {
+				break
+			}
+			cursor, err = q.PageCursor(results[len(results)-1])
+			if err != nil {
+				t.Fatalf("PageCursor fail: %s", err)
+			}
+		}
+		want := make([]string, 0)
+		results, err := query().AllStringMap()
+		if err != nil {
+			t.Fatalf("AllStringMap fail: %s", err)
+		}
+		for _, r := range results {
+			want = append(want, r["id"])
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("want %v got %v", want, got)
+		}
+	})
+
+	// the order columns of both tables are named id
+	joinQuery := func(fields func(ti, gi *sqlchemy.STable) []sqlchemy.IQueryField) func() *sqlchemy.SQuery {
+		return func() *sqlchemy.SQuery {
+			ti := ts.InstanceWithAlias("item")
+			gi := groups.InstanceWithAlias("grp")
+			q := ti.Query(fields(ti, gi)...).Join(gi, sqlchemy.Equals(ti.Field("group_id"), gi.Field("id")))
+			return q.Desc(gi.Field("id")).Asc(ti.Field("id"))
+		}
+	}
+
+	t.Run("join ordered by same-named columns", func(t *testing.T) {
+		query := joinQuery(func(ti, gi *sqlchemy.STable) []sqlchemy.IQueryField {
+			return []sqlchemy.IQueryField{ti.Field("id"), ti.Field("name"), gi.Field("id", "grp_id")}
+		})
+		got := make([]string, 0)
+		cursor := ""
+		for page := 0; page < 10; page++ {
+			q, err := query().PageAfter(cursor)
+			if err != nil {
+				t.Fatalf("PageAfter fail: %s", err)
+			}
+			results, err := q.Limit(3).AllStringMap()
+			if err != nil {
+				t.Fatalf("AllStringMap fail: %s", err)
+			}
+			for _, r := range results {
+				got = append(got, r["grp_id"]+"/"+r["id"])
+			}
+			if len(results) < 3 {
+				break
+			}
+			cursor, err = q.PageCursor(results[len(results)-1])
+			if err != nil {
+				t.Fatalf("PageCursor fail: %s", err)
+			}
+		}
+		want := make([]string, 0)
+		results, err := query().AllStringMap()
+		if err != nil {
+			t.Fatalf("AllStringMap fail: %s", err)
+		}
+		for _, r := range results {
+			want = append(want, r["grp_id"]+"/"+r["id"])
+		}
+		if len(want) != 20 || !reflect.DeepEqual(got, want) {
+			t.Errorf("want %v got %v", want, got)
+		}
+	})
+
+	t.Run("join into nested structs", func(t *testing.T) {
+		type pageJoinResult struct {
+			Item  pageTestTable  `alias:"item"`
+			Group *pageTestGroup `alias:"grp"`
+		}
+		query := joinQuery(func(ti, gi *sqlchemy.STable) []sqlchemy.IQueryField {
+			return append(ti.Fields(), gi.Fields()...)
+		})
+		ids := func(results []pageJoinResult) []string {
+			ret := make([]string, len(results))
+			for i, r := range results {
+				ret[i] = fmt.Sprintf("%d/%d", r.Group.Id, r.Item.Id)
+			}
+			return ret
+		}
+		got := make([]string, 0)
+		cursor := ""
+		for page := 0; page < 10; page++ {
+			q, err := query().PageAfter(cursor)
+			if err != nil {
+				t.Fatalf("PageAfter fail: %s", err)
+			}
+			results := make([]pageJoinResult, 0)
+			err = q.Limit(3).All(&results)
+			if err != nil {
+				t.Fatalf("All fail: %s", err)
+			}
+			got = append(got, ids(results)...)
+			if len(results) < 3 {
+				break
+			}
+			cursor, err = q.PageCursor(&results[len(results)-1])
+			if err != nil {
+				t.Fatalf("PageCursor fail: %s", err)
+			}
+		}
+		results := make([]pageJoinResult, 0)
+		err := query().All(&results)
+		if err != nil {
+			t.Fatalf("All fail: %s", err)
+		}
+		want := ids(results)
+		if len(want) != 20 || !reflect.DeepEqual(got, want) {
+			t.Errorf("want %v got %v", want, got)
+		}
+	})
+
+	t.Run("map row ordered by nullable column", func(t *testing.T) {
+		q := ts.Query().Asc("score").Asc("id")
+		results, err := q.Limit(3).AllStringMap()
+		if err != nil {
+			t.Fatalf("AllStringMap fail: %s", err)
+		}
+		_, err = q.PageCursor(results[len(results)-1])
+		if errors.Cause(err) != sqlchemy.ErrNotSupported {
+			t.Errorf("want ErrNotSupported got %v", err)
+		}
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		_, err := ts.Query().PageAfter("invalid")
+		if err == nil {
+			t.Errorf("PageAfter of an invalid cursor should fail")
+		}
+	})
+}
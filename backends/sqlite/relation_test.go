@@ -0,0 +1,205 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/nyl1001/pkg/errors"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+func init() {
+	// at most 999 variables of a statement, the limit of SQLite before 3.32, see MaxInsertPlaceholders
+	sql.Register("sqlite3_limited", &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			conn.SetLimit(sqlite3.SQLITE_LIMIT_VARIABLE_NUMBER, 999)
+			return nil
+		},
+	})
+}
+
+type relationAuthor struct {
+	Id   int    `primary:"true"`
+	Name string `width:"32"`
+
+	Books []relationBook `ignore:"true"`
+	Tags  []*relationTag `ignore:"true"`
+}
+
+type relationBook struct {
+	Id       int    `primary:"true"`
+	AuthorId int    `nullable:"false"`
+	Title    string `width:"32"`
+
+	Author *relationAuthor `ignore:"true"`
+}
+
+type relationTag struct {
+	Id   int    `primary:"true"`
+	Name string `width:"32"`
+}
+
+type relationAuthorTag struct {
+	AuthorId int `primary:"true"`
+	TagId    int `primary:"true"`
+}
+
+func TestPreload(t *testing.T) {
+	openTestDB(t, "relationtest")
+	authors := syncTestTable(t, relationAuthor{}, "relation_authors_tbl")
+	books := syncTestTable(t, relationBook{}, "relation_books_tbl")
+	tags := syncTestTable(t, relationTag{}, "relation_tags_tbl")
+	authorTags := syncTestTable(t, relationAuthorTag{}, "relation_author_tags_tbl")
+	authors.HasMany("Books", books, "author_id")
+	authors.ManyToMany("Tags", tags, authorTags, "author_id", "tag_id")
+	books.BelongsTo("Author", authors, "author_id")
+
+	inserts := []struct {
+		ts   *sqlchemy.STableSpec
+		rows []interface{}
+	}{
+		{authors, []interface{}{&relationAuthor{Id: 1, Name: "alice"}, &relationAuthor{Id: 2, Name: "bob"}, &relationAuthor{Id: 3, Name: "carol"}}},
+		{books, []interface{}{&relationBook{Id: 1, AuthorId: 1, Title: "a1"}, &relationBook{Id: 2, AuthorId: 1, Title: "a2"}, &relationBook{Id: 3, AuthorId: 2, Title: "b1"}}},
+		{tags, []interface{}{&relationTag{Id: 1, Name: "go"}, &relationTag{Id: 2, Name: "sql"}}},
+		{authorTags, []interface{}{&relationAuthorTag{AuthorId: 1, TagId: 1}, &relationAuthorTag{AuthorId: 1, TagId: 2}, &relationAuthorTag{AuthorId: 2, TagId: 2}}},
+	}
+	for _, ins := range inserts {
+		err := ins.ts.InsertBatch(ins.rows)
+		if err != nil {
+			t.Fatalf("insert %s fail: %s", ins.ts.Name(), err)
+		}
+	}
+
+	t.Run("has many and many to many", func(t *testing.T) {
+		results := make([]relationAuthor, 0)
+		q := authors.Query().Asc("id").Preload("Books", "Tags")
+		err := q.All(&results)
+		if err != nil {
+			t.Fatalf("All fail: %s", err)
+		}
+		if len(results) != 3 {
+			t.Fatalf("want 3 authors got %d", len(results))
+		}
+		wantBooks := []int{2, 1, 0}
+		wantTags := []int{2, 1, 0}
+		for i := range results {
+			if len(results[i].Books) != wantBooks[i] {
+				t.Errorf("author %d: want %d books got %d", results[i].Id, wantBooks[i], len(results[i].Books))
+			}
+			for _, book := range results[i].Books {
+				if book.AuthorId != results[i].Id {
+					t.Errorf("author %d: book %d of author %d", results[i].Id, book.Id, book.AuthorId)
+				}
+			}
+			if len(results[i].Tags) != wantTags[i] {
+				t.Errorf("author %d: want %d tags got %d", results[i].Id, wantTags[i], len(results[i].Tags))
+			}
+		}
+		if len(results[1].Tags) == 1 && results[1].Tags[0].Name != "sql" {
+			t.Errorf("author 2: want tag sql got %s", results[1].Tags[0].Name)
+		}
+	})
+
+	t.Run("belongs to", func(t *testing.T) {
+		book := relationBook{}
+		err := books.Query().Equals("id", 3).Preload("Author").First(&book)
+		if err != nil {
+			t.Fatalf("First fail: %s", err)
+		}
+		if book.Author == nil || book.Author.Name != "bob" {
+			t.Errorf("want author bob got %#v", book.Author)
+		}
+	})
+
+	t.Run("iterate by keyset", func(t *testing.T) {
+		wantBooks := map[int]int{1: 2, 2: 1, 3: 0}
+		cnt := 0
+		err := authors.Query().Preload("Books").IterateByKeyset(2, func(author *relationAuthor) error {
+			cnt++
+			if len(author.Books) != wantBooks[author.Id] {
+				t.Errorf("author %d: want %d books got %d", author.Id, wantBooks[author.Id], len(author.Books))
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("IterateByKeyset fail: %s", err)
+		}
+		if cnt != 3 {
+			t.Errorf("want 3 authors got %d", cnt)
+		}
+	})
+
+	t.Run("iterate", func(t *testing.T) {
+		err := authors.Query().Preload("Books").Iterate(func(author *relationAuthor) error {
+			return nil
+		})
+		if errors.Cause(err) != sqlchemy.ErrNotSupported {
+			t.Errorf("want ErrNotSupported got %v", err)
+		}
+	})
+
+	t.Run("unknown relation", func(t *testing.T) {
+		results := make([]relationBook, 0)
+		err := books.Query().Preload("Publisher").All(&results)
+		if err == nil {
+			t.Errorf("preload unknown relation should fail")
+		}
+	})
+}
+
+func TestPreloadManyRows(t *testing.T) {
+	dbConn, err := sql.Open("sqlite3_limited", "file:relationmanytest?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite memory db fail: %s", err)
+	}
+	t.Cleanup(func() { dbConn.Close() })
+	sqlchemy.SetDBWithNameBackend(dbConn, sqlchemy.DefaultDB, sqlchemy.SQLiteBackend)
+	authors := syncTestTable(t, relationAuthor{}, "relation_many_authors_tbl")
+	books := syncTestTable(t, relationBook{}, "relation_many_books_tbl")
+	authors.HasMany("Books", books, "author_id")
+	books.BelongsTo("Author", authors, "author_id")
+
+	const count = 1200
+	authorRows := make([]interface{}, count)
+	bookRows := make([]interface{}, count)
+	for i := 0; i < count; i++ {
+		authorRows[i] = &relationAuthor{Id: i + 1, Name: "author"}
+		bookRows[i] = &relationBook{Id: i + 1, AuthorId: i + 1, Title: "book"}
+	}
+	for _, ins := range []struct {
+		ts   *sqlchemy.STableSpec
+		rows []interface{}
+	}{{authors, authorRows}, {books, bookRows}} {
+		err = ins.ts.InsertBatch(ins.rows)
+		if err != nil {
+			t.Fatalf("insert %s fail: %s", ins.ts.Name(), err)
+		}
+	}
+
+	authorResults := make([]relationAuthor, 0)
+	err = authors.Query().Preload("Books").All(&authorResults)
+	if err != nil {
+		t.Fatalf("preload books fail: %s", err)
+	}
+	for _, author := range authorResults {
+		if len(author.Books) != 1 || author.Books[0].AuthorId != author.Id {
+			t.Fatalf("author %d: want 1 book got %#v", author.Id, author.Books)
+		}
+	}
+
+	bookResults := make([]relationBook, 0)
+	err = books.Query().Preload("Author").All(&bookResults)
+	if err != nil {
+		t.Fatalf("preload authors fail: %s", err)
+	}
+	for _, book := range bookResults {
+		if book.Author == nil || book.Author.Id != book.AuthorId {
+			t.Fatalf("book %d: want author %d got %#v", book.Id, book.AuthorId, book.Author)
+		}
+	}
+	if len(authorResults) != count || len(bookResults) != count {
+		t.Errorf("want %d authors and books got %d and %d", count, len(authorResults), len(bookResults))
+	}
+}
@@ -0,0 +1,159 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+type scanTestTable struct {
+	Id        int       `primary:"true"`
+	Name      string    `width:"64"`
+	Score     float64   `nullable:"true"`
+	Enabled   bool      `default:"false"`
+	CreatedAt time.Time `created_at:"true"`
+}
+
+func setupScanTestDB(tb testing.TB, name string, count int) *sqlchemy.STableSpec {
+	openTestDB(tb, name)
+	ts := syncTestTable(tb, scanTestTable{}, "scan_test_tbl")
+	rows := make([]interface{}, count)
+	for i := range rows {
+		rows[i] = &scanTestTable{Id: i + 1, Name: fmt.Sprintf("name%d", i), Score: float64(i) + 0.123456789, Enabled: i%2 == 0}
+	}
+	err := ts.InsertBatch(rows)
+	if err != nil {
+		tb.Fatalf("insert fail: %s", err)
+	}
+	return ts
+}
+
+func TestScan(t *testing.T) {
+	ts := setupScanTestDB(t, "scantest", 10)
+
+	rows := make([]scanTestTable, 0)
+	err := ts.Query().Asc("id").All(&rows)
+	if err != nil {
+		t.Fatalf("All fail: %s", err)
+	}
+	if len(rows) != 10 {
+		t.Fatalf("All: want 10 rows got %d", len(rows))
+	}
+	for i, row := range rows {
+		if row.Id != i+1 || row.Name != fmt.Sprintf("name%d", i) || row.Score != float64(i)+0.123456789 || row.Enabled != (i%2 == 0) || row.CreatedAt.IsZero() {
+			t.Errorf("row %d: got %#v", i, row)
+		}
+	}
+
+	row := scanTestTable{}
+	err = ts.Query().Equals("id", 3).First(&row)
+	if err != nil {
+		t.Fatalf("First fail: %s", err)
+	}
+	if row != rows[2] {
+		t.Errorf("First: want %#v got %#v", rows[2], row)
+	}
+	err = ts.Query().Equals("id", 100).First(&row)
+	if err != sql.ErrNoRows {
+		t.Errorf("First: want %s got %v", sql.ErrNoRows, err)
+	}
+}
+
+func BenchmarkScanStringMap(b *testing.B) {
+	ts := setupScanTestDB(b, "scanbenchmap", 1000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q := ts.Query()
+		results, err := q.AllStringMap()
+		if err != nil {
+			b.Fatalf("AllStringMap fail: %s", err)
+		}
+		rows := make([]scanTestTable, len(results))
+		for j := range results {
+			err = q.RowMap2Struct(results[j], &rows[j])
+			if err != nil {
+				b.Fatalf("RowMap2Struct fail: %s", err)
+			}
+		}
+	}
+}
+
+func BenchmarkScanTyped(b *testing.B) {
+	ts := setupScanTestDB(b, "scanbenchtyped", 1000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rows := make([]scanTestTable, 0)
+		err := ts.Query().All(&rows)
+		if err != nil {
+			b.Fatalf("All fail: %s", err)
+		}
+	}
+}
+
+type scanNestedAuthor struct {
+	Id   int    `primary:"true"`
+	Name string `width:"32"`
+}
+
+type scanNestedBook struct {
+	Id       int    `primary:"true"`
+	Name     string `width:"32"`
+	AuthorId int    `nullable:"true"`
+}
+
+type scanNestedResult struct {
+	Book   scanNestedBook    `alias:"book"`
+	Author *scanNestedAuthor `alias:"author"`
+}
+
+func TestScanNested(t *testing.T) {
+	openTestDB(t, "scannestedtest")
+	authors := syncTestTable(t, scanNestedAuthor{}, "scan_nested_authors_tbl")
+	books := syncTestTable(t, scanNestedBook{}, "scan_nested_books_tbl")
+	err := authors.InsertBatch([]interface{}{&scanNestedAuthor{Id: 1, Name: "alice"}})
+	if err != nil {
+		t.Fatalf("insert authors fail: %s", err)
+	}
+	err = books.InsertBatch([]interface{}{&scanNestedBook{Id: 1, Name: "go", AuthorId: 1}, &scanNestedBook{Id: 2, Name: "sql"}})
+	if err != nil {
+		t.Fatalf("insert books fail: %s", err)
+	}
+
+	b := books.InstanceWithAlias("book")
+	a := authors.InstanceWithAlias("author")
+	q := b.Query(append(b.Fields(), a.Fields()...)...).LeftJoin(a, sqlchemy.Equals(b.Field("author_id"), a.Field("id"))).Asc(b.Field("id"))
+	results := make([]scanNestedResult, 0)
+	err = q.All(&results)
+	if err != nil {
+		t.Fatalf("All fail: %s", err)
+	}
+	want := []scanNestedResult{
+		{Book: scanNestedBook{Id: 1, Name: "go", AuthorId: 1}, Author: &scanNestedAuthor{Id: 1, Name: "alice"}},
+		{Book: scanNestedBook{Id: 2, Name: "sql"}},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("want %#v got %#v", want, results)
+	}
+
+	t.Run("labels", func(t *testing.T) {
+		b := books.Instance()
+		a := authors.Instance()
+		q := b.Query(b.Field("id", "book.id"), a.Field("name", "author.name")).Join(a, sqlchemy.Equals(b.Field("author_id"), a.Field("id")))
+		result := scanNestedResult{}
+		err := q.First(&result)
+		if err != nil {
+			t.Fatalf("First fail: %s", err)
+		}
+		if result.Book.Id != 1 || result.Author == nil || result.Author.Name != "alice" {
+			t.Errorf("unexpected result %#v", result)
+		}
+	})
+}
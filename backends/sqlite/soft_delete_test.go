@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+type softDeleteTestTable struct {
+	Id        string    `primary:"true" width:"32"`
+	Name      string    `width:"64"`
+	Deleted   bool      `soft_delete:"true"`
+	DeletedAt time.Time `deleted_at:"true" nullable:"true"`
+}
+
+func TestSoftDelete(t *testing.T) {
+	openTestDB(t, "softdeletetest")
+	ts := syncTestTable(t, softDeleteTestTable{}, "soft_delete_tbl")
+	for i := 0; i < 4; i++ {
+		err := ts.Insert(&softDeleteTestTable{Id: fmt.Sprintf("id%d", i), Name: fmt.Sprintf("name%d", i)})
+		if err != nil {
+			t.Fatalf("insert fail: %s", err)
+		}
+	}
+
+	err := ts.DeleteFrom(map[string]interface{}{"id": []string{"id0", "id1"}})
+	if err != nil {
+		t.Fatalf("DeleteFrom fail: %s", err)
+	}
+	cnt, err := ts.Query().CountWithError()
+	if err != nil || cnt != 2 {
+		t.Errorf("Query: want 2 got %d %v", cnt, err)
+	}
+	cnt, err = ts.Query().Unscoped().CountWithError()
+	if err != nil || cnt != 4 {
+		t.Errorf("Unscoped: want 4 got %d %v", cnt, err)
+	}
+
+	row := softDeleteTestTable{Id: "id0"}
+	err = ts.Fetch(&row)
+	if err != sql.ErrNoRows {
+		t.Errorf("Fetch deleted row: want %s got %v", sql.ErrNoRows, err)
+	}
+	row = softDeleteTestTable{}
+	err = ts.Query().Unscoped().Equals("id", "id0").First(&row)
+	if err != nil || !row.Deleted || row.DeletedAt.IsZero() {
+		t.Errorf("deleted row: want deleted with deleted_at got %#v %v", row, err)
+	}
+	rows := []softDeleteTestTable{{Id: "id1"}, {Id: "id2"}}
+	err = ts.FetchAll(&rows)
+	if err != nil {
+		t.Fatalf("FetchAll fail: %s", err)
+	}
+	if rows[0].Name != "" || rows[1].Name != "name2" {
+		t.Errorf("FetchAll: want only id2 fetched got %#v", rows)
+	}
+
+	target := ts.Target()
+	cnt2, err := ts.DeleteWhere(sqlchemy.Equals(target.Field("id"), "id2"))
+	if err != nil || cnt2 != 1 {
+		t.Errorf("DeleteWhere: want 1 got %d %v", cnt2, err)
+	}
+	err = ts.HardDelete(map[string]interface{}{"id": "id0"})
+	if err != nil {
+		t.Fatalf("HardDelete fail: %s", err)
+	}
+	cnt2, err = ts.HardDeleteWhere(sqlchemy.Equals(target.Field("id"), "id1"))
+	if err != nil || cnt2 != 1 {
+		t.Errorf("HardDeleteWhere: want 1 got %d %v", cnt2, err)
+	}
+	cnt, err = ts.Query().Unscoped().CountWithError()
+	if err != nil || cnt != 2 {
+		t.Errorf("after HardDelete: want 2 got %d %v", cnt, err)
+	}
+	cnt, err = ts.Query().CountWithError()
+	if err != nil || cnt != 1 {
+		t.Errorf("after DeleteWhere: want 1 got %d %v", cnt, err)
+	}
+}
+
+func TestSoftDeletedRowWrite(t *testing.T) {
+	openTestDB(t, "softdeletewritetest")
+	ts := syncTestTable(t, softDeleteTestTable{}, "soft_delete_write_tbl")
+
+	// the row is read back after insert and update regardless of the soft delete filter
+	row := softDeleteTestTable{Id: "id0", Name: "name0", Deleted: true, DeletedAt: time.Now()}
+	err := ts.Insert(&row)
+	if err != nil {
+		t.Fatalf("insert soft-deleted row fail: %s", err)
+	}
+	_, err = ts.Update(&row, func() error {
+		row.Name = "renamed"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("update soft-deleted row fail: %s", err)
+	}
+	got := softDeleteTestTable{}
+	err = ts.Query().Unscoped().Equals("id", "id0").First(&got)
+	if err != nil || got.Name != "renamed" || !got.Deleted {
+		t.Errorf("want renamed soft-deleted row got %#v %v", got, err)
+	}
+}
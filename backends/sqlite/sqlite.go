@@ -19,7 +19,7 @@ import (
 	"reflect"
 	"strings"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 
 	"github.com/nyl1001/pkg/errors"
 	"github.com/nyl1001/pkg/gotypes"
@@ -64,11 +64,60 @@ func (sqlite *SSqliteBackend) CurrentTimeStampString() string {
 }
 
 func (sqlite *SSqliteBackend) DropIndexSQLTemplate() string {
-	return "DROP INDEX IF EXISTS `{{ .Table }}`.`{{ .Index }}`"
+	return "DROP INDEX IF EXISTS {{ .Table }}.{{ .Index }}"
+}
+
+// MaxInsertPlaceholders returns the default SQLITE_MAX_VARIABLE_NUMBER of SQLite before 3.32.0
+func (sqlite *SSqliteBackend) MaxInsertPlaceholders() int {
+	return 999
 }
 
 func (sqlite *SSqliteBackend) InsertOrUpdateSQLTemplate() string {
-	return "INSERT INTO `{{ .Table }}` ({{ .Columns }}) VALUES ({{ .Values }}) ON CONFLICT({{ .PrimaryKeys }}) DO UPDATE SET {{ .SetValues }}"
+	return "INSERT INTO {{ .Table }} ({{ .Columns }}) VALUES ({{ .Values }}) ON CONFLICT({{ .PrimaryKeys }}) DO UPDATE SET {{ .SetValues }}"
+}
+
+// InsertOrIgnoreSQLTemplate returns ON CONFLICT DO NOTHING, which skips the rows with duplicate primary keys
+func (sqlite *SSqliteBackend) InsertOrIgnoreSQLTemplate() string {
+	return "INSERT INTO {{ .Table }} ({{ .Columns }}) VALUES ({{ .Values }}) ON CONFLICT({{ .PrimaryKeys }}) DO NOTHING"
+}
+
+// InsertOrIgnoreFromQuerySQLTemplate returns INSERT ... SELECT ... ON CONFLICT DO NOTHING, the query is wrapped
+// with WHERE true as InsertOrUpdateFromQuerySQLTemplate does
+func (sqlite *SSqliteBackend) InsertOrIgnoreFromQuerySQLTemplate() string {
+	return "INSERT INTO {{ .Table }} ({{ .Columns }}) SELECT * FROM ({{ .Query }}) WHERE true ON CONFLICT({{ .PrimaryKeys }}) DO NOTHING"
+}
+
+// minUpdateFromVersion is the version number of SQLite 3.33.0, which introduces UPDATE ... FROM
+const minUpdateFromVersion = 3033000
+
+// UpdateJoinStyle returns UPDATE_FROM, i.e. UPDATE a SET ... FROM b WHERE ..., which requires SQLite 3.33+,
+// UPDATE_JOIN_NOT_SUPPORTED is returned if the linked SQLite is older, so that an update join fails with ErrNotSupported
+func (sqlite *SSqliteBackend) UpdateJoinStyle() sqlchemy.UpdateJoinStyle {
+	_, version, _ := sqlite3.Version()
+	return updateJoinStyle(version)
+}
+
+func updateJoinStyle(version int) sqlchemy.UpdateJoinStyle {
+	if version < minUpdateFromVersion {
+		return sqlchemy.UPDATE_JOIN_NOT_SUPPORTED
+	}
+	return sqlchemy.UPDATE_FROM
+}
+
+// CanSupportRowValueIn returns true, the row values are supported since SQLite 3.15
+func (sqlite *SSqliteBackend) CanSupportRowValueIn() bool {
+	return true
+}
+
+// InsertOrUpdateFromQuerySQLTemplate wraps the query with WHERE true, which is required to parse
+// the ON CONFLICT clause after a SELECT without WHERE clause
+func (sqlite *SSqliteBackend) InsertOrUpdateFromQuerySQLTemplate() string {
+	return "INSERT INTO {{ .Table }} ({{ .Columns }}) SELECT * FROM ({{ .Query }}) WHERE true ON CONFLICT({{ .PrimaryKeys }}) DO UPDATE SET {{ .SetValues }}"
+}
+
+// InsertOrUpdateValueRef returns the column of the excluded row of ON CONFLICT DO UPDATE
+func (sqlite *SSqliteBackend) InsertOrUpdateValueRef(name string) string {
+	return "excluded." + sqlite.QuoteIdentifier(name)
 }
 
 func (sqlite *SSqliteBackend) GetTableSQL() string {
@@ -0,0 +1,162 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlite
+
+import (
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/nyl1001/pkg/errors"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+type txTestTable struct {
+	Id   string `primary:"true" width:"32"`
+	Name string `width:"64"`
+	Age  int    `default:"0"`
+}
+
+func setupTxTestDB(t *testing.T, name string) *sqlchemy.STableSpec {
+	openTestDB(t, name)
+	return syncTestTable(t, txTestTable{}, "tx_test_tbl")
+}
+
+func countRows(t *testing.T, ts *sqlchemy.STableSpec) int {
+	cnt, err := ts.Query().CountWithError()
+	if err != nil {
+		t.Fatalf("count fail: %s", err)
+	}
+	return cnt
+}
+
+func TestRunInTx(t *testing.T) {
+	ts := setupTxTestDB(t, "txtest")
+
+	db := ts.Database()
+	errFail := errors.Error("fail")
+
+	t.Run("commit", func(t *testing.T) {
+		err := db.RunInTx(func(tx *sqlchemy.STx) error {
+			row := txTestTable{Id: "1", Name: "one"}
+			if err := ts.InsertContext(tx.Context(), &row); err != nil {
+				return err
+			}
+			_, err := ts.UpdateContext(tx.Context(), &row, func() error {
+				row.Age = 10
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			got := txTestTable{}
+			err = ts.Query().Equals("id", "1").WithContext(tx.Context()).First(&got)
+			if err != nil {
+				return err
+			}
+			if got.Age != 10 {
+				t.Errorf("want age 10 inside tx, got %d", got.Age)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("RunInTx fail: %s", err)
+		}
+		if cnt := countRows(t, ts); cnt != 1 {
+			t.Errorf("want 1 row, got %d", cnt)
+		}
+	})
+
+	t.Run("rollback on error", func(t *testing.T) {
+		err := db.RunInTx(func(tx *sqlchemy.STx) error {
+			row := txTestTable{Id: "2", Name: "two"}
+			if err := ts.InsertContext(tx.Context(), &row); err != nil {
+				return err
+			}
+			if err := ts.DeleteFromContext(tx.Context(), map[string]interface{}{"id": "1"}); err != nil {
+				return err
+			}
+			return errFail
+		})
+		if errors.Cause(err) != errFail {
+			t.Fatalf("want error %s, got %v", errFail, err)
+		}
+		if cnt := countRows(t, ts); cnt != 1 {
+			t.Errorf("want 1 row, got %d", cnt)
+		}
+	})
+
+	t.Run("rollback on panic", func(t *testing.T) {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("panic not propagated")
+				}
+			}()
+			db.RunInTx(func(tx *sqlchemy.STx) error {
+				row := txTestTable{Id: "3", Name: "three"}
+				if err := ts.InsertContext(tx.Context(), &row); err != nil {
+					return err
+				}
+				panic("boom")
+			})
+		}()
+		if cnt := countRows(t, ts); cnt != 1 {
+			t.Errorf("want 1 row, got %d", cnt)
+		}
+	})
+
+	t.Run("nested savepoint", func(t *testing.T) {
+		err := db.RunInTx(func(tx *sqlchemy.STx) error {
+			row := txTestTable{Id: "4", Name: "four"}
+			if err := ts.InsertContext(tx.Context(), &row); err != nil {
+				return err
+			}
+			err := db.RunInTxContext(tx.Context(), func(inner *sqlchemy.STx) error {
+				if !inner.IsNested() {
+					t.Errorf("inner transaction should be nested")
+				}
+				row := txTestTable{Id: "5", Name: "five"}
+				if err := ts.InsertContext(inner.Context(), &row); err != nil {
+					return err
+				}
+				return errFail
+			})
+			if errors.Cause(err) != errFail {
+				t.Errorf("want error %s, got %v", errFail, err)
+			}
+			return db.RunInTxContext(tx.Context(), func(inner *sqlchemy.STx) error {
+				row := txTestTable{Id: "6", Name: "six"}
+				return ts.InsertContext(inner.Context(), &row)
+			})
+		})
+		if err != nil {
+			t.Fatalf("RunInTx fail: %s", err)
+		}
+		ids := make([]txTestTable, 0)
+		err = ts.Query().Asc("id").All(&ids)
+		if err != nil {
+			t.Fatalf("query fail: %s", err)
+		}
+		got := ""
+		for i := range ids {
+			got += ids[i].Id
+		}
+		if got != "146" {
+			t.Errorf("want rows 146, got %s", got)
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+type projectTestTable struct {
+	Id   string `primary:"true" width:"32"`
+	Name string `width:"64"`
+}
+
+type resourceTestTable struct {
+	Id          string `primary:"true" width:"32"`
+	ProjectId   string `width:"32"`
+	ProjectName string `width:"64"`
+}
+
+func TestUpdateJoin(t *testing.T) {
+	openTestDB(t, "updatejointest")
+	projects := syncTestTable(t, projectTestTable{}, "project_test_tbl")
+	resources := syncTestTable(t, resourceTestTable{}, "resource_test_tbl")
+	for _, p := range []projectTestTable{{Id: "p1", Name: "alpha"}, {Id: "p2", Name: "beta"}} {
+		err := projects.Insert(&p)
+		if err != nil {
+			t.Fatalf("insert project fail: %s", err)
+		}
+	}
+	for _, r := range []resourceTestTable{{Id: "r1", ProjectId: "p1"}, {Id: "r2", ProjectId: "p2"}, {Id: "r3", ProjectId: "p3"}} {
+		err := resources.Insert(&r)
+		if err != nil {
+			t.Fatalf("insert resource fail: %s", err)
+		}
+	}
+
+	sqlchemy.ResetTableID()
+	p := projects.Instance()
+	target := resources.Target()
+	uj := resources.UpdateJoin().
+		Join(p, sqlchemy.Equals(p.Field("id"), target.Field("project_id"))).
+		Set("project_name", p.Field("name")).
+		Filter(sqlchemy.NotEquals(target.Field("id"), "r2"))
+	sql, _, err := uj.Sql()
+	if err != nil {
+		t.Fatalf("UpdateJoin Sql fail: %s", err)
+	}
+	want := "UPDATE `resource_test_tbl` SET `project_name` = `t1`.`name` FROM `project_test_tbl` AS `t1` WHERE (`t1`.`id` = `resource_test_tbl`.`project_id`) AND (`resource_test_tbl`.`id` <>  ? )"
+	if sql != want {
+		t.Errorf("want %s got %s", want, sql)
+	}
+	cnt, err := uj.Exec()
+	if err != nil {
+		t.Fatalf("UpdateJoin fail: %s", err)
+	}
+	if cnt != 1 {
+		t.Errorf("affected rows: want 1 got %d", cnt)
+	}
+
+	rows := make([]resourceTestTable, 0)
+	err = resources.Query().Asc("id").All(&rows)
+	if err != nil {
+		t.Fatalf("query fail: %s", err)
+	}
+	for i, want := range []string{"alpha", "", ""} {
+		if rows[i].ProjectName != want {
+			t.Errorf("%s: want %q got %q", rows[i].Id, want, rows[i].ProjectName)
+		}
+	}
+}
+
+func TestUpdateJoinStyle(t *testing.T) {
+	for _, c := range []struct {
+		version int
+		want    sqlchemy.UpdateJoinStyle
+	}{
+		{3032003, sqlchemy.UPDATE_JOIN_NOT_SUPPORTED},
+		{3033000, sqlchemy.UPDATE_FROM},
+		{3045001, sqlchemy.UPDATE_FROM},
+	} {
+		if got := updateJoinStyle(c.version); got != c.want {
+			t.Errorf("version %d: want %q got %q", c.version, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package sqlite
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+type whereTestTable struct {
+	Id    string `primary:"true" width:"32"`
+	Name  string `width:"64"`
+	Score int    `default:"0"`
+}
+
+func TestUpdateDeleteWhere(t *testing.T) {
+	openTestDB(t, "wheretest")
+	ts := syncTestTable(t, whereTestTable{}, "where_test_tbl")
+	for i := 0; i < 10; i++ {
+		err := ts.Insert(&whereTestTable{Id: fmt.Sprintf("id%d", i), Name: fmt.Sprintf("name%d", i), Score: i})
+		if err != nil {
+			t.Fatalf("insert fail: %s", err)
+		}
+	}
+
+	target := ts.Target()
+	cnt, err := ts.UpdateWhere(sqlchemy.OR(sqlchemy.LT(target.Field("score"), 3), sqlchemy.Equals(target.Field("name"), "name9")), map[string]interface{}{
+		"score": sqlchemy.MUL("", target.Field("score"), sqlchemy.NewConstField(10)),
+	})
+	if err != nil {
+		t.Fatalf("UpdateWhere fail: %s", err)
+	}
+	if cnt != 4 {
+		t.Errorf("UpdateWhere: want 4 got %d", cnt)
+	}
+	row := whereTestTable{}
+	err = ts.Query().Equals("id", "id9").First(&row)
+	if err != nil || row.Score != 90 {
+		t.Errorf("want score 90 got %d %v", row.Score, err)
+	}
+
+	cnt, err = ts.DeleteWhere(sqlchemy.GE(target.Field("score"), 10))
+	if err != nil {
+		t.Fatalf("DeleteWhere fail: %s", err)
+	}
+	if cnt != 3 {
+		t.Errorf("DeleteWhere: want 3 got %d", cnt)
+	}
+	total, err := ts.Query().CountWithError()
+	if err != nil || total != 7 {
+		t.Errorf("want 7 rows left got %d %v", total, err)
+	}
+}
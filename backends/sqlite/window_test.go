@@ -0,0 +1,60 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/nyl1001/sqlchemy"
+)
+
+type windowTestTable struct {
+	Id       int    `primary:"true"`
+	Category string `width:"32"`
+	Value    int    `default:"0"`
+}
+
+func TestWindowFunction(t *testing.T) {
+	openTestDB(t, "windowtest")
+	ts := syncTestTable(t, windowTestTable{}, "window_test_tbl")
+	rows := []windowTestTable{
+		{Id: 1, Category: "a", Value: 1},
+		{Id: 2, Category: "a", Value: 2},
+		{Id: 3, Category: "b", Value: 3},
+		{Id: 4, Category: "a", Value: 4},
+		{Id: 5, Category: "b", Value: 5},
+	}
+	for i := range rows {
+		err := ts.Insert(&rows[i])
+		if err != nil {
+			t.Fatalf("insert fail: %s", err)
+		}
+	}
+
+	// the latest record per category
+	t1 := ts.Instance()
+	over := sqlchemy.Over([]sqlchemy.IQueryField{t1.Field("category")}, []sqlchemy.SWindowOrder{sqlchemy.WindowDesc(t1.Field("id"))}, "")
+	ranked := t1.Query(t1.Field("id"), t1.Field("category"), t1.Field("value"), sqlchemy.ROW_NUMBER("rn", over)).SubQuery()
+	latest := make([]windowTestTable, 0)
+	err := ranked.Query(ranked.Field("id"), ranked.Field("category"), ranked.Field("value")).Equals("rn", 1).Asc("id").All(&latest)
+	if err != nil {
+		t.Fatalf("query latest fail: %s", err)
+	}
+	if len(latest) != 2 || latest[0].Id != 4 || latest[1].Id != 5 {
+		t.Errorf("want latest records 4, 5, got %#v", latest)
+	}
+
+	// running sum per category
+	t2 := ts.Instance()
+	over = sqlchemy.Over([]sqlchemy.IQueryField{t2.Field("category")}, []sqlchemy.SWindowOrder{sqlchemy.WindowAsc(t2.Field("id"))}, "ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW")
+	q := t2.Query(t2.Field("id"), sqlchemy.SUM_OVER("total", t2.Field("value"), over), sqlchemy.LAG("prev", t2.Field("value"), 1, over)).Asc("id")
+	results, err := q.AllStringMap()
+	if err != nil {
+		t.Fatalf("query running sum fail: %s", err)
+	}
+	wantTotals := []string{"1", "3", "3", "7", "8"}
+	wantPrevs := []string{"", "1", "", "2", "3"}
+	for i := range results {
+		if results[i]["total"] != wantTotals[i] || results[i]["prev"] != wantPrevs[i] {
+			t.Errorf("row %d: want total %s prev %s, got %v", i, wantTotals[i], wantPrevs[i], results[i])
+		}
+	}
+}
@@ -18,6 +18,8 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+
+	"github.com/nyl1001/pkg/errors"
 )
 
 var defaultBackend IBackend = (*SBaseBackend)(nil)
@@ -73,7 +75,7 @@ func (bb *SBaseBackend) UnionDistinctString() string {
 }
 
 func (bb *SBaseBackend) DropTableSQL(table string) string {
-	return fmt.Sprintf("DROP TABLE `%s`", table)
+	return fmt.Sprintf("DROP TABLE %s", bb.QuoteIdentifier(table))
 }
 
 func (bb *SBaseBackend) SupportMixedInsertVariables() bool {
@@ -100,26 +102,94 @@ func (bb *SBaseBackend) FetchIndexesAndConstraints(ts ITableSpec) ([]STableIndex
 	return nil, nil, nil
 }
 
+// DropIndexSQLTemplate returns the template of dropping an index, the Index and Table are quoted by QuoteIdentifier
 func (bb *SBaseBackend) DropIndexSQLTemplate() string {
-	return "DROP INDEX `{{ .Index }}` ON `{{ .Table }}`"
+	return "DROP INDEX {{ .Index }} ON {{ .Table }}"
 }
 
 func (bb *SBaseBackend) CanSupportRowAffected() bool {
 	return true
 }
 
+func (bb *SBaseBackend) CanSupportReturning() bool {
+	return false
+}
+
+func (bb *SBaseBackend) QuoteIdentifier(name string) string {
+	return quoteIdentifierWithBackticks(name)
+}
+
+func (bb *SBaseBackend) Placeholder(index int) string {
+	return "?"
+}
+
+func (bb *SBaseBackend) MaxInsertPlaceholders() int {
+	return 999
+}
+
+func (bb *SBaseBackend) MaxInsertPacketSize() int {
+	return 0
+}
+
+func (bb *SBaseBackend) UpdateJoinStyle() UpdateJoinStyle {
+	return UPDATE_JOIN_NOT_SUPPORTED
+}
+
+func (bb *SBaseBackend) CanSupportRowValueIn() bool {
+	return false
+}
+
+func (bb *SBaseBackend) CanSupportRecursiveCTE() bool {
+	return true
+}
+
+func (bb *SBaseBackend) IsNullsFirst(order QueryOrderType) bool {
+	return order != SQL_ORDER_DESC
+}
+
+func (bb *SBaseBackend) BooleanLiteral(v bool) string {
+	if v {
+		return "1"
+	}
+	return "0"
+}
+
+func (bb *SBaseBackend) LockingClause(lockType QueryLockType, waitType QueryLockWaitType) (string, error) {
+	return "", errors.Wrapf(ErrNotSupported, "row locking FOR %s", lockType)
+}
+
 func (bb *SBaseBackend) InsertSQLTemplate() string {
-	return "INSERT INTO `{{ .Table }}` ({{ .Columns }}) VALUES ({{ .Values }})"
+	return "INSERT INTO {{ .Table }} ({{ .Columns }}) VALUES ({{ .Values }})"
 }
 
 func (bb *SBaseBackend) UpdateSQLTemplate() string {
-	return "UPDATE `{{ .Table }}` SET {{ .Columns }} WHERE {{ .Conditions }}"
+	return "UPDATE {{ .Table }} SET {{ .Columns }} WHERE {{ .Conditions }}"
 }
 
 func (bb *SBaseBackend) InsertOrUpdateSQLTemplate() string {
 	return ""
 }
 
+func (bb *SBaseBackend) InsertOrUpdateFromQuerySQLTemplate() string {
+	return ""
+}
+
+func (bb *SBaseBackend) InsertOrIgnoreSQLTemplate() string {
+	return ""
+}
+
+func (bb *SBaseBackend) InsertOrIgnoreFromQuerySQLTemplate() string {
+	return ""
+}
+
+func (bb *SBaseBackend) DeleteSQLTemplate() string {
+	return "DELETE FROM {{ .Table }} WHERE {{ .Conditions }}"
+}
+
+func (bb *SBaseBackend) InsertOrUpdateValueRef(name string) string {
+	return "VALUES(" + bb.QuoteIdentifier(name) + ")"
+}
+
 func (bb *SBaseBackend) CAST(field IQueryField, typeStr string, fieldname string) IQueryField {
 	return NewFunctionField(fieldname, `CAST(%s AS `+typeStr+`)`, field)
 }
@@ -230,3 +300,38 @@ func (bb *SBaseBackend) UPPER(name string, field IQueryField) IQueryField {
 func (bb *SBaseBackend) DATEDIFF(unit string, field1, field2 IQueryField) IQueryField {
 	return NewFunctionField("", fmt.Sprintf("DATEDIFF('%s',%s,%s)", unit, "%s", "%s"), field1, field2)
 }
+
+// ROW_NUMBER represents SQL window function of ROW_NUMBER
+func (bb *SBaseBackend) ROW_NUMBER(name string, over *SWindow) IQueryField {
+	return NewWindowFunctionField(name, "ROW_NUMBER()", over)
+}
+
+// RANK represents SQL window function of RANK
+func (bb *SBaseBackend) RANK(name string, over *SWindow) IQueryField {
+	return NewWindowFunctionField(name, "RANK()", over)
+}
+
+// DENSE_RANK represents SQL window function of DENSE_RANK
+func (bb *SBaseBackend) DENSE_RANK(name string, over *SWindow) IQueryField {
+	return NewWindowFunctionField(name, "DENSE_RANK()", over)
+}
+
+// LAG represents SQL window function of LAG
+func (bb *SBaseBackend) LAG(name string, field IQueryField, offset int, over *SWindow) IQueryField {
+	return NewWindowFunctionField(name, fmt.Sprintf("LAG(%%s, %d)", offset), over, field)
+}
+
+// LEAD represents SQL window function of LEAD
+func (bb *SBaseBackend) LEAD(name string, field IQueryField, offset int, over *SWindow) IQueryField {
+	return NewWindowFunctionField(name, fmt.Sprintf("LEAD(%%s, %d)", offset), over, field)
+}
+
+// FIRST_VALUE represents SQL window function of FIRST_VALUE
+func (bb *SBaseBackend) FIRST_VALUE(name string, field IQueryField, over *SWindow) IQueryField {
+	return NewWindowFunctionField(name, "FIRST_VALUE(%s)", over, field)
+}
+
+// SUM_OVER represents SQL aggregate function of SUM over a window
+func (bb *SBaseBackend) SUM_OVER(name string, field IQueryField, over *SWindow) IQueryField {
+	return NewWindowFunctionField(name, "SUM(%s)", over, field)
+}
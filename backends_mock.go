@@ -116,12 +116,36 @@ func (mock *sMockBackend) CanInsertOrUpdate() bool {
 	return true
 }
 
+func (mock *sMockBackend) LockingClause(lockType QueryLockType, waitType QueryLockWaitType) (string, error) {
+	clause := "FOR " + string(lockType)
+	if len(waitType) > 0 {
+		clause += " " + string(waitType)
+	}
+	return clause, nil
+}
+
+func (mock *sMockBackend) UpdateJoinStyle() UpdateJoinStyle {
+	return UPDATE_JOIN_ON
+}
+
 func (mock *sMockBackend) DropIndexSQLTemplate() string {
 	return ""
 }
 
 func (mock *sMockBackend) InsertOrUpdateSQLTemplate() string {
-	return "INSERT INTO `{{ .Table }}` ({{ .Columns }}) VALUES ({{ .Values }}) ON DUPLICATE KEY UPDATE {{ .SetValues }}"
+	return "INSERT INTO {{ .Table }} ({{ .Columns }}) VALUES ({{ .Values }}) ON DUPLICATE KEY UPDATE {{ .SetValues }}"
+}
+
+func (mock *sMockBackend) InsertOrUpdateFromQuerySQLTemplate() string {
+	return "INSERT INTO {{ .Table }} ({{ .Columns }}) {{ .Query }} ON DUPLICATE KEY UPDATE {{ .SetValues }}"
+}
+
+func (mock *sMockBackend) InsertOrIgnoreSQLTemplate() string {
+	return "INSERT IGNORE INTO {{ .Table }} ({{ .Columns }}) VALUES ({{ .Values }})"
+}
+
+func (mock *sMockBackend) InsertOrIgnoreFromQuerySQLTemplate() string {
+	return "INSERT IGNORE INTO {{ .Table }} ({{ .Columns }}) {{ .Query }}"
 }
 
 func (mock *sMockBackend) GetTableSQL() string {
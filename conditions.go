@@ -19,6 +19,7 @@ import (
 	"fmt"
 
 	"github.com/nyl1001/pkg/util/reflectutils"
+	"yunion.io/x/log"
 )
 
 type sNoop struct{}
@@ -474,7 +475,7 @@ func In(f IQueryField, v interface{}) ICondition {
 		expandV := reflectutils.ExpandInterface(v)
 		switch len(expandV) {
 		case 0:
-			return &SFalseCondition{}
+			return &SFalseCondition{db: f.database()}
 		case 1:
 			return Equals(f, expandV[0])
 		default:
@@ -495,7 +496,7 @@ func NotIn(f IQueryField, v interface{}) ICondition {
 		expandV := reflectutils.ExpandInterface(v)
 		switch len(expandV) {
 		case 0:
-			return &STrueCondition{}
+			return &STrueCondition{db: f.database()}
 		case 1:
 			return NotEquals(f, expandV[0])
 		default:
@@ -508,6 +509,196 @@ func NotIn(f IQueryField, v interface{}) ICondition {
 	return &c
 }
 
+// SRowValueInCondition represents the IN operation of the row values of multiple fields, e.g.
+// (a, b) IN ((?, ?), (?, ?)), which is expanded to (a = ? AND b = ?) OR (a = ? AND b = ?)
+// if the backend does not support row values
+type SRowValueInCondition struct {
+	fields []IQueryField
+	values [][]interface{}
+}
+
+// InTuples SQL operator, values are the tuples of the values of fields.
+// It is false if there is no field or any tuple does not have as many values as the fields
+func InTuples(fields []IQueryField, values [][]interface{}) ICondition {
+	if len(fields) == 0 {
+		log.Errorf("InTuples without fields")
+		return &SFalseCondition{}
+	}
+	if len(values) == 0 {
+		return &SFalseCondition{db: fields[0].database()}
+	}
+	for i := range values {
+		if len(values[i]) != len(fields) {
+			log.Errorf("InTuples: %d values in tuple %d but %d fields", len(values[i]), i, len(fields))
+			return &SFalseCondition{db: fields[0].database()}
+		}
+	}
+	if len(fields) == 1 {
+		vals := make([]interface{}, len(values))
+		for i := range values {
+			vals[i] = values[i][0]
+		}
+		return In(fields[0], vals)
+	}
+	return &SRowValueInCondition{
+		fields: fields,
+		values: values,
+	}
+}
+
+// WhereClause implementation of SRowValueInCondition for ICondition
+func (t *SRowValueInCondition) WhereClause() string {
+	var buf bytes.Buffer
+	if db := t.database(); db != nil && db.backend.CanSupportRowValueIn() {
+		buf.WriteByte('(')
+		for i, f := range t.fields {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(f.Reference())
+		}
+		buf.WriteString(") IN (")
+		for i := range t.values {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(questionMark(len(t.fields)))
+		}
+		buf.WriteByte(')')
+		return buf.String()
+	}
+	for i := range t.values {
+		if i > 0 {
+			buf.WriteString(" OR ")
+		}
+		buf.WriteByte('(')
+		for j, f := range t.fields {
+			if j > 0 {
+				buf.WriteString(" AND ")
+			}
+			buf.WriteString(f.Reference())
+			buf.WriteString(" = ?")
+		}
+		buf.WriteByte(')')
+	}
+	return buf.String()
+}
+
+// Variables implementation of SRowValueInCondition for ICondition
+func (t *SRowValueInCondition) Variables() []interface{} {
+	vars := make([]interface{}, 0, len(t.values)*len(t.fields))
+	for i := range t.values {
+		vars = append(vars, t.values[i]...)
+	}
+	return vars
+}
+
+// database implementation of SRowValueInCondition for ICondition
+func (t *SRowValueInCondition) database() *SDatabase {
+	return t.fields[0].database()
+}
+
+// sRowValueCompareCondition represents the comparison of the row values of multiple fields, e.g. (a, b) > (?, ?)
+type sRowValueCompareCondition struct {
+	fields []IQueryField
+	values []interface{}
+	op     string
+}
+
+// WhereClause implementation of sRowValueCompareCondition for ICondition
+func (t *sRowValueCompareCondition) WhereClause() string {
+	var buf bytes.Buffer
+	buf.WriteByte('(')
+	for i, f := range t.fields {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(f.Reference())
+	}
+	buf.WriteString(") ")
+	buf.WriteString(t.op)
+	buf.WriteByte(' ')
+	buf.WriteString(questionMark(len(t.fields)))
+	return buf.String()
+}
+
+// Variables implementation of sRowValueCompareCondition for ICondition
+func (t *sRowValueCompareCondition) Variables() []interface{} {
+	return t.values
+}
+
+// database implementation of sRowValueCompareCondition for ICondition
+func (t *sRowValueCompareCondition) database() *SDatabase {
+	return t.fields[0].database()
+}
+
+// SExistsCondition represents EXISTS (subquery) operation in a SQL query,
+// the subquery can reference the fields of the outer query, i.e. a correlated subquery
+type SExistsCondition struct {
+	query IQuery
+	op    string
+}
+
+// WhereClause implementation of SExistsCondition for ICondition
+func (c *SExistsCondition) WhereClause() string {
+	return fmt.Sprintf("%s (%s)", c.op, c.query.String())
+}
+
+// Variables implementation of SExistsCondition for ICondition
+func (c *SExistsCondition) Variables() []interface{} {
+	return c.query.Variables()
+}
+
+// database implementation of SExistsCondition for ICondition
+func (c *SExistsCondition) database() *SDatabase {
+	return c.query.database()
+}
+
+// Exists SQL operator
+func Exists(q IQuery) ICondition {
+	return &SExistsCondition{query: q, op: SQL_OP_EXISTS}
+}
+
+// NotExists SQL operator
+func NotExists(q IQuery) ICondition {
+	return &SExistsCondition{query: q, op: SQL_OP_NOTEXISTS}
+}
+
+// SInQueryCondition represents field IN (subquery) operation in a SQL query
+type SInQueryCondition struct {
+	field IQueryField
+	query IQuery
+	op    string
+}
+
+// WhereClause implementation of SInQueryCondition for ICondition
+func (c *SInQueryCondition) WhereClause() string {
+	return fmt.Sprintf("%s %s (%s)", c.field.Reference(), c.op, c.query.String())
+}
+
+// Variables implementation of SInQueryCondition for ICondition
+func (c *SInQueryCondition) Variables() []interface{} {
+	vars := make([]interface{}, 0)
+	vars = append(vars, c.field.Variables()...)
+	vars = append(vars, c.query.Variables()...)
+	return vars
+}
+
+// database implementation of SInQueryCondition for ICondition
+func (c *SInQueryCondition) database() *SDatabase {
+	return c.field.database()
+}
+
+// InQuery SQL operator, the subquery should select exactly one field
+func InQuery(f IQueryField, q IQuery) ICondition {
+	return &SInQueryCondition{field: f, query: q, op: SQL_OP_IN}
+}
+
+// NotInQuery SQL operator, the subquery should select exactly one field
+func NotInQuery(f IQueryField, q IQuery) ICondition {
+	return &SInQueryCondition{field: f, query: q, op: SQL_OP_NOTIN}
+}
+
 // SLikeCondition represents LIKE operation in a SQL query
 type SLikeCondition struct {
 	STupleCondition
@@ -718,10 +909,16 @@ func Between(f IQueryField, r1, r2 interface{}) ICondition {
 }
 
 // STrueCondition represents a dummy condition that is always true
-type STrueCondition struct{}
+type STrueCondition struct {
+	// db is the database whose backend renders the condition, 1 if not set
+	db *SDatabase
+}
 
 // WhereClause implementation of STrueCondition for ICondition
 func (t *STrueCondition) WhereClause() string {
+	if t.db != nil {
+		return t.db.backend.BooleanLiteral(true)
+	}
 	return "1"
 }
 
@@ -731,14 +928,20 @@ func (t *STrueCondition) Variables() []interface{} {
 }
 
 func (t *STrueCondition) database() *SDatabase {
-	return nil
+	return t.db
 }
 
 // SFalseCondition is a dummy condition that is always false
-type SFalseCondition struct{}
+type SFalseCondition struct {
+	// db is the database whose backend renders the condition, 0 if not set
+	db *SDatabase
+}
 
 // WhereClause implementation of SFalseCondition for ICondition
 func (t *SFalseCondition) WhereClause() string {
+	if t.db != nil {
+		return t.db.backend.BooleanLiteral(false)
+	}
 	return "0"
 }
 
@@ -748,5 +951,5 @@ func (t *SFalseCondition) Variables() []interface{} {
 }
 
 func (t *SFalseCondition) database() *SDatabase {
-	return nil
+	return t.db
 }
@@ -72,3 +72,36 @@ func Test_likeEscape(t *testing.T) {
 		})
 	}
 }
+
+func TestInTuplesInvalid(t *testing.T) {
+	field := &SRawQueryField{
+		name: "id",
+		db:   nil,
+	}
+	cases := []struct {
+		name   string
+		fields []IQueryField
+		values [][]interface{}
+	}{
+		{
+			name:   "no fields",
+			values: [][]interface{}{{1}},
+		},
+		{
+			name:   "short tuple",
+			fields: []IQueryField{field, field},
+			values: [][]interface{}{{1, 2}, {3}},
+		},
+		{
+			name:   "short tuple of a field",
+			fields: []IQueryField{field},
+			values: [][]interface{}{{}},
+		},
+	}
+	for _, c := range cases {
+		cond := InTuples(c.fields, c.values)
+		if _, ok := cond.(*SFalseCondition); !ok {
+			t.Errorf("%s: want SFalseCondition got %s", c.name, cond.WhereClause())
+		}
+	}
+}
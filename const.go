@@ -29,6 +29,10 @@ const (
 	SQL_OP_IN = "IN"
 	// SQL_OP_NOTIN represents NOT IN operator
 	SQL_OP_NOTIN = "NOT IN"
+	// SQL_OP_EXISTS represents EXISTS operator
+	SQL_OP_EXISTS = "EXISTS"
+	// SQL_OP_NOTEXISTS represents NOT EXISTS operator
+	SQL_OP_NOTEXISTS = "NOT EXISTS"
 	// SQL_OP_EQUAL represents EQUAL operator
 	SQL_OP_EQUAL = "="
 	// SQL_OP_LT represents < operator
@@ -81,4 +85,16 @@ const (
 	TAG_CREATE_TIMESTAMP = "created_at"
 	// TAG_ALLOW_ZERO is a field tag that indicates whether the column allow zero value
 	TAG_ALLOW_ZERO = "allow_zero"
+	// TAG_OPTIMISTIC_LOCK is a field tag that indicates the auto_version column is checked on update
+	TAG_OPTIMISTIC_LOCK = "optimistic_lock"
+	// TAG_SOFT_DELETE is a field tag that indicates the boolean column is the deleted flag of a soft-deletable table
+	TAG_SOFT_DELETE = "soft_delete"
+	// TAG_DELETED_AT is a field tag that indicates the datetime column records the time of soft deletion
+	TAG_DELETED_AT = "deleted_at"
+	// TAG_ALIAS is a field tag of a nested struct in the destination of a joined query,
+	// that indicates the struct receives the columns of the table of the alias
+	TAG_ALIAS = "alias"
+
+	// EXTRA_OPTION_OPTIMISTIC_LOCK_KEY is a table extra option that enables optimistic locking on all auto_version columns
+	EXTRA_OPTION_OPTIMISTIC_LOCK_KEY = "optimistic_lock"
 )
@@ -0,0 +1,285 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlchemy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nyl1001/pkg/errors"
+	"yunion.io/x/log"
+)
+
+// SCTEField represents a field of a common table expression, which implements IQueryField
+type SCTEField struct {
+	cte   *SCTE
+	name  string
+	alias string
+}
+
+// Expression implementation of SCTEField for IQueryField
+func (cf *SCTEField) Expression() string {
+	db := cf.database()
+	if len(cf.alias) > 0 {
+		return fmt.Sprintf("%s.%s AS %s", quoteIdentifier(db, cf.cte.alias), quoteIdentifier(db, cf.name), quoteIdentifier(db, cf.alias))
+	}
+	return fmt.Sprintf("%s.%s", quoteIdentifier(db, cf.cte.alias), quoteIdentifier(db, cf.name))
+}
+
+// Name implementation of SCTEField for IQueryField
+func (cf *SCTEField) Name() string {
+	if len(cf.alias) > 0 {
+		return cf.alias
+	}
+	return cf.name
+}
+
+// Reference implementation of SCTEField for IQueryField
+func (cf *SCTEField) Reference() string {
+	db := cf.database()
+	return fmt.Sprintf("%s.%s", quoteIdentifier(db, cf.cte.alias), quoteIdentifier(db, cf.Name()))
+}
+
+// Label implementation of SCTEField for IQueryField
+func (cf *SCTEField) Label(label string) IQueryField {
+	if len(label) > 0 {
+		cf.alias = label
+	}
+	return cf
+}
+
+// Variables implementation of SCTEField for IQueryField
+func (cf *SCTEField) Variables() []interface{} {
+	return nil
+}
+
+// database implementation of SCTEField for IQueryField
+func (cf *SCTEField) database() *SDatabase {
+	return cf.cte.database()
+}
+
+// SCTE represents a common table expression, i.e. WITH name AS (query), which implements IQuerySource.
+// A query that selects from or joins a SCTE renders the WITH clause of the SCTE automatically
+type SCTE struct {
+	name  string
+	alias string
+
+	// query is the definition of the CTE, or the anchor member of a recursive CTE
+	query IQuery
+	// recursive is the recursive member of a recursive CTE, which references the CTE itself
+	recursive IQuery
+
+	// isSelf indicates the CTE is the self reference in the recursive member, whose definition is not rendered
+	isSelf bool
+}
+
+// NewCTE returns a common table expression with given name and definition
+func NewCTE(name string, q IQuery) *SCTE {
+	return &SCTE{
+		name:  name,
+		alias: getTableAliasName(),
+		query: q,
+	}
+}
+
+// NewRecursiveCTE returns a recursive common table expression, i.e. WITH RECURSIVE name AS (anchor UNION ALL recursive).
+// The recursive member is built by the callback, which receives the reference of the CTE itself, e.g.
+//
+//	tree := NewRecursiveCTE("tree", t.Query().IsNullOrEmpty("parent_id"), func(self *SCTE) IQuery {
+//		t2 := t.Instance()
+//		return t2.Query().Join(self, Equals(t2.Field("parent_id"), self.Field("id")))
+//	})
+func NewRecursiveCTE(name string, anchor IQuery, recursive func(self *SCTE) IQuery) *SCTE {
+	self := &SCTE{
+		name:   name,
+		alias:  getTableAliasName(),
+		query:  anchor,
+		isSelf: true,
+	}
+	rq := recursive(self)
+	cte := NewCTE(name, anchor)
+	cte.recursive = rq
+	return cte
+}
+
+// With defines a common table expression in the query, the returned SCTE can be used as a query source
+// in Join, LeftJoin or DoQuery
+func (tq *SQuery) With(name string, q IQuery) *SCTE {
+	cte := NewCTE(name, q)
+	tq.withs = append(tq.withs, cte)
+	return cte
+}
+
+// WithRecursive defines a recursive common table expression in the query, see NewRecursiveCTE
+func (tq *SQuery) WithRecursive(name string, anchor IQuery, recursive func(self *SCTE) IQuery) *SCTE {
+	cte := NewRecursiveCTE(name, anchor, recursive)
+	tq.withs = append(tq.withs, cte)
+	return cte
+}
+
+// Instance returns a reference of the CTE with a new alias, so that the CTE can be joined more than once in a query
+func (cte *SCTE) Instance() *SCTE {
+	ncte := *cte
+	ncte.alias = getTableAliasName()
+	return &ncte
+}
+
+// Name returns the name of the CTE
+func (cte *SCTE) Name() string {
+	return cte.name
+}
+
+// IsRecursive returns whether the CTE is recursive
+func (cte *SCTE) IsRecursive() bool {
+	return cte.recursive != nil
+}
+
+// Query returns a SQuery that selects from the CTE
+func (cte *SCTE) Query(f ...IQueryField) *SQuery {
+	return DoQuery(cte, f...)
+}
+
+// Expression implementation of SCTE for IQuerySource
+func (cte *SCTE) Expression() string {
+	return quoteIdentifier(cte.database(), cte.name)
+}
+
+// Alias implementation of SCTE for IQuerySource
+func (cte *SCTE) Alias() string {
+	return cte.alias
+}
+
+// Variables implementation of SCTE for IQuerySource, the variables of the definition belong to the WITH clause
+func (cte *SCTE) Variables() []interface{} {
+	return nil
+}
+
+// Field implementation of SCTE for IQuerySource
+func (cte *SCTE) Field(id string, alias ...string) IQueryField {
+	for _, f := range cte.query.QueryFields() {
+		if f.Name() == id {
+			cf := SCTEField{cte: cte, name: id}
+			if len(alias) > 0 {
+				cf.Label(alias[0])
+			}
+			return &cf
+		}
+	}
+	return nil
+}
+
+// Fields implementation of SCTE for IQuerySource
+func (cte *SCTE) Fields() []IQueryField {
+	ret := make([]IQueryField, 0)
+	for _, f := range cte.query.QueryFields() {
+		ret = append(ret, &SCTEField{cte: cte, name: f.Name()})
+	}
+	return ret
+}
+
+// database implementation of SCTE for IQuerySource
+func (cte *SCTE) database() *SDatabase {
+	return cte.query.database()
+}
+
+// definition returns the clause name AS (...) of the CTE
+func (cte *SCTE) definition() string {
+	var buf strings.Builder
+	buf.WriteString(quoteIdentifier(cte.database(), cte.name))
+	buf.WriteString(" AS (")
+	buf.WriteString(cte.query.String())
+	if cte.recursive != nil {
+		buf.WriteByte(' ')
+		buf.WriteString(cte.database().backend.UnionAllString())
+		buf.WriteByte(' ')
+		buf.WriteString(cte.recursive.String())
+	}
+	buf.WriteByte(')')
+	return buf.String()
+}
+
+func (cte *SCTE) definitionVariables() []interface{} {
+	vars := cte.query.Variables()
+	if cte.recursive != nil {
+		vars = append(vars, cte.recursive.Variables()...)
+	}
+	return vars
+}
+
+// ctes returns the common table expressions that are defined in or referenced by the query.
+// An error is returned if two different CTEs share a name, or a recursive CTE is not supported by the backend,
+// the CTEs are returned anyway so that the statement rendered is rejected by the database
+func (tq *SQuery) ctes() ([]*SCTE, error) {
+	ret := make([]*SCTE, 0)
+	names := make(map[string]*SCTE)
+	var err error
+	add := func(src IQuerySource) {
+		cte, ok := src.(*SCTE)
+		if !ok || cte.isSelf {
+			return
+		}
+		if prev, ok := names[cte.name]; ok {
+			if prev.query == cte.query && prev.recursive == cte.recursive {
+				return
+			}
+			if err == nil {
+				err = errors.Wrap(ErrDuplicateCTE, cte.name)
+			}
+		} else {
+			names[cte.name] = cte
+		}
+		if cte.IsRecursive() && err == nil && !cte.database().backend.CanSupportRecursiveCTE() {
+			err = errors.Wrapf(ErrNotSupported, "recursive common table expression %s on backend %s", cte.name, cte.database().backend.Name())
+		}
+		ret = append(ret, cte)
+	}
+	for i := range tq.withs {
+		add(tq.withs[i])
+	}
+	if tq.from != nil {
+		add(tq.from)
+	}
+	for i := range tq.joins {
+		add(tq.joins[i].from)
+	}
+	return ret, err
+}
+
+// withClause returns the WITH clause of the query, empty if no common table expression is involved
+func (tq *SQuery) withClause() string {
+	ctes, err := tq.ctes()
+	if err != nil {
+		log.Errorf("invalid WITH clause: %s", err)
+	}
+	if len(ctes) == 0 {
+		return ""
+	}
+	var buf strings.Builder
+	buf.WriteString("WITH ")
+	for i := range ctes {
+		if ctes[i].IsRecursive() {
+			buf.WriteString("RECURSIVE ")
+			break
+		}
+	}
+	for i := range ctes {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(ctes[i].definition())
+	}
+	buf.WriteByte(' ')
+	return buf.String()
+}
@@ -15,6 +15,7 @@
 package sqlchemy
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -62,15 +63,15 @@ func (sqf *SSubQuery) DebugQuery() {
 
 // DebugInsert does insert with debug mode on
 func (t *STableSpec) DebugInsert(dt interface{}) error {
-	return t.insert(dt, false, true)
+	return t.insert(context.Background(), dt, false, true)
 }
 
 // DebugInsertOrUpdate does insertOrUpdate with debug mode on
 func (t *STableSpec) DebugInsertOrUpdate(dt interface{}) error {
-	return t.insert(dt, true, true)
+	return t.insert(context.Background(), dt, true, true)
 }
 
 // DebugUpdateFields does update with debug mode on
 func (t *STableSpec) DebugUpdateFields(dt interface{}, fields map[string]interface{}) error {
-	return t.updateFields(dt, fields, true)
+	return t.updateFields(context.Background(), dt, fields, true)
 }
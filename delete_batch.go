@@ -15,6 +15,7 @@
 package sqlchemy
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"strings"
@@ -22,7 +23,7 @@ import (
 	"yunion.io/x/log"
 )
 
-func getSQLFilters(filter map[string]interface{}) ([]string, []interface{}) {
+func (ts *STableSpec) getSQLFilters(filter map[string]interface{}) ([]string, []interface{}) {
 	conds := make([]string, 0, len(filter))
 	params := make([]interface{}, 0, len(filter))
 	for k, v := range filter {
@@ -36,9 +37,9 @@ func getSQLFilters(filter map[string]interface{}) ([]string, []interface{}) {
 				arr[i] = "?"
 				params = append(params, value.Index(i).Interface())
 			}
-			conds = append(conds, fmt.Sprintf("`%s` in (%s)", k, strings.Join(arr, ", ")))
+			conds = append(conds, fmt.Sprintf("%s in (%s)", ts.quoteIdentifier(k), strings.Join(arr, ", ")))
 		} else {
-			conds = append(conds, fmt.Sprintf("`%s` = ?", k))
+			conds = append(conds, fmt.Sprintf("%s = ?", ts.quoteIdentifier(k)))
 			params = append(params, v)
 		}
 	}
@@ -46,13 +47,29 @@ func getSQLFilters(filter map[string]interface{}) ([]string, []interface{}) {
 }
 
 func (ts *STableSpec) DeleteFrom(filters map[string]interface{}) error {
+	return ts.DeleteFromContext(context.Background(), filters)
+}
+
+// DeleteFromContext is the context-aware version of DeleteFrom,
+// the rows of a soft-deletable table are marked as deleted instead
+func (ts *STableSpec) DeleteFromContext(ctx context.Context, filters map[string]interface{}) error {
+	if err := ts.checkNoHooks("DeleteFrom"); err != nil {
+		return err
+	}
+	if ts.IsSoftDelete() {
+		_, err := ts.softDeleteWhere(ctx, ts.filtersCondition(filters))
+		return err
+	}
+	return ts.hardDeleteFrom(ctx, filters)
+}
+
+func (ts *STableSpec) hardDeleteFrom(ctx context.Context, filters map[string]interface{}) error {
 	buf := strings.Builder{}
 
-	buf.WriteString("DELETE FROM `")
-	buf.WriteString(ts.Name())
-	buf.WriteString("`")
+	buf.WriteString("DELETE FROM ")
+	buf.WriteString(ts.quoteIdentifier(ts.Name()))
 
-	conds, params := getSQLFilters(filters)
+	conds, params := ts.getSQLFilters(filters)
 
 	if len(conds) > 0 {
 		buf.WriteString(" WHERE ")
@@ -63,6 +80,46 @@ func (ts *STableSpec) DeleteFrom(filters map[string]interface{}) error {
 		log.Infof("Update: %s %s", buf.String(), params)
 	}
 
-	_, err := ts.Database().TxExec(buf.String(), params...)
+	_, err := ts.Database().TxExecContext(ctx, buf.String(), params...)
 	return err
 }
+
+// DeleteWhere deletes the rows matching cond, whose fields should come from ts.Target().
+// It returns the number of deleted rows, which is always 0 if the backend does not support RowsAffected,
+// e.g. the mutations of ClickHouse. The rows of a soft-deletable table are marked as deleted instead.
+func (ts *STableSpec) DeleteWhere(cond ICondition) (int64, error) {
+	return ts.DeleteWhereContext(context.Background(), cond)
+}
+
+// DeleteWhereContext is the context-aware version of DeleteWhere
+func (ts *STableSpec) DeleteWhereContext(ctx context.Context, cond ICondition) (int64, error) {
+	if err := ts.checkNoHooks("DeleteWhere"); err != nil {
+		return 0, err
+	}
+	return ts.deleteWhere(ctx, cond)
+}
+
+func (ts *STableSpec) deleteWhere(ctx context.Context, cond ICondition) (int64, error) {
+	if ts.IsSoftDelete() {
+		return ts.softDeleteWhere(ctx, cond)
+	}
+	return ts.hardDeleteWhere(ctx, cond)
+}
+
+// DeleteWhereSql returns the SQL and variables of DeleteWhere
+func (ts *STableSpec) DeleteWhereSql(cond ICondition) (string, []interface{}) {
+	conditions, vars := ts.whereSqlConditions(cond)
+	deleteSql := templateEval(ts.Database().backend.DeleteSQLTemplate(), struct {
+		Table      string
+		Conditions string
+	}{
+		Table:      ts.quoteIdentifier(ts.name),
+		Conditions: conditions,
+	})
+
+	if DEBUG_SQLCHEMY {
+		log.Infof("Delete: %s %s", deleteSql, vars)
+	}
+
+	return deleteSql, vars
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlchemy
+
+import (
+	"strings"
+)
+
+// quoteIdentifier quotes the name of a table, column or alias with the quoter of the backend of db.
+// If db is unknown, e.g. the alias of a function field without any field argument, the backend
+// of the default database is used
+func quoteIdentifier(db *SDatabase, name string) string {
+	return dialectBackend(db).QuoteIdentifier(name)
+}
+
+func dialectBackend(db *SDatabase) IBackend {
+	if db == nil || db.backend == nil {
+		db = GetDefaultDB()
+	}
+	if db == nil || db.backend == nil {
+		return defaultBackend
+	}
+	return db.backend
+}
+
+// quoteIdentifierWithBackticks quotes an identifier with backticks, the embedded backticks are doubled
+func quoteIdentifierWithBackticks(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// Rebind converts the ? placeholders of a SQL statement into the placeholder style of the backend
+// of the database, e.g. $1, $2, ... for PostgreSQL. The question marks inside quoted strings
+// and identifiers are left untouched. The backslashes are escapes only in the E'...' strings,
+// as the standard conforming strings of PostgreSQL.
+func (db *SDatabase) Rebind(sqlstr string) string {
+	return rebindSQL(dialectBackend(db), sqlstr)
+}
+
+func rebindSQL(backend IBackend, sqlstr string) string {
+	if backend.Placeholder(1) == "?" || !strings.Contains(sqlstr, "?") {
+		return sqlstr
+	}
+	var buf strings.Builder
+	buf.Grow(len(sqlstr) + 16)
+	var quote byte
+	escape := false
+	index := 0
+	for i := 0; i < len(sqlstr); i++ {
+		c := sqlstr[i]
+		switch {
+		case quote != 0:
+			if escape && c == '\\' && i+1 < len(sqlstr) {
+				buf.WriteByte(c)
+				i++
+				c = sqlstr[i]
+			} else if c == quote {
+				quote = 0
+			}
+			buf.WriteByte(c)
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			escape = c == '\'' && isEscapeStringPrefix(sqlstr, i)
+			buf.WriteByte(c)
+		case c == '?':
+			index++
+			buf.WriteString(backend.Placeholder(index))
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
+
+// isEscapeStringPrefix returns whether the quote at pos starts an escape string, i.e. E'...'
+func isEscapeStringPrefix(sqlstr string, pos int) bool {
+	if pos == 0 || (sqlstr[pos-1] != 'E' && sqlstr[pos-1] != 'e') {
+		return false
+	}
+	if pos == 1 {
+		return true
+	}
+	c := sqlstr[pos-2]
+	return !(c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z')
+}
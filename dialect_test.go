@@ -0,0 +1,33 @@
+package sqlchemy
+
+import "testing"
+
+func TestQuoteIdentifier(t *testing.T) {
+	cases := []struct {
+		In   string
+		Want string
+	}{
+		{
+			In:   "name",
+			Want: "`name`",
+		},
+		{
+			In:   "na`me",
+			Want: "`na``me`",
+		},
+	}
+	for _, c := range cases {
+		got := defaultBackend.QuoteIdentifier(c.In)
+		if got != c.Want {
+			t.Errorf("want: %s got: %s", c.Want, got)
+		}
+	}
+}
+
+func TestRebindSQL(t *testing.T) {
+	sqlstr := "SELECT * FROM `t` WHERE `a` = ?"
+	got := rebindSQL(defaultBackend, sqlstr)
+	if got != sqlstr {
+		t.Errorf("want: %s got: %s", sqlstr, got)
+	}
+}
@@ -54,4 +54,10 @@ const (
 
 	// ErrUnionDatabasesNotMatch is an Error constant: backend database of union queries not match
 	ErrUnionAcrossDatabases = errors.Error("cannot union across different databases")
+
+	// ErrConcurrentModification is an Error constant: the record was modified by others since it was read
+	ErrConcurrentModification = errors.Error("concurrent modification")
+
+	// ErrDuplicateCTE is an Error constant: different common table expressions share a name in a query
+	ErrDuplicateCTE = errors.Error("duplicate common table expression name")
 )
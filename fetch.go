@@ -15,10 +15,12 @@
 package sqlchemy
 
 import (
+	"context"
 	"reflect"
 
 	"github.com/nyl1001/pkg/errors"
 	"github.com/nyl1001/pkg/gotypes"
+	"github.com/nyl1001/pkg/jsonutils"
 	"github.com/nyl1001/pkg/util/reflectutils"
 	"yunion.io/x/log"
 )
@@ -26,7 +28,12 @@ import (
 // Fetch method fetches the values of a struct whose primary key values have been set
 // input is a pointer to the model to be populated
 func (ts *STableSpec) Fetch(dt interface{}) error {
-	q := ts.Query()
+	return ts.FetchContext(context.Background(), dt)
+}
+
+// FetchContext is the context-aware version of Fetch
+func (ts *STableSpec) FetchContext(ctx context.Context, dt interface{}) error {
+	q := ts.Query().WithContext(ctx)
 	dataValue := reflect.ValueOf(dt).Elem()
 	fields := reflectutils.FetchStructFieldValueSet(dataValue)
 	for _, c := range ts.Columns() {
@@ -41,6 +48,11 @@ func (ts *STableSpec) Fetch(dt interface{}) error {
 // FetchAll method fetches the values of an array of structs whose primary key values have been set
 // input is a pointer to the array of models to be populated
 func (ts *STableSpec) FetchAll(dest interface{}) error {
+	return ts.FetchAllContext(context.Background(), dest)
+}
+
+// FetchAllContext is the context-aware version of FetchAll
+func (ts *STableSpec) FetchAllContext(ctx context.Context, dest interface{}) error {
 	arrayType := reflect.TypeOf(dest).Elem()
 	if arrayType.Kind() != reflect.Array && arrayType.Kind() != reflect.Slice {
 		return errors.Wrap(ErrNeedsArray, "dest is not an array or slice")
@@ -49,33 +61,59 @@ func (ts *STableSpec) FetchAll(dest interface{}) error {
 	arrayValue := reflect.ValueOf(dest).Elem()
 
 	primaryCols := ts.PrimaryColumns()
-	if len(primaryCols) != 1 {
-		return errors.Wrap(ErrNotSupported, "support 1 primary key only")
+	if len(primaryCols) == 0 {
+		return errors.Wrap(ErrEmptyPrimaryKey, "FetchAll")
 	}
-	primaryCol := primaryCols[0]
 
-	keyValues := make([]interface{}, arrayValue.Len())
+	keyValues := make([][]interface{}, arrayValue.Len())
 	for i := 0; i < arrayValue.Len(); i++ {
 		eleValue := arrayValue.Index(i)
 		fields := reflectutils.FetchStructFieldValueSet(eleValue)
-		keyValues[i], _ = fields.GetInterface(primaryCol.Name())
+		keyValues[i] = make([]interface{}, len(primaryCols))
+		for j, c := range primaryCols {
+			keyValues[i][j], _ = fields.GetInterface(c.Name())
+		}
 	}
-	q := ts.Query().In(primaryCol.Name(), keyValues)
 
-	tmpDestMaps, err := q.AllStringMap()
-	if err != nil {
-		return errors.Wrap(err, "q.AllStringMap")
+	// chunk the keys so that the number of placeholders of a query does not exceed the limit of the backend
+	chunkSize := ts.Database().backend.MaxInsertPlaceholders() / len(primaryCols)
+	if chunkSize <= 0 {
+		chunkSize = sqlBlockLimit
 	}
-
 	tmpDestMapMap := make(map[string]map[string]string)
-	for i := 0; i < len(tmpDestMaps); i++ {
-		tmpDestMapMap[tmpDestMaps[i][primaryCol.Name()]] = tmpDestMaps[i]
+	for start := 0; start < len(keyValues); start += chunkSize {
+		end := start + chunkSize
+		if end > len(keyValues) {
+			end = len(keyValues)
+		}
+		q := ts.Query().WithContext(ctx)
+		fields := make([]IQueryField, len(primaryCols))
+		for j, c := range primaryCols {
+			fields[j] = q.Field(c.Name())
+		}
+		q = q.Filter(InTuples(fields, keyValues[start:end]))
+
+		tmpDestMaps, err := q.AllStringMap()
+		if err != nil {
+			return errors.Wrap(err, "q.AllStringMap")
+		}
+		for i := 0; i < len(tmpDestMaps); i++ {
+			keys := make([]string, len(primaryCols))
+			for j, c := range primaryCols {
+				keys[j] = tmpDestMaps[i][c.Name()]
+			}
+			tmpDestMapMap[fetchKeyString(keys)] = tmpDestMaps[i]
+		}
 	}
 
 	for i := 0; i < arrayValue.Len(); i++ {
-		keyValueStr := GetStringValue(keyValues[i])
+		keys := make([]string, len(primaryCols))
+		for j := range primaryCols {
+			keys[j] = GetStringValue(keyValues[i][j])
+		}
+		keyValueStr := fetchKeyString(keys)
 		if tmpMap, ok := tmpDestMapMap[keyValueStr]; ok {
-			err = mapString2Struct(tmpMap, arrayValue.Index(i))
+			err := mapString2Struct(tmpMap, arrayValue.Index(i))
 			if err != nil {
 				return errors.Wrapf(err, "mapString2Struct %d:%s", i, keyValueStr)
 			}
@@ -86,3 +124,8 @@ func (ts *STableSpec) FetchAll(dest interface{}) error {
 
 	return nil
 }
+
+// fetchKeyString joins the string values of the primary keys as the key of a record
+func fetchKeyString(keys []string) string {
+	return jsonutils.Marshal(keys).String()
+}
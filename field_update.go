@@ -16,6 +16,7 @@ package sqlchemy
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"reflect"
 
@@ -28,7 +29,12 @@ import (
 // UpdateFields update a record with the values provided by fields stringmap
 // params dt: model struct, fileds: {struct-field-name-string: update-value}
 func (ts *STableSpec) UpdateFields(dt interface{}, fields map[string]interface{}) error {
-	return ts.updateFields(dt, fields, false)
+	return ts.UpdateFieldsContext(context.Background(), dt, fields)
+}
+
+// UpdateFieldsContext is the context-aware version of UpdateFields
+func (ts *STableSpec) UpdateFieldsContext(ctx context.Context, dt interface{}, fields map[string]interface{}) error {
+	return ts.updateFields(ctx, dt, fields, false)
 }
 
 // params dt: model struct, fileds: {struct-field-name-string: update-value}
@@ -88,27 +94,27 @@ func (ts *STableSpec) updateFieldSql(dt interface{}, fields map[string]interface
 
 	vars := make([]interface{}, 0)
 	var buf bytes.Buffer
-	buf.WriteString(fmt.Sprintf("UPDATE `%s` SET ", ts.name))
+	buf.WriteString(fmt.Sprintf("UPDATE %s SET ", ts.quoteIdentifier(ts.name)))
 	for i, k := range cnames {
 		v := cv[k]
 		if i > 0 {
 			buf.WriteString(", ")
 		}
-		buf.WriteString(fmt.Sprintf("`%s` = ?", k))
+		buf.WriteString(fmt.Sprintf("%s = ?", ts.quoteIdentifier(k)))
 		vars = append(vars, v)
 	}
 	for _, versionField := range versionFields {
-		buf.WriteString(fmt.Sprintf(", `%s` = `%s` + 1", versionField, versionField))
+		buf.WriteString(fmt.Sprintf(", %s = %s + 1", ts.quoteIdentifier(versionField), ts.quoteIdentifier(versionField)))
 	}
 	for _, updatedField := range updatedFields {
-		buf.WriteString(fmt.Sprintf(", `%s` = %s", updatedField, ts.Database().backend.CurrentUTCTimeStampString()))
+		buf.WriteString(fmt.Sprintf(", %s = %s", ts.quoteIdentifier(updatedField), ts.Database().backend.CurrentUTCTimeStampString()))
 	}
 	buf.WriteString(" WHERE ")
 	for i, pkv := range primaryCols {
 		if i > 0 {
 			buf.WriteString(" AND ")
 		}
-		buf.WriteString(fmt.Sprintf("`%s` = ?", pkv.key))
+		buf.WriteString(fmt.Sprintf("%s = ?", ts.quoteIdentifier(pkv.key)))
 		vars = append(vars, pkv.value)
 	}
 
@@ -123,13 +129,16 @@ func (ts *STableSpec) updateFieldSql(dt interface{}, fields map[string]interface
 	}, nil
 }
 
-func (ts *STableSpec) updateFields(dt interface{}, fields map[string]interface{}, debug bool) error {
+func (ts *STableSpec) updateFields(ctx context.Context, dt interface{}, fields map[string]interface{}, debug bool) error {
+	if err := ts.checkNoHooks("UpdateFields", dt); err != nil {
+		return err
+	}
 	results, err := ts.updateFieldSql(dt, fields, debug)
 	if err != nil {
 		return errors.Wrap(err, "updateFieldSql")
 	}
 
-	err = ts.execUpdateSql(dt, results)
+	err = ts.execUpdateSql(ctx, dt, results)
 	if err != nil {
 		return errors.Wrap(err, "execUpdateSql")
 	}
@@ -35,12 +35,12 @@ func (tq *SQuery) Filter(cond ICondition) *SQuery {
 
 // FilterByTrue filters query with a true condition
 func (tq *SQuery) FilterByTrue() *SQuery {
-	return tq.Filter(&STrueCondition{})
+	return tq.Filter(&STrueCondition{db: tq.database()})
 }
 
 // FilterByFalse filters query with a false condition
 func (tq *SQuery) FilterByFalse() *SQuery {
-	return tq.Filter(&SFalseCondition{})
+	return tq.Filter(&SFalseCondition{db: tq.database()})
 }
 
 // Like filters query with a like condition
@@ -50,14 +50,14 @@ func (ff *SFunctionFieldBase) Reference() string {
 		// log.Warningf("reference a function field without alias! %s", ff.expression())
 		return ff.expression()
 	}
-	return fmt.Sprintf("`%s`", ff.alias)
+	return quoteIdentifier(ff.database(), ff.alias)
 }
 
 // Expression implementation of SFunctionFieldBase for IQueryField
 func (ff *SFunctionFieldBase) Expression() string {
 	if len(ff.alias) > 0 {
 		// add alias
-		return fmt.Sprintf("%s AS `%s`", ff.expression(), ff.alias)
+		return fmt.Sprintf("%s AS %s", ff.expression(), quoteIdentifier(ff.database(), ff.alias))
 	}
 	// no alias
 	return ff.expression()
@@ -196,7 +196,7 @@ func (s *SConstField) Expression() string {
 	if len(name) == 0 {
 		return s.Reference()
 	} else {
-		return fmt.Sprintf("%s AS `%s`", s.Reference(), name)
+		return fmt.Sprintf("%s AS %s", s.Reference(), quoteIdentifier(nil, name))
 	}
 }
 
@@ -245,7 +245,7 @@ func (s *SStringField) Expression() string {
 	if len(name) == 0 {
 		return s.Reference()
 	} else {
-		return fmt.Sprintf("%s AS `%s`", s.Reference(), name)
+		return fmt.Sprintf("%s AS %s", s.Reference(), quoteIdentifier(nil, name))
 	}
 }
 
@@ -0,0 +1,228 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlchemy
+
+import (
+	"context"
+)
+
+// Table is a typed table of the model T, on top of the untyped STableSpec
+type Table[T any] struct {
+	*STableSpec
+}
+
+// NewTable returns the typed table of the model T on the default database
+func NewTable[T any](name string) *Table[T] {
+	return NewTableWithDBName[T](name, DefaultDB)
+}
+
+// NewTableWithDBName returns the typed table of the model T on the database of dbName
+func NewTableWithDBName[T any](name string, dbName DBName) *Table[T] {
+	var dt T
+	return &Table[T]{
+		STableSpec: NewTableSpecFromStructWithDBName(dt, name, dbName),
+	}
+}
+
+// Spec returns the underlying untyped table
+func (t *Table[T]) Spec() *STableSpec {
+	return t.STableSpec
+}
+
+// Query returns a typed query of the table
+func (t *Table[T]) Query(f ...IQueryField) *Query[T] {
+	return NewQuery[T](t.STableSpec.Query(f...))
+}
+
+// Insert inserts a record
+func (t *Table[T]) Insert(dt *T) error {
+	return t.STableSpec.Insert(dt)
+}
+
+// InsertContext is the context-aware version of Insert
+func (t *Table[T]) InsertContext(ctx context.Context, dt *T) error {
+	return t.STableSpec.InsertContext(ctx, dt)
+}
+
+// InsertOrUpdate inserts a record, or updates the record if it exists
+func (t *Table[T]) InsertOrUpdate(dt *T) error {
+	return t.STableSpec.InsertOrUpdate(dt)
+}
+
+// InsertOrUpdateContext is the context-aware version of InsertOrUpdate
+func (t *Table[T]) InsertOrUpdateContext(ctx context.Context, dt *T) error {
+	return t.STableSpec.InsertOrUpdateContext(ctx, dt)
+}
+
+// Update updates a record by the changes made by doUpdate
+func (t *Table[T]) Update(dt *T, doUpdate func(*T) error) (UpdateDiffs, error) {
+	return t.UpdateContext(context.Background(), dt, doUpdate)
+}
+
+// UpdateContext is the context-aware version of Update
+func (t *Table[T]) UpdateContext(ctx context.Context, dt *T, doUpdate func(*T) error) (UpdateDiffs, error) {
+	return t.STableSpec.UpdateContext(ctx, dt, func() error {
+		return doUpdate(dt)
+	})
+}
+
+// Delete deletes a record by its primary keys
+func (t *Table[T]) Delete(dt *T) error {
+	return t.STableSpec.Delete(dt)
+}
+
+// DeleteContext is the context-aware version of Delete
+func (t *Table[T]) DeleteContext(ctx context.Context, dt *T) error {
+	return t.STableSpec.DeleteContext(ctx, dt)
+}
+
+// Fetch fetches a record whose primary key values have been set
+func (t *Table[T]) Fetch(dt *T) error {
+	return t.STableSpec.Fetch(dt)
+}
+
+// FetchContext is the context-aware version of Fetch
+func (t *Table[T]) FetchContext(ctx context.Context, dt *T) error {
+	return t.STableSpec.FetchContext(ctx, dt)
+}
+
+// Query is a typed query whose rows are decoded into the model T, on top of the untyped SQuery
+type Query[T any] struct {
+	query *SQuery
+}
+
+// NewQuery returns the typed query of an untyped query
+func NewQuery[T any](q *SQuery) *Query[T] {
+	return &Query[T]{query: q}
+}
+
+// SQuery returns the underlying untyped query, which is shared with the typed query
+func (q *Query[T]) SQuery() *SQuery {
+	return q.query
+}
+
+// Field returns the field of the query by name
+func (q *Query[T]) Field(name string) IQueryField {
+	return q.query.Field(name)
+}
+
+// Filter adds a condition to the query
+func (q *Query[T]) Filter(cond ICondition) *Query[T] {
+	q.query.Filter(cond)
+	return q
+}
+
+// Equals adds a condition that the field equals the value
+func (q *Query[T]) Equals(field string, val interface{}) *Query[T] {
+	q.query.Equals(field, val)
+	return q
+}
+
+// In adds a condition that the field is in the values
+func (q *Query[T]) In(field string, val interface{}) *Query[T] {
+	q.query.In(field, val)
+	return q
+}
+
+// Asc orders the query in ascending order of the fields
+func (q *Query[T]) Asc(fields ...interface{}) *Query[T] {
+	q.query.Asc(fields...)
+	return q
+}
+
+// Desc orders the query in descending order of the fields
+func (q *Query[T]) Desc(fields ...interface{}) *Query[T] {
+	q.query.Desc(fields...)
+	return q
+}
+
+// Limit limits the number of rows
+func (q *Query[T]) Limit(limit int) *Query[T] {
+	q.query.Limit(limit)
+	return q
+}
+
+// Offset skips the rows before offset
+func (q *Query[T]) Offset(offset int) *Query[T] {
+	q.query.Offset(offset)
+	return q
+}
+
+// Unscoped includes the soft-deleted rows
+func (q *Query[T]) Unscoped() *Query[T] {
+	q.query.Unscoped()
+	return q
+}
+
+// Preload loads the relations into the rows returned by First, All and IterateByKeyset
+func (q *Query[T]) Preload(names ...string) *Query[T] {
+	q.query.Preload(names...)
+	return q
+}
+
+// PageAfter restricts the query to the rows after the cursor, see SQuery.PageAfter
+func (q *Query[T]) PageAfter(cursor string, orderFields ...interface{}) (*Query[T], error) {
+	_, err := q.query.PageAfter(cursor, orderFields...)
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// PageCursor returns the cursor of the row for the next page
+func (q *Query[T]) PageCursor(row *T) (string, error) {
+	return q.query.PageCursor(row)
+}
+
+// WithContext binds the query to the context
+func (q *Query[T]) WithContext(ctx context.Context) *Query[T] {
+	q.query.WithContext(ctx)
+	return q
+}
+
+// First returns the first row of the query, sql.ErrNoRows if there is none
+func (q *Query[T]) First() (*T, error) {
+	dt := new(T)
+	err := q.query.First(dt)
+	if err != nil {
+		return nil, err
+	}
+	return dt, nil
+}
+
+// All returns all rows of the query
+func (q *Query[T]) All() ([]T, error) {
+	ret := make([]T, 0)
+	err := q.query.All(&ret)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// Count returns the number of rows of the query
+func (q *Query[T]) Count() (int, error) {
+	return q.query.CountWithError()
+}
+
+// Iterate calls fn with the rows one at a time
+func (q *Query[T]) Iterate(fn func(*T) error) error {
+	return q.query.Iterate(fn)
+}
+
+// IterateByKeyset walks the rows in the order of the primary keys, chunkSize rows a query
+func (q *Query[T]) IterateByKeyset(chunkSize int, fn func(*T) error) error {
+	return q.query.IterateByKeyset(chunkSize, fn)
+}
@@ -1,6 +1,6 @@
 module github.com/nyl1001/sqlchemy
 
-go 1.17
+go 1.18
 
 require (
 	github.com/ClickHouse/clickhouse-go v1.5.4
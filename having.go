@@ -0,0 +1,185 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlchemy
+
+// Having method filters the groups of a SQL query with given ICondition
+// equivalent to add a clause in having conditions, it is usually used with GroupBy
+// to filter on aggregates, e.g. COUNT(...) > 5
+func (tq *SQuery) Having(cond ICondition) *SQuery {
+	if tq.having == nil {
+		tq.having = cond
+	} else {
+		tq.having = AND(tq.having, cond)
+	}
+	return tq
+}
+
+// havingField returns the field of the name to be filtered in HAVING, which is the aggregate expression itself
+// rather than its label, because some backends, e.g. PostgreSQL, reject the output column labels in HAVING
+func (tq *SQuery) havingField(name string) IQueryField {
+	f := tq.Field(name)
+	if ff, ok := f.(*SFunctionFieldBase); ok && len(ff.alias) > 0 {
+		return &SFunctionFieldBase{IFunction: ff.IFunction}
+	}
+	return f
+}
+
+// HavingByTrue filters the groups of the query with a true condition
+func (tq *SQuery) HavingByTrue() *SQuery {
+	return tq.Having(&STrueCondition{db: tq.database()})
+}
+
+// HavingByFalse filters the groups of the query with a false condition
+func (tq *SQuery) HavingByFalse() *SQuery {
+	return tq.Having(&SFalseCondition{db: tq.database()})
+}
+
+// HavingLike filters the groups of the query with a like condition
+func (tq *SQuery) HavingLike(f string, v string) *SQuery {
+	cond := Like(tq.havingField(f), v)
+	return tq.Having(cond)
+}
+
+// HavingRegexp filters the groups of the query with a regexp condition
+func (tq *SQuery) HavingRegexp(f string, v string) *SQuery {
+	cond := Regexp(tq.havingField(f), v)
+	return tq.Having(cond)
+}
+
+// HavingContains filters the groups of the query with a contains condition
+func (tq *SQuery) HavingContains(f string, v string) *SQuery {
+	cond := Contains(tq.havingField(f), v)
+	return tq.Having(cond)
+}
+
+// HavingStartswith filters the groups of the query with a startswith condition
+func (tq *SQuery) HavingStartswith(f string, v string) *SQuery {
+	cond := Startswith(tq.havingField(f), v)
+	return tq.Having(cond)
+}
+
+// HavingEndswith filters the groups of the query with a endswith condition
+func (tq *SQuery) HavingEndswith(f string, v string) *SQuery {
+	cond := Endswith(tq.havingField(f), v)
+	return tq.Having(cond)
+}
+
+// HavingNotLike filters the groups of the query with a not like condition
+func (tq *SQuery) HavingNotLike(f string, v string) *SQuery {
+	cond := Like(tq.havingField(f), v)
+	return tq.Having(NOT(cond))
+}
+
+// HavingIn filters the groups of the query with a in condition
+func (tq *SQuery) HavingIn(f string, v interface{}) *SQuery {
+	cond := In(tq.havingField(f), v)
+	return tq.Having(cond)
+}
+
+// HavingNotIn filters the groups of the query with a not in condition
+func (tq *SQuery) HavingNotIn(f string, v interface{}) *SQuery {
+	cond := NotIn(tq.havingField(f), v)
+	return tq.Having(cond)
+}
+
+// HavingBetween filters the groups of the query with a between condition
+func (tq *SQuery) HavingBetween(f string, v1, v2 interface{}) *SQuery {
+	cond := Between(tq.havingField(f), v1, v2)
+	return tq.Having(cond)
+}
+
+// HavingNotBetween filters the groups of the query with a not between condition
+func (tq *SQuery) HavingNotBetween(f string, v1, v2 interface{}) *SQuery {
+	cond := Between(tq.havingField(f), v1, v2)
+	return tq.Having(NOT(cond))
+}
+
+// HavingEquals filters the groups of the query with a equals condition
+func (tq *SQuery) HavingEquals(f string, v interface{}) *SQuery {
+	cond := Equals(tq.havingField(f), v)
+	return tq.Having(cond)
+}
+
+// HavingNotEquals filters the groups of the query with a not equals condition
+func (tq *SQuery) HavingNotEquals(f string, v interface{}) *SQuery {
+	cond := NotEquals(tq.havingField(f), v)
+	return tq.Having(cond)
+}
+
+// HavingGE filters the groups of the query with a >= condition
+func (tq *SQuery) HavingGE(f string, v interface{}) *SQuery {
+	cond := GE(tq.havingField(f), v)
+	return tq.Having(cond)
+}
+
+// HavingLE filters the groups of the query with a <= condition
+func (tq *SQuery) HavingLE(f string, v interface{}) *SQuery {
+	cond := LE(tq.havingField(f), v)
+	return tq.Having(cond)
+}
+
+// HavingGT filters the groups of the query with a > condition
+func (tq *SQuery) HavingGT(f string, v interface{}) *SQuery {
+	cond := GT(tq.havingField(f), v)
+	return tq.Having(cond)
+}
+
+// HavingLT filters the groups of the query with a < condition
+func (tq *SQuery) HavingLT(f string, v interface{}) *SQuery {
+	cond := LT(tq.havingField(f), v)
+	return tq.Having(cond)
+}
+
+// HavingIsNull filters the groups of the query with a is null condition
+func (tq *SQuery) HavingIsNull(f string) *SQuery {
+	cond := IsNull(tq.havingField(f))
+	return tq.Having(cond)
+}
+
+// HavingIsNotNull filters the groups of the query with a is not null condition
+func (tq *SQuery) HavingIsNotNull(f string) *SQuery {
+	cond := IsNotNull(tq.havingField(f))
+	return tq.Having(cond)
+}
+
+// HavingIsEmpty filters the groups of the query with a is_empty condition
+func (tq *SQuery) HavingIsEmpty(f string) *SQuery {
+	cond := IsEmpty(tq.havingField(f))
+	return tq.Having(cond)
+}
+
+// HavingIsNullOrEmpty filters the groups of the query with a is null or empty condition
+func (tq *SQuery) HavingIsNullOrEmpty(f string) *SQuery {
+	cond := IsNullOrEmpty(tq.havingField(f))
+	return tq.Having(cond)
+}
+
+// HavingIsNotEmpty filters the groups of the query with a is not empty condition
+func (tq *SQuery) HavingIsNotEmpty(f string) *SQuery {
+	cond := IsNotEmpty(tq.havingField(f))
+	return tq.Having(cond)
+}
+
+// HavingIsTrue filters the groups of the query with a is true condition
+func (tq *SQuery) HavingIsTrue(f string) *SQuery {
+	cond := IsTrue(tq.havingField(f))
+	return tq.Having(cond)
+}
+
+// HavingIsFalse filters the groups of the query with a is false condition
+func (tq *SQuery) HavingIsFalse(f string) *SQuery {
+	cond := IsFalse(tq.havingField(f))
+	return tq.Having(cond)
+}
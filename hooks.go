@@ -0,0 +1,251 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlchemy
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/nyl1001/pkg/errors"
+	"github.com/nyl1001/pkg/gotypes"
+	"github.com/nyl1001/pkg/util/reflectutils"
+)
+
+// IAfterInsertHook is implemented by a model that should be notified after it is inserted
+type IAfterInsertHook interface {
+	AfterInsert(ctx context.Context) error
+}
+
+// IAfterUpdateHook is implemented by a model that should be notified after it is updated
+type IAfterUpdateHook interface {
+	AfterUpdate(ctx context.Context, diffs UpdateDiffs) error
+}
+
+// IBeforeDeleteHook is implemented by a model that should be notified before it is deleted
+type IBeforeDeleteHook interface {
+	BeforeDelete(ctx context.Context) error
+}
+
+// IAfterDeleteHook is implemented by a model that should be notified after it is deleted
+type IAfterDeleteHook interface {
+	AfterDelete(ctx context.Context) error
+}
+
+// ITableHook is the interface of the hooks registered on a table, which are called
+// for all records of the table, e.g. for auditing. An error returned by a hook aborts the operation.
+type ITableHook interface {
+	BeforeInsert(ctx context.Context, ts *STableSpec, dt interface{}) error
+	AfterInsert(ctx context.Context, ts *STableSpec, dt interface{}) error
+
+	BeforeUpdate(ctx context.Context, ts *STableSpec, dt interface{}) error
+	AfterUpdate(ctx context.Context, ts *STableSpec, dt interface{}, diffs UpdateDiffs) error
+
+	BeforeDelete(ctx context.Context, ts *STableSpec, dt interface{}) error
+	AfterDelete(ctx context.Context, ts *STableSpec, dt interface{}) error
+}
+
+// SBaseTableHook implements ITableHook with hooks doing nothing, to be embedded by the table hooks
+type SBaseTableHook struct{}
+
+func (SBaseTableHook) BeforeInsert(ctx context.Context, ts *STableSpec, dt interface{}) error {
+	return nil
+}
+
+func (SBaseTableHook) AfterInsert(ctx context.Context, ts *STableSpec, dt interface{}) error {
+	return nil
+}
+
+func (SBaseTableHook) BeforeUpdate(ctx context.Context, ts *STableSpec, dt interface{}) error {
+	return nil
+}
+
+func (SBaseTableHook) AfterUpdate(ctx context.Context, ts *STableSpec, dt interface{}, diffs UpdateDiffs) error {
+	return nil
+}
+
+func (SBaseTableHook) BeforeDelete(ctx context.Context, ts *STableSpec, dt interface{}) error {
+	return nil
+}
+
+func (SBaseTableHook) AfterDelete(ctx context.Context, ts *STableSpec, dt interface{}) error {
+	return nil
+}
+
+// AddHook registers a hook called on Insert, Update and Delete of the records of the table.
+// InsertOrUpdate, which cannot tell whether a record is inserted or updated, and the bulk and field-level writes,
+// i.e. InsertBatch, InsertOrUpdateBatch, InsertFromQuery, UpdateWhere, UpdateJoin, UpdateFields, Increment,
+// Decrement, DeleteFrom, DeleteWhere and HardDelete, do not run the hooks, so they fail with ErrNotSupported
+// on a table with hooks
+func (ts *STableSpec) AddHook(hook ITableHook) {
+	ts.hooks = append(ts.hooks, hook)
+}
+
+// checkNoHooks returns ErrNotSupported for a write that does not run the hooks, if any table hook is registered
+// or any of dts implements a hook interface
+func (ts *STableSpec) checkNoHooks(op string, dts ...interface{}) error {
+	if len(ts.hooks) > 0 {
+		return errors.Wrapf(ErrNotSupported, "%s of table %s with hooks", op, ts.Name())
+	}
+	for _, dt := range dts {
+		if ts.hasHooks(dt) {
+			return errors.Wrapf(ErrNotSupported, "%s of %T with hooks", op, dt)
+		}
+	}
+	return nil
+}
+
+// hasHooks returns whether any table hook is registered or dt implements any hook interface
+func (ts *STableSpec) hasHooks(dt interface{}) bool {
+	if len(ts.hooks) > 0 {
+		return true
+	}
+	switch dt.(type) {
+	case IAfterInsertHook, IAfterUpdateHook, IBeforeDeleteHook, IAfterDeleteHook:
+		return true
+	}
+	return false
+}
+
+// runWithHooks executes fn within a transaction if there are hooks of dt,
+// so that the statement and the hooks are committed or rolled back together
+func (ts *STableSpec) runWithHooks(ctx context.Context, dt interface{}, fn func(ctx context.Context) error) error {
+	if !ts.hasHooks(dt) {
+		return fn(ctx)
+	}
+	return ts.Database().RunInTxContext(ctx, func(tx *STx) error {
+		return fn(tx.Context())
+	})
+}
+
+func (ts *STableSpec) callBeforeInsertHooks(ctx context.Context, dt interface{}) error {
+	for _, hook := range ts.hooks {
+		if err := hook.BeforeInsert(ctx, ts, dt); err != nil {
+			return errors.Wrap(err, "BeforeInsert")
+		}
+	}
+	return nil
+}
+
+func (ts *STableSpec) callAfterInsertHooks(ctx context.Context, dt interface{}) error {
+	if hook, ok := dt.(IAfterInsertHook); ok {
+		if err := hook.AfterInsert(ctx); err != nil {
+			return errors.Wrap(err, "AfterInsert")
+		}
+	}
+	for _, hook := range ts.hooks {
+		if err := hook.AfterInsert(ctx, ts, dt); err != nil {
+			return errors.Wrap(err, "AfterInsert")
+		}
+	}
+	return nil
+}
+
+func (ts *STableSpec) callBeforeUpdateHooks(ctx context.Context, dt interface{}) error {
+	for _, hook := range ts.hooks {
+		if err := hook.BeforeUpdate(ctx, ts, dt); err != nil {
+			return errors.Wrap(err, "BeforeUpdate")
+		}
+	}
+	return nil
+}
+
+func (ts *STableSpec) callAfterUpdateHooks(ctx context.Context, dt interface{}, diffs UpdateDiffs) error {
+	if hook, ok := dt.(IAfterUpdateHook); ok {
+		if err := hook.AfterUpdate(ctx, diffs); err != nil {
+			return errors.Wrap(err, "AfterUpdate")
+		}
+	}
+	for _, hook := range ts.hooks {
+		if err := hook.AfterUpdate(ctx, ts, dt, diffs); err != nil {
+			return errors.Wrap(err, "AfterUpdate")
+		}
+	}
+	return nil
+}
+
+func (ts *STableSpec) callBeforeDeleteHooks(ctx context.Context, dt interface{}) error {
+	if hook, ok := dt.(IBeforeDeleteHook); ok {
+		if err := hook.BeforeDelete(ctx); err != nil {
+			return errors.Wrap(err, "BeforeDelete")
+		}
+	}
+	for _, hook := range ts.hooks {
+		if err := hook.BeforeDelete(ctx, ts, dt); err != nil {
+			return errors.Wrap(err, "BeforeDelete")
+		}
+	}
+	return nil
+}
+
+func (ts *STableSpec) callAfterDeleteHooks(ctx context.Context, dt interface{}) error {
+	if hook, ok := dt.(IAfterDeleteHook); ok {
+		if err := hook.AfterDelete(ctx); err != nil {
+			return errors.Wrap(err, "AfterDelete")
+		}
+	}
+	for _, hook := range ts.hooks {
+		if err := hook.AfterDelete(ctx, ts, dt); err != nil {
+			return errors.Wrap(err, "AfterDelete")
+		}
+	}
+	return nil
+}
+
+// Delete deletes the record whose primary key values have been set in dt,
+// the record of a soft-deletable table is marked as deleted instead.
+// Different from DeleteFrom and DeleteWhere, the delete hooks are called.
+func (ts *STableSpec) Delete(dt interface{}) error {
+	return ts.DeleteContext(context.Background(), dt)
+}
+
+// DeleteContext is the context-aware version of Delete
+func (ts *STableSpec) DeleteContext(ctx context.Context, dt interface{}) error {
+	cond, err := ts.primaryCondition(dt)
+	if err != nil {
+		return errors.Wrap(err, "primaryCondition")
+	}
+	return ts.runWithHooks(ctx, dt, func(ctx context.Context) error {
+		err := ts.callBeforeDeleteHooks(ctx, dt)
+		if err != nil {
+			return err
+		}
+		cnt, err := ts.deleteWhere(ctx, cond)
+		if err != nil {
+			return errors.Wrap(err, "deleteWhere")
+		}
+		if cnt > 1 {
+			return errors.Wrapf(ErrUnexpectRowCount, "deleted rows %d != 1", cnt)
+		}
+		return ts.callAfterDeleteHooks(ctx, dt)
+	})
+}
+
+// primaryCondition returns the condition on Target() matching the primary key values of dt
+func (ts *STableSpec) primaryCondition(dt interface{}) (ICondition, error) {
+	fields := reflectutils.FetchStructFieldValueSet(reflect.Indirect(reflect.ValueOf(dt)))
+	target := ts.Target()
+	conds := make([]ICondition, 0)
+	for _, c := range ts.PrimaryColumns() {
+		v, _ := fields.GetInterface(c.Name())
+		if gotypes.IsNil(v) || c.IsZero(v) {
+			return nil, ErrEmptyPrimaryKey
+		}
+		conds = append(conds, Equals(target.Field(c.Name()), v))
+	}
+	if len(conds) == 0 {
+		return nil, ErrEmptyPrimaryKey
+	}
+	return AND(conds...), nil
+}
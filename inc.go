@@ -16,6 +16,7 @@ package sqlchemy
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"reflect"
 
@@ -30,19 +31,35 @@ import (
 // if target is given as a pointer to a variable, the result will be stored in the target
 // if target is not given, the updated result will be stored in diff
 func (t *STableSpec) Increment(diff interface{}, target interface{}) error {
+	return t.IncrementContext(context.Background(), diff, target)
+}
+
+// IncrementContext is the context-aware version of Increment
+func (t *STableSpec) IncrementContext(ctx context.Context, diff interface{}, target interface{}) error {
 	if !t.Database().backend.CanUpdate() {
 		return errors.ErrNotSupported
 	}
-	return t.incrementInternal(diff, "+", target)
+	if err := t.checkNoHooks("Increment", diff, target); err != nil {
+		return err
+	}
+	return t.incrementInternal(ctx, diff, "+", target)
 }
 
 // Decrement is similar to Increment methods, the difference is that this method will atomically decrease the numeric fields
 // with the value of diff
 func (t *STableSpec) Decrement(diff interface{}, target interface{}) error {
+	return t.DecrementContext(context.Background(), diff, target)
+}
+
+// DecrementContext is the context-aware version of Decrement
+func (t *STableSpec) DecrementContext(ctx context.Context, diff interface{}, target interface{}) error {
 	if !t.Database().backend.CanUpdate() {
 		return errors.ErrNotSupported
 	}
-	return t.incrementInternal(diff, "-", target)
+	if err := t.checkNoHooks("Decrement", diff, target); err != nil {
+		return err
+	}
+	return t.incrementInternal(ctx, diff, "-", target)
 }
 
 func (t *STableSpec) incrementInternalSql(diff interface{}, opcode string, target interface{}) (*SUpdateSQLResult, error) {
@@ -105,7 +122,7 @@ func (t *STableSpec) incrementInternalSql(diff interface{}, opcode string, targe
 	}
 
 	var buf bytes.Buffer
-	buf.WriteString(fmt.Sprintf("UPDATE `%s` SET ", t.name))
+	buf.WriteString(fmt.Sprintf("UPDATE %s SET ", t.quoteIdentifier(t.name)))
 	first := true
 	for _, k := range incFields {
 		if first {
@@ -113,13 +130,13 @@ func (t *STableSpec) incrementInternalSql(diff interface{}, opcode string, targe
 		} else {
 			buf.WriteString(", ")
 		}
-		buf.WriteString(fmt.Sprintf("`%s` = `%s` %s ?", k, k, opcode))
+		buf.WriteString(fmt.Sprintf("%s = %s %s ?", t.quoteIdentifier(k), t.quoteIdentifier(k), opcode))
 	}
 	for _, versionField := range versionFields {
-		buf.WriteString(fmt.Sprintf(", `%s` = `%s` + 1", versionField, versionField))
+		buf.WriteString(fmt.Sprintf(", %s = %s + 1", t.quoteIdentifier(versionField), t.quoteIdentifier(versionField)))
 	}
 	for _, updatedField := range updatedFields {
-		buf.WriteString(fmt.Sprintf(", `%s` = %s", updatedField, t.Database().backend.CurrentUTCTimeStampString()))
+		buf.WriteString(fmt.Sprintf(", %s = %s", t.quoteIdentifier(updatedField), t.Database().backend.CurrentUTCTimeStampString()))
 	}
 
 	buf.WriteString(" WHERE ")
@@ -127,7 +144,7 @@ func (t *STableSpec) incrementInternalSql(diff interface{}, opcode string, targe
 		if i > 0 {
 			buf.WriteString(" AND ")
 		}
-		buf.WriteString(fmt.Sprintf("`%s` = ?", pkv.key))
+		buf.WriteString(fmt.Sprintf("%s = ?", t.quoteIdentifier(pkv.key)))
 		vars = append(vars, pkv.value)
 	}
 
@@ -142,7 +159,7 @@ func (t *STableSpec) incrementInternalSql(diff interface{}, opcode string, targe
 	}, nil
 }
 
-func (t *STableSpec) incrementInternal(diff interface{}, opcode string, target interface{}) error {
+func (t *STableSpec) incrementInternal(ctx context.Context, diff interface{}, opcode string, target interface{}) error {
 	if target == nil {
 		if reflect.ValueOf(diff).Kind() != reflect.Ptr {
 			return errors.Wrap(ErrNeedsPointer, "Incremental input must be a Pointer")
@@ -156,9 +173,9 @@ func (t *STableSpec) incrementInternal(diff interface{}, opcode string, target i
 	intResult, err := t.incrementInternalSql(diff, opcode, target)
 
 	if target != nil {
-		err = t.execUpdateSql(target, intResult)
+		err = t.execUpdateSql(ctx, target, intResult)
 	} else {
-		err = t.execUpdateSql(diff, intResult)
+		err = t.execUpdateSql(ctx, diff, intResult)
 	}
 	if err != nil {
 		return errors.Wrap(err, "query after update failed")
@@ -88,10 +88,20 @@ func (index *STableIndex) IsIdentical(cols ...string) bool {
 	return true
 }
 
+// Columns returns the names of columns of the index
+func (index *STableIndex) Columns() []string {
+	return index.columns
+}
+
+// QuotedColumns returns the names of columns of the index quoted by the backend of the table
 func (index *STableIndex) QuotedColumns() []string {
+	var db *SDatabase
+	if index.ts != nil {
+		db = index.ts.Database()
+	}
 	ret := make([]string, len(index.columns))
 	for i := 0; i < len(ret); i++ {
-		ret[i] = fmt.Sprintf("`%s`", index.columns[i])
+		ret[i] = quoteIdentifier(db, index.columns[i])
 	}
 	return ret
 }
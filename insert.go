@@ -15,6 +15,7 @@
 package sqlchemy
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"strings"
@@ -28,30 +29,46 @@ import (
 
 // Insert perform a insert operation, the value of the record is store in dt
 func (t *STableSpec) Insert(dt interface{}) error {
+	return t.InsertContext(context.Background(), dt)
+}
+
+// InsertContext is the context-aware version of Insert
+func (t *STableSpec) InsertContext(ctx context.Context, dt interface{}) error {
 	if !t.Database().backend.CanInsert() {
 		return errors.Wrap(errors.ErrNotSupported, "Insert")
 	}
-	return t.insert(dt, false, false)
+	return t.insert(ctx, dt, false, false)
 }
 
 // InsertOrUpdate perform a insert or update operation, the value of the record is string in dt
 // MySQL: INSERT INTO ... ON DUPLICATE KEY UPDATE ...
 // works only for the cases that all values of primary keys are determeted before insert
 func (t *STableSpec) InsertOrUpdate(dt interface{}) error {
+	return t.InsertOrUpdateContext(context.Background(), dt)
+}
+
+// InsertOrUpdateContext is the context-aware version of InsertOrUpdate
+func (t *STableSpec) InsertOrUpdateContext(ctx context.Context, dt interface{}) error {
 	if !t.Database().backend.CanInsertOrUpdate() {
 		if !t.Database().backend.CanUpdate() {
-			return t.insert(dt, false, false)
+			return t.insert(ctx, dt, false, false)
 		} else {
 			return errors.Wrap(errors.ErrNotSupported, "InsertOrUpdate")
 		}
 	}
-	return t.insert(dt, true, false)
+	// the statement does not tell an insert from an update, so neither the insert nor the update hooks would be right
+	if err := t.checkNoHooks("InsertOrUpdate", dt); err != nil {
+		return err
+	}
+	return t.insert(ctx, dt, true, false)
 }
 
 type InsertSqlResult struct {
 	Sql       string
 	Values    []interface{}
 	Primaries map[string]interface{}
+	// Returning is the name of columns in the RETURNING clause, empty if the backend does not support RETURNING
+	Returning []string
 }
 
 func (t *STableSpec) InsertSqlPrep(data interface{}, update bool) (*InsertSqlResult, error) {
@@ -90,13 +107,13 @@ func (t *STableSpec) InsertSqlPrep(data interface{}, update bool) (*InsertSqlRes
 		}
 
 		if c.IsPrimary() {
-			primaryKeys = append(primaryKeys, fmt.Sprintf("`%s`", k))
+			primaryKeys = append(primaryKeys, t.quoteIdentifier(k))
 		}
 
 		// created_at or updated_at but must not be a primary key
 		if c.IsCreatedAt() || c.IsUpdatedAt() {
 			createdAtFields = append(createdAtFields, k)
-			names = append(names, fmt.Sprintf("`%s`", k))
+			names = append(names, t.quoteIdentifier(k))
 			if c.IsZero(ov) {
 				if t.Database().backend.SupportMixedInsertVariables() {
 					format = append(format, t.Database().backend.CurrentUTCTimeStampString())
@@ -111,10 +128,10 @@ func (t *STableSpec) InsertSqlPrep(data interface{}, update bool) (*InsertSqlRes
 
 			if update && c.IsUpdatedAt() && !c.IsPrimary() {
 				if c.IsZero(ov) {
-					updates = append(updates, fmt.Sprintf("`%s` = %s", k, t.Database().backend.CurrentUTCTimeStampString()))
+					updates = append(updates, fmt.Sprintf("%s = %s", t.quoteIdentifier(k), t.Database().backend.CurrentUTCTimeStampString()))
 					// updateValues = append(updateValues, now)
 				} else {
-					updates = append(updates, fmt.Sprintf("`%s` = ?", k))
+					updates = append(updates, fmt.Sprintf("%s = ?", t.quoteIdentifier(k)))
 					updateValues = append(updateValues, ov)
 				}
 			}
@@ -132,7 +149,7 @@ func (t *STableSpec) InsertSqlPrep(data interface{}, update bool) (*InsertSqlRes
 
 		// auto_version and must not be a primary key
 		if update && c.IsAutoVersion() {
-			updates = append(updates, fmt.Sprintf("`%s` = `%s` + 1", k, k))
+			updates = append(updates, fmt.Sprintf("%s = %s + 1", t.quoteIdentifier(k), t.quoteIdentifier(k)))
 			continue
 		}
 
@@ -140,11 +157,11 @@ func (t *STableSpec) InsertSqlPrep(data interface{}, update bool) (*InsertSqlRes
 		if c.IsSupportDefault() && (len(c.Default()) > 0 || c.IsString()) && !gotypes.IsNil(ov) && c.IsZero(ov) && !c.AllowZero() { // empty text value
 			val := c.ConvertFromString(c.Default())
 			values = append(values, val)
-			names = append(names, fmt.Sprintf("`%s`", k))
+			names = append(names, t.quoteIdentifier(k))
 			format = append(format, "?")
 
 			if update && !c.IsPrimary() {
-				updates = append(updates, fmt.Sprintf("`%s` = ?", k))
+				updates = append(updates, fmt.Sprintf("%s = ?", t.quoteIdentifier(k)))
 				updateValues = append(updateValues, val)
 			}
 
@@ -158,11 +175,11 @@ func (t *STableSpec) InsertSqlPrep(data interface{}, update bool) (*InsertSqlRes
 		if !gotypes.IsNil(ov) && (!c.IsZero(ov) || (!c.IsPointer() && !c.IsText())) && !isAutoInc {
 			v := c.ConvertFromValue(ov)
 			values = append(values, v)
-			names = append(names, fmt.Sprintf("`%s`", k))
+			names = append(names, t.quoteIdentifier(k))
 			format = append(format, "?")
 
 			if update && !c.IsPrimary() {
-				updates = append(updates, fmt.Sprintf("`%s` = ?", k))
+				updates = append(updates, fmt.Sprintf("%s = ?", t.quoteIdentifier(k)))
 				updateValues = append(updateValues, v)
 			}
 
@@ -181,7 +198,7 @@ func (t *STableSpec) InsertSqlPrep(data interface{}, update bool) (*InsertSqlRes
 				autoIncField = k
 			} else if c.IsText() {
 				values = append(values, "")
-				names = append(names, fmt.Sprintf("`%s`", k))
+				names = append(names, t.quoteIdentifier(k))
 				format = append(format, "?")
 				primaries[k] = ""
 			} else {
@@ -192,7 +209,7 @@ func (t *STableSpec) InsertSqlPrep(data interface{}, update bool) (*InsertSqlRes
 
 		// empty without default
 		if update {
-			updates = append(updates, fmt.Sprintf("`%s` = NULL", k))
+			updates = append(updates, fmt.Sprintf("%s = NULL", t.quoteIdentifier(k)))
 			continue
 		}
 	}
@@ -204,7 +221,7 @@ func (t *STableSpec) InsertSqlPrep(data interface{}, update bool) (*InsertSqlRes
 			Columns string
 			Values  string
 		}{
-			Table:   t.name,
+			Table:   t.quoteIdentifier(t.name),
 			Columns: strings.Join(names, ", "),
 			Values:  strings.Join(format, ", "),
 		})
@@ -216,7 +233,7 @@ func (t *STableSpec) InsertSqlPrep(data interface{}, update bool) (*InsertSqlRes
 			PrimaryKeys string
 			SetValues   string
 		}{
-			Table:       t.name,
+			Table:       t.quoteIdentifier(t.name),
 			Columns:     strings.Join(names, ", "),
 			Values:      strings.Join(format, ", "),
 			PrimaryKeys: strings.Join(primaryKeys, ", "),
@@ -225,10 +242,21 @@ func (t *STableSpec) InsertSqlPrep(data interface{}, update bool) (*InsertSqlRes
 		values = append(values, updateValues...)
 	}
 
+	var returning []string
+	if t.Database().backend.CanSupportReturning() {
+		returnCols := make([]string, 0)
+		for _, c := range t.Columns() {
+			returning = append(returning, c.Name())
+			returnCols = append(returnCols, t.quoteIdentifier(c.Name()))
+		}
+		insertSql += " RETURNING " + strings.Join(returnCols, ", ")
+	}
+
 	return &InsertSqlResult{
 		Sql:       insertSql,
 		Values:    values,
 		Primaries: primaries,
+		Returning: returning,
 	}, nil
 }
 
@@ -252,7 +280,21 @@ func beforeInsert(val reflect.Value) {
 	}
 }
 
-func (t *STableSpec) insert(data interface{}, update bool, debug bool) error {
+func (t *STableSpec) insert(ctx context.Context, data interface{}, update bool, debug bool) error {
+	return t.runWithHooks(ctx, data, func(ctx context.Context) error {
+		err := t.callBeforeInsertHooks(ctx, data)
+		if err != nil {
+			return err
+		}
+		err = t.execInsert(ctx, data, update, debug)
+		if err != nil {
+			return err
+		}
+		return t.callAfterInsertHooks(ctx, data)
+	})
+}
+
+func (t *STableSpec) execInsert(ctx context.Context, data interface{}, update bool, debug bool) error {
 	insertResult, err := t.InsertSqlPrep(data, update)
 	if err != nil {
 		return errors.Wrap(err, "insertSqlPrep")
@@ -262,7 +304,23 @@ func (t *STableSpec) insert(data interface{}, update bool, debug bool) error {
 		log.Debugf("%s values: %#v", insertResult.Sql, insertResult.Values)
 	}
 
-	results, err := t.Database().TxExec(insertResult.Sql, insertResult.Values...)
+	if len(insertResult.Returning) > 0 {
+		// the inserted row is read back by the RETURNING clause
+		row := t.Database().queryRowContext(ctx, insertResult.Sql, insertResult.Values...)
+		mapResult, err := rowScan2StringMap(insertResult.Returning, row)
+		if err != nil {
+			return errors.Wrap(err, "insert returning")
+		}
+		dataPtrValue := reflect.ValueOf(data)
+		err = mapString2Struct(mapResult, dataPtrValue.Elem())
+		if err != nil {
+			return errors.Wrap(err, "mapString2Struct")
+		}
+		callAfterQuery(dataPtrValue)
+		return nil
+	}
+
+	results, err := t.Database().TxExecContext(ctx, insertResult.Sql, insertResult.Values...)
 	if err != nil {
 		return errors.Wrap(err, "TxExec")
 	}
@@ -296,7 +354,7 @@ func (t *STableSpec) insert(data interface{}, update bool, debug bool) error {
 
 	// query the value, so default value can be feedback into the object
 	// fields = reflectutils.FetchStructFieldNameValueInterfaces(dataValue)
-	q := t.Query()
+	q := t.Query().Unscoped().WithContext(ctx)
 	for _, c := range t.Columns() {
 		if c.IsPrimary() {
 			if c.IsAutoIncrement() {
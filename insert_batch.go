@@ -15,123 +15,340 @@
 package sqlchemy
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/nyl1001/pkg/errors"
 	"github.com/nyl1001/pkg/gotypes"
 	"github.com/nyl1001/pkg/util/reflectutils"
 	"github.com/nyl1001/pkg/util/timeutils"
+	"github.com/nyl1001/pkg/utils"
 	"yunion.io/x/log"
 )
 
 const (
-	sqlLineLimit = 100
+	// sqlBlockLimit is the max number of rows inserted by a prepared statement in a transaction
+	sqlBlockLimit = 10000
 )
 
+// SInsertBatchSQLResult is an INSERT statement of a batch insert
+type SInsertBatchSQLResult struct {
+	Sql    string
+	Values []interface{}
+
+	// multiRow indicates Sql inserts all rows by a multi-row VALUES clause,
+	// otherwise rows are inserted one by one with rowSql
+	multiRow bool
+	rowSql   string
+	rows     [][]interface{}
+}
+
 func (t *STableSpec) InsertBatch(dataList []interface{}) error {
-	var sql string
-	var fieldCount int
-	{
-		buffer := new(bytes.Buffer)
-
-		buffer.WriteString("INSERT INTO `")
-		buffer.WriteString(t.Name())
-		buffer.WriteString("` (")
-		headers := make([]string, 0)
-		format := make([]string, 0)
-
-		for _, col := range t.Columns() {
-			if col.IsAutoIncrement() {
-				continue
-			}
-			name := col.Name()
-			headers = append(headers, fmt.Sprintf("`%s`", name))
-			if col.IsCreatedAt() || col.IsUpdatedAt() {
-				if t.Database().backend.SupportMixedInsertVariables() {
-					format = append(format, t.Database().backend.CurrentUTCTimeStampString())
-				} else {
-					format = append(format, "?")
-					fieldCount++
-				}
-				continue
-			}
-			format = append(format, "?")
-			fieldCount++
+	return t.InsertBatchContext(context.Background(), dataList)
+}
+
+// InsertBatchContext is the context-aware version of InsertBatch
+func (t *STableSpec) InsertBatchContext(ctx context.Context, dataList []interface{}) error {
+	if err := t.checkNoHooks("InsertBatch", dataList...); err != nil {
+		return err
+	}
+	for _, result := range t.InsertBatchSqlPrep(dataList) {
+		err := t.execInsertBatch(ctx, result)
+		if err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		buffer.WriteString(strings.Join(headers, ","))
-		buffer.WriteString(") VALUES ")
-		buffer.WriteString("(")
-		buffer.WriteString(strings.Join(format, ","))
-		buffer.WriteString(")")
+func (t *STableSpec) execInsertBatch(ctx context.Context, result *SInsertBatchSQLResult) error {
+	if result.multiRow {
+		_, err := t.Database().TxExecContext(ctx, result.Sql, result.Values...)
+		if err == nil {
+			return nil
+		}
+		// a failed statement aborts the whole transaction of PostgreSQL, so the rows are not retried in a transaction
+		if t.Database().txFromContext(ctx) != nil {
+			return errors.Wrap(err, "TxExec")
+		}
+		// a multi-row statement fails as a whole, retry row by row to find out the failed rows
+		log.Debugf("batch insert %d rows fail %s, retry row by row", len(result.rows), err)
+	}
+	results, err := t.Database().TxBatchExecContext(ctx, result.rowSql, result.rows)
+	if err != nil {
+		return errors.Wrap(err, "TxBatchExec")
+	}
+	errs := make([]error, 0)
+	for _, result := range results {
+		if result.Error != nil {
+			errs = append(errs, result.Error)
+		}
+	}
+	if len(errs) != 0 {
+		return errors.NewAggregate(errs)
+	}
+	return nil
+}
 
-		sql = buffer.String()
+// InsertBatchSqlPrep prepares the INSERT statements of a batch insert, rows are grouped into multi-row
+// statements within the placeholder and packet size limits of the backend
+func (t *STableSpec) InsertBatchSqlPrep(dataList []interface{}) []*SInsertBatchSQLResult {
+	cols := make([]IColumnSpec, 0)
+	for _, col := range t.Columns() {
+		if col.IsAutoIncrement() {
+			continue
+		}
+		cols = append(cols, col)
+	}
+	format, fieldCount := t.insertBatchFormat(cols)
+	prefix := "INSERT INTO " + t.quoteIdentifier(t.Name()) + " (" + strings.Join(t.quoteColumnNames(cols), ",") + ") VALUES "
+	return t.batchSqlPrep(dataList, cols, fieldCount, func(rows int) string {
+		rowFormats := make([]string, rows)
+		for i := range rowFormats {
+			rowFormats[i] = "(" + strings.Join(format, ",") + ")"
+		}
+		return prefix + strings.Join(rowFormats, ",")
+	})
+}
 
-		if DEBUG_SQLCHEMY {
-			log.Debugf("batchInsert SQL: %s", buffer.String())
+// InsertOrUpdateBatch inserts the rows, or updates the rows with duplicate primary keys, by multi-row statements.
+// updateFields restricts the columns overwritten on conflict, all columns are overwritten if it is empty,
+// the updated_at columns are refreshed and the auto_version columns are incremented anyway.
+// The primary keys and created_at columns cannot be updated, and the duplicate rows are skipped if nothing is to update
+func (t *STableSpec) InsertOrUpdateBatch(dataList []interface{}, updateFields ...string) error {
+	return t.InsertOrUpdateBatchContext(context.Background(), dataList, updateFields...)
+}
+
+// InsertOrUpdateBatchContext is the context-aware version of InsertOrUpdateBatch
+func (t *STableSpec) InsertOrUpdateBatchContext(ctx context.Context, dataList []interface{}, updateFields ...string) error {
+	if err := t.checkNoHooks("InsertOrUpdateBatch", dataList...); err != nil {
+		return err
+	}
+	if !t.Database().backend.CanInsertOrUpdate() {
+		if !t.Database().backend.CanUpdate() {
+			return t.InsertBatchContext(ctx, dataList)
+		} else {
+			return errors.Wrap(errors.ErrNotSupported, "InsertOrUpdateBatch")
 		}
 	}
+	results, err := t.InsertOrUpdateBatchSqlPrep(dataList, updateFields...)
+	if err != nil {
+		return errors.Wrap(err, "InsertOrUpdateBatchSqlPrep")
+	}
+	for _, result := range results {
+		err := t.execInsertBatch(ctx, result)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	batchParams := make([][]interface{}, 0)
+// InsertOrUpdateBatchSqlPrep prepares the multi-row statements of InsertOrUpdateBatch with InsertOrUpdateSQLTemplate of the backend
+func (t *STableSpec) InsertOrUpdateBatchSqlPrep(dataList []interface{}, updateFields ...string) ([]*SInsertBatchSQLResult, error) {
+	backend := t.Database().backend
 
-	now := timeutils.UtcNow()
-	errs := make([]error, 0)
-	for i := range dataList {
-		v := dataList[i]
+	for _, f := range updateFields {
+		col := t.ColumnSpec(f)
+		if col == nil {
+			return nil, errors.Wrapf(errors.ErrNotFound, "column %s", f)
+		}
+		if col.IsPrimary() || col.IsCreatedAt() {
+			return nil, errors.Wrapf(errors.ErrInvalidFormat, "column %s is not updated on conflict", f)
+		}
+	}
+	cols := make([]IColumnSpec, 0)
+	primaryKeys := make([]string, 0)
+	updates := make([]string, 0)
+	versions := make([]string, 0)
+	for _, col := range t.Columns() {
+		k := col.Name()
+		if col.IsPrimary() {
+			primaryKeys = append(primaryKeys, t.quoteIdentifier(k))
+		}
+		// auto_version is inserted with its default and incremented on update
+		if col.IsAutoVersion() && !col.IsPrimary() {
+			versions = append(versions, fmt.Sprintf("%s = %s + 1", t.quoteIdentifier(k), t.quoteIdentifier(k)))
+			continue
+		}
+		cols = append(cols, col)
+		if col.IsPrimary() || col.IsCreatedAt() {
+			continue
+		}
+		if col.IsUpdatedAt() {
+			updates = append(updates, fmt.Sprintf("%s = %s", t.quoteIdentifier(k), backend.CurrentUTCTimeStampString()))
+			continue
+		}
+		if len(updateFields) > 0 && !utils.IsInStringArray(k, updateFields) {
+			continue
+		}
+		updates = append(updates, fmt.Sprintf("%s = %s", t.quoteIdentifier(k), backend.InsertOrUpdateValueRef(k)))
+	}
+	updates = append(updates, versions...)
 
-		var params []interface{}
+	// nothing to update on conflict, e.g. a table of primary keys only, the duplicate rows are skipped
+	sqlTemplate := backend.InsertOrUpdateSQLTemplate()
+	if len(updates) == 0 {
+		sqlTemplate = backend.InsertOrIgnoreSQLTemplate()
+		if len(sqlTemplate) == 0 {
+			return nil, errors.Wrapf(errors.ErrNotSupported, "insert or ignore on backend %s", backend.Name())
+		}
+	}
 
-		modelValue := reflect.Indirect(reflect.ValueOf(v))
-		beforeInsert(modelValue)
-		dataFields := reflectutils.FetchStructFieldValueSet(modelValue)
+	format, fieldCount := t.insertBatchFormat(cols)
+	columns := strings.Join(t.quoteColumnNames(cols), ", ")
+	return t.batchSqlPrep(dataList, cols, fieldCount, func(rows int) string {
+		rowFormats := make([]string, rows)
+		for i := range rowFormats {
+			rowFormats[i] = strings.Join(format, ", ")
+		}
+		return templateEval(sqlTemplate, struct {
+			Table       string
+			Columns     string
+			Values      string
+			PrimaryKeys string
+			SetValues   string
+		}{
+			Table:       t.quoteIdentifier(t.name),
+			Columns:     columns,
+			Values:      strings.Join(rowFormats, "), ("),
+			PrimaryKeys: strings.Join(primaryKeys, ", "),
+			SetValues:   strings.Join(updates, ", "),
+		})
+	}), nil
+}
 
-		for _, col := range t.Columns() {
-			if col.IsAutoIncrement() {
-				continue
-			}
-			if col.IsCreatedAt() || col.IsUpdatedAt() {
-				if !t.Database().backend.SupportMixedInsertVariables() {
-					params = append(params, now)
-				}
-				continue
-			}
-			ov, find := dataFields.GetInterface(col.Name())
-			if !find || gotypes.IsNil(ov) || col.IsZero(ov) {
-				// empty column
-				if col.IsSupportDefault() && (len(col.Default()) > 0 || col.IsString()) {
-					params = append(params, col.ConvertFromString(col.Default()))
-				} else {
-					params = append(params, nil)
-				}
-			} else {
-				params = append(params, col.ConvertFromValue(ov))
-			}
+func (t *STableSpec) quoteColumnNames(cols []IColumnSpec) []string {
+	names := make([]string, len(cols))
+	for i := range cols {
+		names[i] = t.quoteIdentifier(cols[i].Name())
+	}
+	return names
+}
+
+// insertBatchFormat returns the value format of the columns of a row and the number of placeholders
+func (t *STableSpec) insertBatchFormat(cols []IColumnSpec) ([]string, int) {
+	backend := t.Database().backend
+	format := make([]string, 0)
+	fieldCount := 0
+	for _, col := range cols {
+		if (col.IsCreatedAt() || col.IsUpdatedAt()) && backend.SupportMixedInsertVariables() {
+			format = append(format, backend.CurrentUTCTimeStampString())
+			continue
 		}
+		format = append(format, "?")
+		fieldCount++
+	}
+	return format, fieldCount
+}
+
+// batchSqlPrep groups the rows into statements built by sqlFunc, which returns the statement of the given number of rows
+func (t *STableSpec) batchSqlPrep(dataList []interface{}, cols []IColumnSpec, fieldCount int, sqlFunc func(rows int) string) []*SInsertBatchSQLResult {
+	backend := t.Database().backend
 
+	rowSql := sqlFunc(1)
+	if DEBUG_SQLCHEMY {
+		log.Debugf("batchInsert SQL: %s", rowSql)
+	}
+
+	maxRows := sqlBlockLimit
+	multiRow := backend.MaxInsertPlaceholders() > 0 && fieldCount > 0
+	if multiRow {
+		maxRows = backend.MaxInsertPlaceholders() / fieldCount
+		if maxRows < 1 {
+			maxRows = 1
+		}
+	}
+	maxSize := t.Database().MaxInsertPacketSize()
+	rowSqlSize := len(sqlFunc(2)) - len(rowSql)
+	baseSize := len(rowSql) - rowSqlSize
+
+	ret := make([]*SInsertBatchSQLResult, 0)
+	var current *SInsertBatchSQLResult
+	size := 0
+	flush := func() {
+		if current == nil {
+			return
+		}
+		if multiRow {
+			current.Sql = sqlFunc(len(current.rows))
+		} else {
+			current.Sql = rowSql
+		}
+		ret = append(ret, current)
+		current = nil
+	}
+
+	now := timeutils.UtcNow()
+	for i := range dataList {
+		params := t.insertBatchRowParams(dataList[i], cols, now)
 		if len(params) != fieldCount {
 			log.Errorf("expect %d got %d(%#v)", fieldCount, len(params), params)
 		}
-
-		batchParams = append(batchParams, params)
-		if len(batchParams) >= sqlLineLimit || (i+1) == len(dataList) {
-			results, err := t.Database().TxBatchExec(sql, batchParams)
-			if err != nil {
-				return errors.Wrap(err, "TxBatchExec")
+		rowSize := rowSqlSize
+		for _, p := range params {
+			rowSize += insertVarSize(p)
+		}
+		if current != nil && (len(current.rows) >= maxRows || (multiRow && maxSize > 0 && size+rowSize > maxSize)) {
+			flush()
+		}
+		if current == nil {
+			current = &SInsertBatchSQLResult{
+				multiRow: multiRow,
+				rowSql:   rowSql,
 			}
-			for _, result := range results {
-				if result.Error != nil {
-					errs = append(errs, result.Error)
-				}
+			size = baseSize
+		}
+		current.rows = append(current.rows, params)
+		if multiRow {
+			current.Values = append(current.Values, params...)
+		}
+		size += rowSize
+	}
+	flush()
+
+	return ret
+}
+
+func (t *STableSpec) insertBatchRowParams(v interface{}, cols []IColumnSpec, now time.Time) []interface{} {
+	var params []interface{}
+
+	modelValue := reflect.Indirect(reflect.ValueOf(v))
+	beforeInsert(modelValue)
+	dataFields := reflectutils.FetchStructFieldValueSet(modelValue)
+
+	for _, col := range cols {
+		if col.IsCreatedAt() || col.IsUpdatedAt() {
+			if !t.Database().backend.SupportMixedInsertVariables() {
+				params = append(params, now)
 			}
-			if len(errs) != 0 {
-				return errors.NewAggregate(errs)
+			continue
+		}
+		ov, find := dataFields.GetInterface(col.Name())
+		if !find || gotypes.IsNil(ov) || col.IsZero(ov) {
+			// empty column
+			if col.IsSupportDefault() && (len(col.Default()) > 0 || col.IsString()) {
+				params = append(params, col.ConvertFromString(col.Default()))
+			} else {
+				params = append(params, nil)
 			}
-			batchParams = make([][]interface{}, 0)
+		} else {
+			params = append(params, col.ConvertFromValue(ov))
 		}
 	}
+	return params
+}
 
-	return nil
+// insertVarSize estimates the size in bytes of a variable sent with a statement
+func insertVarSize(v interface{}) int {
+	switch val := v.(type) {
+	case string:
+		return len(val) + 2
+	case []byte:
+		return len(val) + 2
+	}
+	return 16
 }
@@ -0,0 +1,168 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlchemy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nyl1001/pkg/errors"
+	"github.com/nyl1001/pkg/utils"
+	"yunion.io/x/log"
+)
+
+// InsertFromQuery inserts the rows selected by q into the table, i.e. INSERT INTO t (columns) SELECT ...,
+// columns are the names of the columns of the table filled by the fields of q in order, the names of
+// the fields of q are used if columns is empty. As Insert does, the created_at and updated_at columns not
+// in columns are filled with the current time, and the auto_version columns with 1. It returns the number
+// of inserted rows, which is always 0 if the backend does not support RowsAffected.
+func (t *STableSpec) InsertFromQuery(q *SQuery, columns ...string) (int64, error) {
+	return t.InsertFromQueryContext(context.Background(), q, columns...)
+}
+
+// InsertFromQueryContext is the context-aware version of InsertFromQuery
+func (t *STableSpec) InsertFromQueryContext(ctx context.Context, q *SQuery, columns ...string) (int64, error) {
+	if !t.Database().backend.CanInsert() {
+		return 0, errors.Wrap(errors.ErrNotSupported, "InsertFromQuery")
+	}
+	return t.insertFromQuery(ctx, q, false, columns)
+}
+
+// InsertOrUpdateFromQuery inserts the rows selected by q into the table, or updates the rows with duplicate
+// primary keys with the selected values. The updated_at columns are refreshed and the auto_version columns are
+// incremented on update. If there is nothing to update, e.g. all the columns are primary keys, the rows with
+// duplicate primary keys are skipped.
+func (t *STableSpec) InsertOrUpdateFromQuery(q *SQuery, columns ...string) (int64, error) {
+	return t.InsertOrUpdateFromQueryContext(context.Background(), q, columns...)
+}
+
+// InsertOrUpdateFromQueryContext is the context-aware version of InsertOrUpdateFromQuery
+func (t *STableSpec) InsertOrUpdateFromQueryContext(ctx context.Context, q *SQuery, columns ...string) (int64, error) {
+	if !t.Database().backend.CanInsertOrUpdate() || len(t.Database().backend.InsertOrUpdateFromQuerySQLTemplate()) == 0 {
+		if !t.Database().backend.CanUpdate() {
+			return t.insertFromQuery(ctx, q, false, columns)
+		} else {
+			return 0, errors.Wrap(errors.ErrNotSupported, "InsertOrUpdateFromQuery")
+		}
+	}
+	return t.insertFromQuery(ctx, q, true, columns)
+}
+
+func (t *STableSpec) insertFromQuery(ctx context.Context, q *SQuery, update bool, columns []string) (int64, error) {
+	if err := t.checkNoHooks("InsertFromQuery"); err != nil {
+		return 0, err
+	}
+	sql, vars, err := t.InsertFromQuerySql(q, update, columns...)
+	if err != nil {
+		return 0, errors.Wrap(err, "InsertFromQuerySql")
+	}
+	return t.execWhereSql(ctx, sql, vars)
+}
+
+// InsertFromQuerySql returns the SQL and variables of InsertFromQuery, or InsertOrUpdateFromQuery if update is true
+func (t *STableSpec) InsertFromQuerySql(q *SQuery, update bool, columns ...string) (string, []interface{}, error) {
+	fields := q.QueryFields()
+	if len(columns) == 0 {
+		for _, f := range fields {
+			columns = append(columns, f.Name())
+		}
+	}
+	if len(columns) != len(fields) {
+		return "", nil, errors.Wrapf(errors.ErrInvalidFormat, "%d columns but %d query fields", len(columns), len(fields))
+	}
+	names := make([]string, 0, len(columns))
+	for _, c := range columns {
+		if t.ColumnSpec(c) == nil {
+			return "", nil, errors.Wrapf(errors.ErrNotFound, "column %s", c)
+		}
+		names = append(names, t.quoteIdentifier(c))
+	}
+
+	// the created_at, updated_at and auto_version columns not filled by the query are filled as Insert does
+	backend := t.Database().backend
+	extraFields := make([]IQueryField, 0)
+	for _, col := range t.Columns() {
+		k := col.Name()
+		if col.IsPrimary() || utils.IsInStringArray(k, columns) {
+			continue
+		}
+		switch {
+		case col.IsCreatedAt() || col.IsUpdatedAt():
+			extraFields = append(extraFields, NewFunctionField(k, backend.CurrentUTCTimeStampString()))
+		case col.IsAutoVersion():
+			extraFields = append(extraFields, NewConstField(1).Label(k))
+		default:
+			continue
+		}
+		names = append(names, t.quoteIdentifier(k))
+	}
+	if len(extraFields) > 0 {
+		q = q.Copy()
+		q.fields = append(append([]IQueryField{}, fields...), extraFields...)
+	}
+
+	var insertSql string
+	if !update {
+		insertSql = fmt.Sprintf("INSERT INTO %s (%s) %s", t.quoteIdentifier(t.name), strings.Join(names, ", "), q.String())
+	} else {
+		primaryKeys := make([]string, 0)
+		updates := make([]string, 0)
+		versions := make([]string, 0)
+		for _, col := range t.Columns() {
+			k := col.Name()
+			switch {
+			case col.IsPrimary():
+				primaryKeys = append(primaryKeys, t.quoteIdentifier(k))
+			case col.IsAutoVersion():
+				versions = append(versions, fmt.Sprintf("%s = %s + 1", t.quoteIdentifier(k), t.quoteIdentifier(k)))
+			case col.IsUpdatedAt():
+				updates = append(updates, fmt.Sprintf("%s = %s", t.quoteIdentifier(k), backend.CurrentUTCTimeStampString()))
+			case col.IsCreatedAt():
+			case utils.IsInStringArray(k, columns):
+				updates = append(updates, fmt.Sprintf("%s = %s", t.quoteIdentifier(k), backend.InsertOrUpdateValueRef(k)))
+			}
+		}
+		updates = append(updates, versions...)
+		// nothing to update on conflict, e.g. a table of primary keys only, the duplicate rows are skipped
+		sqlTemplate := backend.InsertOrUpdateFromQuerySQLTemplate()
+		if len(updates) == 0 {
+			sqlTemplate = backend.InsertOrIgnoreFromQuerySQLTemplate()
+			if len(sqlTemplate) == 0 {
+				return "", nil, errors.Wrapf(errors.ErrNotSupported, "insert or ignore on backend %s", backend.Name())
+			}
+		}
+		insertSql = templateEval(sqlTemplate, struct {
+			Table       string
+			Columns     string
+			Query       string
+			PrimaryKeys string
+			SetValues   string
+		}{
+			Table:       t.quoteIdentifier(t.name),
+			Columns:     strings.Join(names, ", "),
+			Query:       q.String(),
+			PrimaryKeys: strings.Join(primaryKeys, ", "),
+			SetValues:   strings.Join(updates, ", "),
+		})
+	}
+	vars := q.Variables()
+
+	if DEBUG_SQLCHEMY {
+		log.Infof("InsertFromQuery: %s %s", insertSql, vars)
+	}
+
+	return insertSql, vars, nil
+}
@@ -105,3 +105,177 @@ func TestInsertUpdateSQL(t *testing.T) {
 		t.Errorf("VARs want %d got %d", wantVars, len(results.Values))
 	}
 }
+
+func TestInsertBatchSQL(t *testing.T) {
+	SetupMockDatabaseBackend()
+
+	table := NewTableSpecFromStruct(TableStruct{}, "testtable")
+	results := table.InsertBatchSqlPrep([]interface{}{
+		&TableStruct{Id: 1, Name: "John"},
+		&TableStruct{Id: 2, Name: "Jane"},
+	})
+	if len(results) != 1 {
+		t.Fatalf("want 1 statement got %d", len(results))
+	}
+	want := "INSERT INTO `testtable` (`id`,`user_id`,`name`,`age`,`is_male`,`created_at`,`updated_at`,`version`) VALUES (?,?,?,?,?,UTC_NOW(),UTC_NOW(),?),(?,?,?,?,?,UTC_NOW(),UTC_NOW(),?)"
+	if results[0].Sql != want {
+		t.Errorf("SQL: want %s got %s", want, results[0].Sql)
+	}
+	if len(results[0].Values) != 12 {
+		t.Errorf("VARs want 12 got %d", len(results[0].Values))
+	}
+
+	// 6 placeholders per row, at most 999 placeholders per statement
+	dataList := make([]interface{}, 400)
+	for i := range dataList {
+		dataList[i] = &TableStruct{Id: i + 1}
+	}
+	results = table.InsertBatchSqlPrep(dataList)
+	wantRows := []int{166, 166, 68}
+	if len(results) != len(wantRows) {
+		t.Fatalf("want %d statements got %d", len(wantRows), len(results))
+	}
+	for i := range results {
+		if len(results[i].Values) != wantRows[i]*6 {
+			t.Errorf("statement %d: VARs want %d got %d", i, wantRows[i]*6, len(results[i].Values))
+		}
+	}
+}
+
+func TestInsertOrUpdateBatchSQL(t *testing.T) {
+	SetupMockDatabaseBackend()
+
+	table := NewTableSpecFromStruct(TableStruct2{}, "testtable2")
+	dataList := []interface{}{
+		&TableStruct2{Id: 1, Name: "John"},
+		&TableStruct2{Id: 2, Name: "Jane"},
+	}
+	cases := []struct {
+		updateFields []string
+		want         string
+	}{
+		{
+			want: "INSERT INTO `testtable2` (`id`, `user_id`, `name`, `age`, `is_male`, `created_at`, `updated_at`) VALUES (?, ?, ?, ?, ?, UTC_NOW(), UTC_NOW()), (?, ?, ?, ?, ?, UTC_NOW(), UTC_NOW()) ON DUPLICATE KEY UPDATE `user_id` = VALUES(`user_id`), `name` = VALUES(`name`), `age` = VALUES(`age`), `is_male` = VALUES(`is_male`), `updated_at` = UTC_NOW(), `version` = `version` + 1",
+		},
+		{
+			updateFields: []string{"name"},
+			want:         "INSERT INTO `testtable2` (`id`, `user_id`, `name`, `age`, `is_male`, `created_at`, `updated_at`) VALUES (?, ?, ?, ?, ?, UTC_NOW(), UTC_NOW()), (?, ?, ?, ?, ?, UTC_NOW(), UTC_NOW()) ON DUPLICATE KEY UPDATE `name` = VALUES(`name`), `updated_at` = UTC_NOW(), `version` = `version` + 1",
+		},
+	}
+	for _, c := range cases {
+		results, err := table.InsertOrUpdateBatchSqlPrep(dataList, c.updateFields...)
+		if err != nil {
+			t.Fatalf("InsertOrUpdateBatchSqlPrep fail %s", err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("want 1 statement got %d", len(results))
+		}
+		if results[0].Sql != c.want {
+			t.Errorf("SQL: want %s got %s", c.want, results[0].Sql)
+		}
+		if len(results[0].Values) != 10 {
+			t.Errorf("VARs want 10 got %d", len(results[0].Values))
+		}
+	}
+
+	_, err := table.InsertOrUpdateBatchSqlPrep(dataList, "no_such_column")
+	if err == nil {
+		t.Errorf("unknown update field should fail")
+	}
+	_, err = table.InsertOrUpdateBatchSqlPrep(dataList, "id")
+	if err == nil {
+		t.Errorf("primary key update field should fail")
+	}
+
+	type keyOnly struct {
+		Id   int    `primary:"true"`
+		Name string `primary:"true" width:"16"`
+	}
+	keyTable := NewTableSpecFromStruct(keyOnly{}, "keyonly")
+	results, err := keyTable.InsertOrUpdateBatchSqlPrep([]interface{}{&keyOnly{Id: 1, Name: "a"}})
+	if err != nil {
+		t.Fatalf("InsertOrUpdateBatchSqlPrep fail %s", err)
+	}
+	want := "INSERT IGNORE INTO `keyonly` (`id`, `name`) VALUES (?, ?)"
+	if results[0].Sql != want {
+		t.Errorf("SQL: want %s got %s", want, results[0].Sql)
+	}
+}
+
+// isolateTableID numbers the table aliases of the test from t1, and restores the numbering when the test ends,
+// so that the aliases of the other tests are not shifted
+func isolateTableID(t *testing.T) {
+	tableIDLock.Lock()
+	saved := tableID
+	tableID = 0
+	tableIDLock.Unlock()
+	t.Cleanup(func() {
+		tableIDLock.Lock()
+		tableID = saved
+		tableIDLock.Unlock()
+	})
+}
+
+func TestInsertFromQuerySQL(t *testing.T) {
+	SetupMockDatabaseBackend()
+	isolateTableID(t)
+
+	type RawStruct struct {
+		Id     int    `json:"id" primary:"true"`
+		Name   string `width:"16"`
+		Age    int    `nullable:"true"`
+		Hidden bool   `nullable:"true"`
+	}
+	raw := NewTableSpecFromStruct(RawStruct{}, "rawtable")
+	table := NewTableSpecFromStruct(TableStruct2{}, "testtable2")
+
+	r := raw.Instance()
+	q := r.Query(r.Field("id"), r.Field("name"), SUM("age", r.Field("age"))).Equals("hidden", false).GroupBy(r.Field("id"), r.Field("name"))
+	cases := []struct {
+		update  bool
+		columns []string
+		want    string
+	}{
+		{
+			want: "INSERT INTO `testtable2` (`id`, `name`, `age`, `created_at`, `updated_at`, `version`) SELECT `t1`.`id`, `t1`.`name`, SUM(`t1`.`age`) AS `age`, UTC_NOW() AS `created_at`, UTC_NOW() AS `updated_at`, 1 AS `version` FROM `rawtable` AS `t1` WHERE `t1`.`hidden` =  ?  GROUP BY `t1`.`id`, `t1`.`name`",
+		},
+		{
+			update:  true,
+			columns: []string{"id", "user_id", "age"},
+			want:    "INSERT INTO `testtable2` (`id`, `user_id`, `age`, `created_at`, `updated_at`, `version`) SELECT `t1`.`id`, `t1`.`name`, SUM(`t1`.`age`) AS `age`, UTC_NOW() AS `created_at`, UTC_NOW() AS `updated_at`, 1 AS `version` FROM `rawtable` AS `t1` WHERE `t1`.`hidden` =  ?  GROUP BY `t1`.`id`, `t1`.`name` ON DUPLICATE KEY UPDATE `user_id` = VALUES(`user_id`), `age` = VALUES(`age`), `updated_at` = UTC_NOW(), `version` = `version` + 1",
+		},
+	}
+	for _, c := range cases {
+		sql, vars, err := table.InsertFromQuerySql(q, c.update, c.columns...)
+		if err != nil {
+			t.Fatalf("InsertFromQuerySql fail %s", err)
+		}
+		if sql != c.want {
+			t.Errorf("SQL: want %s got %s", c.want, sql)
+		}
+		if len(vars) != 1 {
+			t.Errorf("VARs want 1 got %d", len(vars))
+		}
+	}
+
+	_, _, err := table.InsertFromQuerySql(q, false, "id", "name")
+	if err == nil {
+		t.Errorf("mismatched columns should fail")
+	}
+
+	// nothing to update on conflict, the duplicate rows are skipped
+	type KeysStruct struct {
+		Id   int    `json:"id" primary:"true"`
+		Name string `width:"16" primary:"true"`
+	}
+	keys := NewTableSpecFromStruct(KeysStruct{}, "keystable")
+	r = raw.Instance()
+	sql, _, err := keys.InsertFromQuerySql(r.Query(r.Field("id"), r.Field("name")), true)
+	if err != nil {
+		t.Fatalf("InsertFromQuerySql fail %s", err)
+	}
+	want := "INSERT IGNORE INTO `keystable` (`id`, `name`) SELECT `t2`.`id`, `t2`.`name` FROM `rawtable` AS `t2`"
+	if sql != want {
+		t.Errorf("SQL: want %s got %s", want, sql)
+	}
+}
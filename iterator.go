@@ -0,0 +1,324 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlchemy
+
+import (
+	"database/sql"
+	"reflect"
+
+	"github.com/nyl1001/pkg/errors"
+	"github.com/nyl1001/pkg/gotypes"
+	"github.com/nyl1001/pkg/util/reflectutils"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// SQueryIterator is a cursor over the rows of a query that decodes one struct at a time
+//
+//	it, err := q.Iterator()
+//	defer it.Close()
+//	for it.Next() {
+//	    err = it.Scan(&row)
+//	}
+//	err = it.Err()
+type SQueryIterator struct {
+	query *SQuery
+	rows  *sql.Rows
+
+	// plan is the scan plan of the struct type scanned last time
+	plan *sScanPlan
+	raws []interface{}
+}
+
+// Iterator executes the query and returns a cursor over the rows, which should be closed after use.
+// The relations are not loaded row by row, so a query with Preload fails with ErrNotSupported, see IterateByKeyset
+func (tq *SQuery) Iterator() (*SQueryIterator, error) {
+	if len(tq.preloads) > 0 {
+		return nil, errors.Wrap(ErrNotSupported, "preload of a cursor-based iteration")
+	}
+	rows, err := tq.Rows()
+	if err != nil {
+		return nil, err
+	}
+	return &SQueryIterator{
+		query: tq,
+		rows:  rows,
+	}, nil
+}
+
+// Next prepares the next row for Scan, false if there are no more rows or an error occurs
+func (it *SQueryIterator) Next() bool {
+	return it.rows.Next()
+}
+
+// Scan decodes the current row into dest, a pointer to a struct, and calls its AfterQuery
+func (it *SQueryIterator) Scan(dest interface{}) error {
+	destPtrValue := reflect.ValueOf(dest)
+	if destPtrValue.Kind() != reflect.Ptr {
+		return errors.Wrap(ErrNeedsPointer, "input must be a pointer")
+	}
+	destValue := destPtrValue.Elem()
+	if it.plan == nil || it.plan.dataType != destValue.Type() {
+		it.plan = it.query.newScanPlan(destValue.Type())
+		if it.plan == nil {
+			return it.query.Row2Struct(it.rows, dest)
+		}
+		it.raws = make([]interface{}, len(it.plan.names))
+	}
+	err := it.plan.scanRow(it.rows, destValue, it.raws)
+	if err != nil {
+		return err
+	}
+	callAfterQuery(destPtrValue)
+	return nil
+}
+
+// Err returns the error occurred during the iteration
+func (it *SQueryIterator) Err() error {
+	return it.rows.Err()
+}
+
+// Close closes the cursor
+func (it *SQueryIterator) Close() error {
+	return it.rows.Close()
+}
+
+// iterateFunc validates fn is a func(obj *T) error and returns the struct type T
+func iterateFunc(fn interface{}) (reflect.Value, reflect.Type, error) {
+	fnValue := reflect.ValueOf(fn)
+	fnType := fnValue.Type()
+	if fnType.Kind() != reflect.Func || fnType.NumIn() != 1 || fnType.In(0).Kind() != reflect.Ptr || fnType.NumOut() != 1 || fnType.Out(0) != errorType {
+		return fnValue, nil, errors.Wrapf(errors.ErrInvalidFormat, "%s is not a func(obj *T) error", fnType)
+	}
+	return fnValue, fnType.In(0).Elem(), nil
+}
+
+func callIterateFunc(fnValue reflect.Value, obj reflect.Value) error {
+	ret := fnValue.Call([]reflect.Value{obj})[0]
+	if ret.IsNil() {
+		return nil
+	}
+	return ret.Interface().(error)
+}
+
+// Iterate decodes the rows one at a time and calls fn, a func(obj *T) error, with each of them.
+// The cursor is held during the iteration, which stops at the first error returned by fn.
+func (tq *SQuery) Iterate(fn interface{}) error {
+	fnValue, elemType, err := iterateFunc(fn)
+	if err != nil {
+		return err
+	}
+	return tq.iterate(elemType, func(obj reflect.Value) error {
+		return callIterateFunc(fnValue, obj)
+	})
+}
+
+func (tq *SQuery) iterate(elemType reflect.Type, fn func(obj reflect.Value) error) error {
+	it, err := tq.Iterator()
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	for it.Next() {
+		obj := reflect.New(elemType)
+		err = it.Scan(obj.Interface())
+		if err != nil {
+			return errors.Wrap(err, "Scan")
+		}
+		err = fn(obj)
+		if err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// IterateByKeyset walks the rows of a table query in the order of the primary keys, chunkSize rows at a time,
+// and calls fn, a func(obj *T) error, with each of them. Every chunk is fetched by a separate query that
+// continues after the primary keys of the last row, so no cursor is held while fn is called.
+// The query should neither be ordered nor limited, T should contain the primary key fields, and chunkSize should be positive.
+// The relations of Preload are loaded for each chunk.
+func (tq *SQuery) IterateByKeyset(chunkSize int, fn interface{}) error {
+	if chunkSize <= 0 {
+		return errors.Wrapf(errors.ErrInvalidFormat, "chunk size %d", chunkSize)
+	}
+	fnValue, elemType, err := iterateFunc(fn)
+	if err != nil {
+		return err
+	}
+	if len(tq.orderBy) > 0 || tq.limit > 0 || tq.offset > 0 {
+		return errors.Wrap(ErrNotSupported, "keyset iteration of an ordered or limited query")
+	}
+	orders, err := tq.primaryOrders()
+	if err != nil {
+		return err
+	}
+	var last []interface{}
+	for {
+		q := tq.Copy()
+		if last != nil {
+			q = q.Filter(keysetCondition(orders, last))
+		}
+		q.orderBy = append([]sQueryOrder{}, orders...)
+		q.preloads = nil
+		q = q.Limit(chunkSize)
+		objs := make([]reflect.Value, 0, chunkSize)
+		elems := make([]reflect.Value, 0, chunkSize)
+		err := q.iterate(elemType, func(obj reflect.Value) error {
+			objs = append(objs, obj)
+			elems = append(elems, obj.Elem())
+			return nil
+		})
+		if err != nil {
+			return errors.Wrap(err, "iterate chunk")
+		}
+		err = tq.loadPreloads(elems)
+		if err != nil {
+			return errors.Wrap(err, "preload chunk")
+		}
+		for _, obj := range objs {
+			err = callIterateFunc(fnValue, obj)
+			if err != nil {
+				return err
+			}
+		}
+		if len(objs) < chunkSize {
+			return nil
+		}
+		last, err = keysetValues(orders, objs[len(objs)-1].Elem())
+		if err != nil {
+			return errors.Wrapf(err, "keyset of %s", elemType)
+		}
+	}
+}
+
+// primaryOrders returns the ascending orders of the primary keys of the table queried by tq
+func (tq *SQuery) primaryOrders() ([]sQueryOrder, error) {
+	tbl, ok := tq.from.(*STable)
+	if !ok {
+		return nil, errors.Wrap(ErrNotSupported, "keyset iteration of a query not from a table")
+	}
+	primaries := tbl.spec.PrimaryColumns()
+	if len(primaries) == 0 {
+		return nil, ErrEmptyPrimaryKey
+	}
+	orders := make([]sQueryOrder, len(primaries))
+	for i, c := range primaries {
+		orders[i] = sQueryOrder{field: tbl.Field(c.Name()), order: SQL_ORDER_ASC}
+	}
+	return orders, nil
+}
+
+// keysetCondition returns the condition of the rows after the row of the values in the orders, e.g.
+//
+//	(a > ?) OR (a = ? AND b > ?)
+//
+// or (a, b) > (?, ?) if the orders are of the same direction, the fields are not nullable and the backend
+// supports row values. The nil values are NULLs, which are sorted as the backend does.
+func keysetCondition(orders []sQueryOrder, values []interface{}) ICondition {
+	backend := orders[0].field.database().backend
+	if len(orders) > 1 && backend.CanSupportRowValueIn() && isRowValueKeyset(orders, values) {
+		fields := make([]IQueryField, len(orders))
+		for i := range orders {
+			fields[i] = orders[i].field
+		}
+		op := SQL_OP_GT
+		if orders[0].order == SQL_ORDER_DESC {
+			op = SQL_OP_LT
+		}
+		return &sRowValueCompareCondition{fields: fields, values: values, op: op}
+	}
+	conds := make([]ICondition, 0, len(orders))
+	for i := range orders {
+		after := keysetAfter(backend, orders[i], values[i])
+		if after == nil {
+			continue
+		}
+		prev := make([]ICondition, 0, i+1)
+		for j := 0; j < i; j++ {
+			if gotypes.IsNil(values[j]) {
+				prev = append(prev, IsNull(orders[j].field))
+			} else {
+				prev = append(prev, Equals(orders[j].field, values[j]))
+			}
+		}
+		prev = append(prev, after)
+		if len(prev) == 1 {
+			conds = append(conds, prev[0])
+		} else {
+			conds = append(conds, AND(prev...))
+		}
+	}
+	switch len(conds) {
+	case 0:
+		return &SFalseCondition{db: orders[0].field.database()}
+	case 1:
+		return conds[0]
+	}
+	return OR(conds...)
+}
+
+// keysetAfter returns the condition of the values of the field after the value in the order, nil if none
+func keysetAfter(backend IBackend, order sQueryOrder, value interface{}) ICondition {
+	nullsFirst := backend.IsNullsFirst(order.order)
+	if gotypes.IsNil(value) {
+		if nullsFirst {
+			return IsNotNull(order.field)
+		}
+		return nil
+	}
+	var after ICondition
+	if order.order == SQL_ORDER_DESC {
+		after = LT(order.field, value)
+	} else {
+		after = GT(order.field, value)
+	}
+	if !nullsFirst && isNullableField(order.field) {
+		after = OR(after, IsNull(order.field))
+	}
+	return after
+}
+
+// isRowValueKeyset returns whether the keyset could be compared as row values
+func isRowValueKeyset(orders []sQueryOrder, values []interface{}) bool {
+	for i := range orders {
+		if orders[i].order != orders[0].order || gotypes.IsNil(values[i]) || isNullableField(orders[i].field) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNullableField returns whether the values of the field could be NULL, true unless it is a not null column
+func isNullableField(f IQueryField) bool {
+	if tf, ok := f.(*STableField); ok {
+		return tf.spec.IsNullable()
+	}
+	return true
+}
+
+// keysetValues returns the values of the order fields of a struct
+func keysetValues(orders []sQueryOrder, value reflect.Value) ([]interface{}, error) {
+	fields := reflectutils.FetchStructFieldValueSet(value)
+	values := make([]interface{}, len(orders))
+	for i := range orders {
+		v, ok := fields.GetInterface(orders[i].field.Name())
+		if !ok {
+			return nil, errors.Wrapf(errors.ErrNotFound, "field %s", orders[i].field.Name())
+		}
+		values[i] = v
+	}
+	return values, nil
+}
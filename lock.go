@@ -0,0 +1,76 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlchemy
+
+import (
+	"github.com/nyl1001/pkg/errors"
+)
+
+// QueryLockType indicates the strength of the row lock of a SELECT statement
+type QueryLockType string
+
+// QueryLockWaitType indicates how a locking SELECT statement behaves when the rows are locked by others
+type QueryLockWaitType string
+
+const (
+	// SQL_LOCK_FOR_UPDATE represents SELECT ... FOR UPDATE
+	SQL_LOCK_FOR_UPDATE QueryLockType = "UPDATE"
+	// SQL_LOCK_FOR_SHARE represents SELECT ... FOR SHARE
+	SQL_LOCK_FOR_SHARE QueryLockType = "SHARE"
+
+	// SQL_LOCK_NOWAIT fails immediately if the rows are locked
+	SQL_LOCK_NOWAIT QueryLockWaitType = "NOWAIT"
+	// SQL_LOCK_SKIP_LOCKED skips the rows that are locked
+	SQL_LOCK_SKIP_LOCKED QueryLockWaitType = "SKIP LOCKED"
+)
+
+// ForUpdate locks the selected rows for update, i.e. SELECT ... FOR UPDATE.
+// The lock is held until the end of the transaction, so the query should run within a transaction
+func (tq *SQuery) ForUpdate() *SQuery {
+	tq.lockType = SQL_LOCK_FOR_UPDATE
+	return tq
+}
+
+// ForShare locks the selected rows in share mode, i.e. SELECT ... FOR SHARE
+func (tq *SQuery) ForShare() *SQuery {
+	tq.lockType = SQL_LOCK_FOR_SHARE
+	return tq
+}
+
+// NoWait makes a locking query fail immediately instead of waiting for the rows locked by others,
+// it takes effect with ForUpdate or ForShare
+func (tq *SQuery) NoWait() *SQuery {
+	tq.lockWaitType = SQL_LOCK_NOWAIT
+	return tq
+}
+
+// SkipLocked makes a locking query skip the rows locked by others, which is useful to implement job queues,
+// it takes effect with ForUpdate or ForShare
+func (tq *SQuery) SkipLocked() *SQuery {
+	tq.lockWaitType = SQL_LOCK_SKIP_LOCKED
+	return tq
+}
+
+// lockingClause returns the row locking clause of the query, empty if the query does not lock rows
+func (tq *SQuery) lockingClause() (string, error) {
+	if len(tq.lockType) == 0 {
+		return "", nil
+	}
+	clause, err := tq.database().backend.LockingClause(tq.lockType, tq.lockWaitType)
+	if err != nil {
+		return "", errors.Wrapf(err, "backend %s", tq.database().backend.Name())
+	}
+	return clause, nil
+}
@@ -0,0 +1,187 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlchemy
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strconv"
+
+	"github.com/nyl1001/pkg/errors"
+	"github.com/nyl1001/pkg/gotypes"
+	"github.com/nyl1001/pkg/jsonutils"
+	"github.com/nyl1001/pkg/util/reflectutils"
+)
+
+// PageAfter restricts the query to the rows after the cursor in the orders of the query, which is
+// returned by PageCursor of the last row of the previous page, an empty cursor for the first page.
+// The orderFields are appended as ascending orders, e.g. the primary keys that make the orders unique,
+// the query is ordered by the primary keys of the table if there is no order.
+func (tq *SQuery) PageAfter(cursor string, orderFields ...interface{}) (*SQuery, error) {
+	if len(orderFields) > 0 {
+		tq = tq.Asc(orderFields...)
+	}
+	if len(tq.orderBy) == 0 {
+		orders, err := tq.primaryOrders()
+		if err != nil {
+			return nil, errors.Wrap(err, "primaryOrders")
+		}
+		tq.orderBy = orders
+	}
+	if len(cursor) == 0 {
+		return tq, nil
+	}
+	values, err := decodePageCursor(cursor, tq.orderBy)
+	if err != nil {
+		return nil, err
+	}
+	return tq.Filter(keysetCondition(tq.orderBy, values)), nil
+}
+
+// PageCursor returns the opaque cursor of the row, a struct or a map[string]string of the query result,
+// which is the values of the order fields of the row. The value of an order field is read by the label of
+// the selected field of the same column, and from the nested struct tagged by the alias of its table, so that
+// the identically named columns of the joined tables are told apart. The nullable order columns should be
+// scanned into pointer fields so that NULLs are not taken as zero values, and a map row, where a NULL is an
+// empty string, is refused with ErrNotSupported if any order column is nullable.
+func (tq *SQuery) PageCursor(row interface{}) (string, error) {
+	if len(tq.orderBy) == 0 {
+		return "", errors.Wrap(ErrNotSupported, "page cursor of a query without order")
+	}
+	result, isMap := row.(map[string]string)
+	value := reflect.Indirect(reflect.ValueOf(row))
+	if !isMap && value.Kind() != reflect.Struct {
+		return "", errors.Wrapf(errors.ErrInvalidFormat, "row of %s", value.Type())
+	}
+	if isMap {
+		for i := range tq.orderBy {
+			if isNullableField(tq.orderBy[i].field) {
+				return "", errors.Wrapf(ErrNotSupported, "page cursor of a map row ordered by nullable field %s", tq.orderBy[i].field.Name())
+			}
+		}
+	}
+	values := make([]interface{}, len(tq.orderBy))
+	for i := range tq.orderBy {
+		label, alias := tq.outputColumn(tq.orderBy[i].field)
+		var ok bool
+		if isMap {
+			values[i], ok = result[label]
+		} else {
+			values[i], ok = structColumnValue(value, alias, label)
+		}
+		if !ok {
+			return "", errors.Wrapf(errors.ErrNotFound, "field %s", label)
+		}
+	}
+	return encodePageCursor(values), nil
+}
+
+// outputColumn returns the label of the field in the query result, which is the label of the selected field of
+// the same column, and the alias of the table of the column, which is empty if the selected field is labelled
+func (tq *SQuery) outputColumn(field IQueryField) (string, string) {
+	tf, _ := field.(*STableField)
+	for _, f := range tq.QueryFields() {
+		if f == field {
+			return f.Name(), fieldTableAlias(f)
+		}
+		if qf, ok := f.(*STableField); ok && tf != nil && qf.table.Alias() == tf.table.Alias() && qf.spec.Name() == tf.spec.Name() {
+			return qf.Name(), fieldTableAlias(qf)
+		}
+	}
+	return field.Name(), fieldTableAlias(field)
+}
+
+// fieldTableAlias returns the alias of the table of an unlabelled table field, by which it is scanned into a nested struct
+func fieldTableAlias(f IQueryField) string {
+	if tf, ok := f.(*STableField); ok && len(tf.alias) == 0 {
+		return tf.table.Alias()
+	}
+	return ""
+}
+
+// structColumnValue returns the value of the struct field receiving the column of the label from the table alias,
+// nil if the column belongs to a nil nested struct pointer
+func structColumnValue(value reflect.Value, alias, label string) (interface{}, bool) {
+	info := getStructScanInfo(value.Type())
+	if info == nil {
+		return reflectutils.FetchStructFieldValueSet(value).GetInterface(label)
+	}
+	path, _ := info.resolve(value.Type(), alias, label)
+	if path == nil {
+		return nil, false
+	}
+	fv := value
+	for _, idx := range path {
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				return nil, true
+			}
+			fv = fv.Elem()
+		}
+		fv = fv.Field(idx)
+	}
+	return fv.Interface(), true
+}
+
+// encodePageCursor encodes the values into the base64 of a json array of the string values or nulls
+func encodePageCursor(values []interface{}) string {
+	arr := jsonutils.NewArray()
+	for _, v := range values {
+		if gotypes.IsNil(v) {
+			arr.Add(jsonutils.JSONNull)
+			continue
+		}
+		switch fv := reflect.Indirect(reflect.ValueOf(v)); fv.Kind() {
+		case reflect.Float32, reflect.Float64:
+			arr.Add(jsonutils.NewString(strconv.FormatFloat(fv.Float(), 'g', -1, 64)))
+		default:
+			arr.Add(jsonutils.NewString(GetStringValue(v)))
+		}
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(arr.String()))
+}
+
+// decodePageCursor decodes the values of the orders from the cursor, converted by the columns of the order fields
+func decodePageCursor(cursor string, orders []sQueryOrder) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidFormat, "page cursor")
+	}
+	obj, err := jsonutils.Parse(data)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidFormat, "page cursor")
+	}
+	arr, ok := obj.(*jsonutils.JSONArray)
+	if !ok || arr.Length() != len(orders) {
+		return nil, errors.Wrapf(errors.ErrInvalidFormat, "page cursor of %d orders", len(orders))
+	}
+	values := make([]interface{}, len(orders))
+	for i := range orders {
+		v, _ := arr.GetAt(i)
+		if v == jsonutils.JSONNull {
+			continue
+		}
+		str, err := v.GetString()
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrInvalidFormat, "page cursor")
+		}
+		if tf, ok := orders[i].field.(*STableField); ok {
+			values[i] = tf.spec.ConvertFromString(str)
+		} else {
+			values[i] = str
+		}
+	}
+	return values, nil
+}
@@ -15,6 +15,7 @@
 package sqlchemy
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"reflect"
@@ -50,9 +51,10 @@ type sQueryJoin struct {
 
 // SQuery is a data structure represents a SQL query in the form of
 //
-//	SELECT ... FROM ... JOIN ... ON ... WHERE ... GROUP BY ... ORDER BY ... HAVING ...
+//	WITH ... SELECT ... FROM ... JOIN ... ON ... WHERE ... GROUP BY ... HAVING ... ORDER BY ...
 type SQuery struct {
 	rawSql   string
+	withs    []*SCTE
 	fields   []IQueryField
 	distinct bool
 	from     IQuerySource
@@ -60,20 +62,31 @@ type SQuery struct {
 	where    ICondition
 	groupBy  []IQueryField
 	orderBy  []sQueryOrder
-	// having   ICondition
-	limit  int
-	offset int
+	having   ICondition
+	limit    int
+	offset   int
+
+	lockType     QueryLockType
+	lockWaitType QueryLockWaitType
+
+	// unscoped indicates the soft-deleted rows are not excluded
+	unscoped bool
+
+	// preloads are the names of the relations loaded by First and All
+	preloads []string
 
 	fieldCache map[string]IQueryField
 
 	snapshot string
 
-	db *SDatabase
+	db  *SDatabase
+	ctx context.Context
 }
 
 func (self *SQuery) Copy() *SQuery {
 	q := &SQuery{
 		rawSql:     self.rawSql,
+		withs:      []*SCTE{},
 		fields:     []IQueryField{},
 		distinct:   self.distinct,
 		from:       self.from,
@@ -81,11 +94,21 @@ func (self *SQuery) Copy() *SQuery {
 		where:      self.where,
 		groupBy:    []IQueryField{},
 		orderBy:    []sQueryOrder{},
+		having:     self.having,
 		limit:      self.limit,
 		offset:     self.offset,
 		fieldCache: map[string]IQueryField{},
 		snapshot:   self.snapshot,
 		db:         self.db,
+		ctx:        self.ctx,
+
+		lockType:     self.lockType,
+		lockWaitType: self.lockWaitType,
+		unscoped:     self.unscoped,
+		preloads:     append([]string(nil), self.preloads...),
+	}
+	for i := range self.withs {
+		q.withs = append(q.withs, self.withs[i])
 	}
 	for i := range self.fields {
 		q.fields = append(q.fields, self.fields[i])
@@ -105,6 +128,21 @@ func (self *SQuery) Copy() *SQuery {
 	return q
 }
 
+// WithContext binds a context to the query, the context is used when the query is executed,
+// so that a slow query can be cancelled or be given a deadline
+func (tq *SQuery) WithContext(ctx context.Context) *SQuery {
+	tq.ctx = ctx
+	return tq
+}
+
+// Context returns the context bound to the query, context.Background() if no context is bound
+func (tq *SQuery) Context() context.Context {
+	if tq.ctx == nil {
+		return context.Background()
+	}
+	return tq.ctx
+}
+
 // IsGroupBy returns wether the query contains group by clauses
 func (tq *SQuery) IsGroupBy() bool {
 	return len(tq.groupBy) > 0
@@ -287,6 +325,11 @@ func (tq *SQuery) _join(from IQuerySource, on ICondition, joinType QueryJoinType
 func (tq *SQuery) Variables() []interface{} {
 	vars := make([]interface{}, 0)
 	var fromvars []interface{}
+	ctes, _ := tq.ctes()
+	for _, cte := range ctes {
+		fromvars = cte.definitionVariables()
+		vars = append(vars, fromvars...)
+	}
 	fields := tq.fields
 	for i := range fields {
 		fromvars = fields[i].Variables()
@@ -302,14 +345,14 @@ func (tq *SQuery) Variables() []interface{} {
 		fromvars = join.condition.Variables()
 		vars = append(vars, fromvars...)
 	}
-	if tq.where != nil {
-		fromvars = tq.where.Variables()
+	if where := tq.whereCondition(); where != nil {
+		fromvars = where.Variables()
 		vars = append(vars, fromvars...)
 	}
-	/*if tq.having != nil {
+	if tq.having != nil {
 		fromvars = tq.having.Variables()
 		vars = append(vars, fromvars...)
-	}*/
+	}
 	return vars
 }
 
@@ -329,8 +372,27 @@ func (tq *SQuery) database() *SDatabase {
 	return tq.db
 }
 
-// Row of SQuery returns an instance of  sql.Row for native data fetching
+// Row of SQuery returns an instance of  sql.Row for native data fetching.
+// As sql.Row cannot carry the error of an invalid query, e.g. row locking on a backend that does not
+// support row locking, the error is logged and the statement is rejected by the database, use RowWithError
+// to get the error beforehand
 func (tq *SQuery) Row() *sql.Row {
+	if err := tq.validate(); err != nil {
+		log.Errorf("SQuery row %s: %s", tq.String(), err)
+	}
+	return tq.row()
+}
+
+// RowWithError of SQuery returns an instance of sql.Row for native data fetching,
+// or the error of the query that the backend does not support
+func (tq *SQuery) RowWithError() (*sql.Row, error) {
+	if err := tq.validate(); err != nil {
+		return nil, err
+	}
+	return tq.row(), nil
+}
+
+func (tq *SQuery) row() *sql.Row {
 	sqlstr := tq.String()
 	vars := tq.Variables()
 	if DEBUG_SQLCHEMY {
@@ -342,17 +404,31 @@ func (tq *SQuery) Row() *sql.Row {
 	if tq.db.db == nil {
 		panic("tq.db.db")
 	}
-	return tq.db.db.QueryRow(sqlstr, vars...)
+	return tq.db.queryRowContext(tq.Context(), sqlstr, vars...)
+}
+
+// validate checks the clauses that the backend of the query may not support before the query is executed
+func (tq *SQuery) validate() error {
+	if _, err := tq.lockingClause(); err != nil {
+		return errors.Wrap(err, "lockingClause")
+	}
+	if _, err := tq.ctes(); err != nil {
+		return errors.Wrap(err, "ctes")
+	}
+	return nil
 }
 
 // Rows of SQuery returns an instance of sql.Rows for native data fetching
 func (tq *SQuery) Rows() (*sql.Rows, error) {
+	if err := tq.validate(); err != nil {
+		return nil, err
+	}
 	sqlstr := tq.String()
 	vars := tq.Variables()
 	if DEBUG_SQLCHEMY {
 		sqlDebug(sqlstr, vars)
 	}
-	return tq.db.db.Query(sqlstr, vars...)
+	return tq.db.queryContext(tq.Context(), sqlstr, vars...)
 }
 
 // Count of SQuery returns the count of a query
@@ -367,12 +443,15 @@ func (tq *SQuery) CountQuery() *SQuery {
 	tq2 := *tq
 	tq2.limit = 0
 	tq2.offset = 0
+	tq2.lockType = ""
+	tq2.lockWaitType = ""
 	cq := &SQuery{
 		fields: []IQueryField{
 			COUNT("count"),
 		},
 		from: tq2.SubQuery(),
 		db:   tq.database(),
+		ctx:  tq.ctx,
 	}
 	return cq
 }
@@ -381,7 +460,10 @@ func (tq *SQuery) CountQuery() *SQuery {
 func (tq *SQuery) CountWithError() (int, error) {
 	cq := tq.CountQuery()
 	count := 0
-	err := cq.Row().Scan(&count)
+	row, err := cq.RowWithError()
+	if err == nil {
+		err = row.Scan(&count)
+	}
 	if err == nil {
 		return count, nil
 	}
@@ -485,7 +567,11 @@ func (tq *SQuery) rowScan2StringMap(row IRowScanner) (map[string]string, error)
 
 // FirstStringMap returns query result of the first row in a stringmap(map[string]string)
 func (tq *SQuery) FirstStringMap() (map[string]string, error) {
-	return tq.rowScan2StringMap(tq.Row())
+	row, err := tq.RowWithError()
+	if err != nil {
+		return nil, err
+	}
+	return tq.rowScan2StringMap(row)
 }
 
 // AllStringMap returns query result of all rows in an array of stringmap(map[string]string)
@@ -532,21 +618,33 @@ func callAfterQuery(val reflect.Value) {
 
 // First return query result of first row and store the result in a data struct
 func (tq *SQuery) First(dest interface{}) error {
-	mapResult, err := tq.FirstStringMap()
-	if err != nil {
-		return err
-	}
 	destPtrValue := reflect.ValueOf(dest)
 	if destPtrValue.Kind() != reflect.Ptr {
 		return errors.Wrap(ErrNeedsPointer, "input must be a pointer")
 	}
 	destValue := destPtrValue.Elem()
+	if plan := tq.newScanPlan(destValue.Type()); plan != nil {
+		row, err := tq.RowWithError()
+		if err != nil {
+			return err
+		}
+		err = plan.scanRow(row, destValue, make([]interface{}, len(plan.names)))
+		if err != nil {
+			return err
+		}
+		callAfterQuery(destPtrValue)
+		return tq.loadPreloads([]reflect.Value{destValue})
+	}
+	mapResult, err := tq.FirstStringMap()
+	if err != nil {
+		return err
+	}
 	err = mapString2Struct(mapResult, destValue)
 	if err != nil {
 		return err
 	}
 	callAfterQuery(destPtrValue)
-	return nil
+	return tq.loadPreloads([]reflect.Value{destValue})
 }
 
 // All return query results of all rows and store the result in an array of data struct
@@ -557,25 +655,48 @@ func (tq *SQuery) All(dest interface{}) error {
 		return errors.Wrap(ErrNeedsArray, "dest is not an array or slice")
 	}
 	elemType := arrayType.Elem()
+	arrayValue := reflect.ValueOf(dest).Elem()
+	start := arrayValue.Len()
+
+	if plan := tq.newScanPlan(elemType); plan != nil && arrayType.Kind() == reflect.Slice {
+		rows, err := tq.Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		err = plan.scanRows(rows, arrayValue)
+		if err != nil {
+			return err
+		}
+		return tq.loadPreloads(sliceElems(arrayValue, start))
+	}
 
 	mapResults, err := tq.AllStringMap()
 	if err != nil {
 		return err
 	}
 
-	arrayValue := reflect.ValueOf(dest).Elem()
 	for _, mapV := range mapResults {
 		elemPtrValue := reflect.New(elemType)
 		elemValue := reflect.Indirect(elemPtrValue)
 		err = mapString2Struct(mapV, elemValue)
 		if err != nil {
-			break
+			return err
 		}
 		callAfterQuery(elemPtrValue)
 		newArray := reflect.Append(arrayValue, elemValue)
 		arrayValue.Set(newArray)
 	}
-	return err
+	return tq.loadPreloads(sliceElems(arrayValue, start))
+}
+
+// sliceElems returns the elements of arrayValue from start
+func sliceElems(arrayValue reflect.Value, start int) []reflect.Value {
+	elems := make([]reflect.Value, 0, arrayValue.Len()-start)
+	for i := start; i < arrayValue.Len(); i++ {
+		elems = append(elems, arrayValue.Index(i))
+	}
+	return elems
 }
 
 // Row2Map is a utility function that fetch stringmap(map[string]string) from a native sql.Row or sql.Rows
@@ -601,6 +722,17 @@ func (tq *SQuery) RowMap2Struct(result map[string]string, dest interface{}) erro
 
 // Row2Struct is a utility function that fill a struct with the value of a sql.Row or sql.Rows
 func (tq *SQuery) Row2Struct(row IRowScanner, dest interface{}) error {
+	destPtrValue := reflect.ValueOf(dest)
+	if destPtrValue.Kind() == reflect.Ptr {
+		if plan := tq.newScanPlan(destPtrValue.Elem().Type()); plan != nil {
+			err := plan.scanRow(row, destPtrValue.Elem(), make([]interface{}, len(plan.names)))
+			if err != nil {
+				return err
+			}
+			callAfterQuery(destPtrValue)
+			return nil
+		}
+	}
 	result, err := tq.rowScan2StringMap(row)
 	if err != nil {
 		return err
@@ -1,6 +1,7 @@
 package sqlchemy
 
 import (
+	"context"
 	"reflect"
 	"testing"
 	"time"
@@ -562,3 +563,449 @@ func TestQueryString3(t *testing.T) {
 		}
 	}
 }
+
+func TestQueryHaving(t *testing.T) {
+	SetupMockDatabaseBackend()
+	ResetTableID()
+
+	type TableStruct struct {
+		Id   int    `json:"id" primary:"true"`
+		Name string `width:"16"`
+		Age  int    `nullable:"true"`
+	}
+	table := NewTableSpecFromStruct(TableStruct{}, "testtable")
+	cases := []struct {
+		query *SQuery
+		want  string
+		vars  []interface{}
+	}{
+		{
+			query: func() *SQuery {
+				t := table.Instance()
+				q := t.Query(t.Field("name"), COUNT("cnt", t.Field("id"))).GroupBy(t.Field("name"))
+				return q.HavingGT("cnt", 5)
+			}(),
+			want: "SELECT `t1`.`name`, COUNT(`t1`.`id`) AS `cnt` FROM `testtable` AS `t1` GROUP BY `t1`.`name` HAVING COUNT(`t1`.`id`) >  ? ",
+			vars: []interface{}{5},
+		},
+		{
+			query: func() *SQuery {
+				t := table.Instance()
+				q := t.Query(t.Field("name")).GE("age", 18).GroupBy(t.Field("name"))
+				q = q.Having(GT(COUNT("", t.Field("id")), 1)).HavingLT("name", "x").Asc("name")
+				return q
+			}(),
+			want: "SELECT `t2`.`name` FROM `testtable` AS `t2` WHERE `t2`.`age` >=  ?  GROUP BY `t2`.`name` HAVING (COUNT(`t2`.`id`) >  ? ) AND (`t2`.`name` <  ? ) ORDER BY `t2`.`name` ASC",
+			vars: []interface{}{18, 1, "x"},
+		},
+		{
+			query: func() *SQuery {
+				t := table.Instance()
+				q := t.Query(t.Field("name"), SUM("total", t.Field("age"))).GroupBy(t.Field("name"))
+				return q.HavingBetween("total", 10, 20).Copy()
+			}(),
+			want: "SELECT `t3`.`name`, SUM(`t3`.`age`) AS `total` FROM `testtable` AS `t3` GROUP BY `t3`.`name` HAVING SUM(`t3`.`age`) BETWEEN  ?  AND  ? ",
+			vars: []interface{}{10, 20},
+		},
+		{
+			query: func() *SQuery {
+				t := table.Instance()
+				q := t.Query(t.Field("name"), MAX("last", t.Field("name"))).GroupBy(t.Field("name"))
+				return q.HavingStartswith("last", "a").HavingNotBetween("name", "b", "c").HavingIsNotEmpty("name")
+			}(),
+			want: "SELECT `t4`.`name`, MAX(`t4`.`name`) AS `last` FROM `testtable` AS `t4` GROUP BY `t4`.`name` HAVING (MAX(`t4`.`name`) LIKE  ? ) AND (NOT (`t4`.`name` BETWEEN  ?  AND  ? )) AND (`t4`.`name` IS NOT NULL AND LENGTH(`t4`.`name`) > 0)",
+			vars: []interface{}{"a%", "b", "c"},
+		},
+	}
+	for _, c := range cases {
+		got := c.query.String()
+		if got != c.want {
+			t.Errorf("want: %s got: %s", c.want, got)
+		}
+		vars := c.query.Variables()
+		if !reflect.DeepEqual(vars, c.vars) {
+			t.Errorf("want vars: %v got %v", c.vars, vars)
+		}
+	}
+}
+
+func TestQueryCTE(t *testing.T) {
+	SetupMockDatabaseBackend()
+	ResetTableID()
+
+	type TableStruct struct {
+		Id       int    `json:"id" primary:"true"`
+		Name     string `width:"16"`
+		ParentId int    `nullable:"true"`
+	}
+	table := NewTableSpecFromStruct(TableStruct{}, "testtable")
+	cases := []struct {
+		query *SQuery
+		want  string
+		vars  []interface{}
+	}{
+		{
+			query: func() *SQuery {
+				t := table.Instance()
+				cte := NewCTE("children", t.Query(t.Field("id"), t.Field("name")).Equals("parent_id", 1))
+				return cte.Query(cte.Field("name")).Equals("id", 2)
+			}(),
+			want: "WITH `children` AS (SELECT `t1`.`id`, `t1`.`name` FROM `testtable` AS `t1` WHERE `t1`.`parent_id` =  ? ) SELECT `t2`.`name` FROM `children` AS `t2` WHERE `t2`.`id` =  ? ",
+			vars: []interface{}{1, 2},
+		},
+		{
+			query: func() *SQuery {
+				t := table.Instance()
+				q := t.Query(t.Field("id"), t.Field("name"))
+				t2 := table.Instance()
+				cte := q.With("named", t2.Query(t2.Field("id")).IsNotEmpty("name"))
+				return q.Join(cte, Equals(t.Field("id"), cte.Field("id")))
+			}(),
+			want: "WITH `named` AS (SELECT `t4`.`id` FROM `testtable` AS `t4` WHERE `t4`.`name` IS NOT NULL AND LENGTH(`t4`.`name`) > 0) SELECT `t3`.`id`, `t3`.`name` FROM `testtable` AS `t3` JOIN `named` AS `t5` ON `t3`.`id` = `t5`.`id`",
+			vars: []interface{}{},
+		},
+		{
+			query: func() *SQuery {
+				t := table.Instance()
+				anchor := t.Query(t.Field("id"), t.Field("name")).Equals("id", 1)
+				tree := NewRecursiveCTE("tree", anchor, func(self *SCTE) IQuery {
+					t2 := table.Instance()
+					return t2.Query(t2.Field("id"), t2.Field("name")).Join(self, Equals(t2.Field("parent_id"), self.Field("id")))
+				})
+				return tree.Query().Asc("id")
+			}(),
+			want: "WITH RECURSIVE `tree` AS (SELECT `t6`.`id`, `t6`.`name` FROM `testtable` AS `t6` WHERE `t6`.`id` =  ?  UNION ALL SELECT `t8`.`id`, `t8`.`name` FROM `testtable` AS `t8` JOIN `tree` AS `t7` ON `t8`.`parent_id` = `t7`.`id`) SELECT `t9`.`id`, `t9`.`name` FROM `tree` AS `t9` ORDER BY `t9`.`id` ASC",
+			vars: []interface{}{1},
+		},
+	}
+	for _, c := range cases {
+		got := c.query.String()
+		if got != c.want {
+			t.Errorf("want: %s got: %s", c.want, got)
+		}
+		vars := c.query.Variables()
+		if !reflect.DeepEqual(vars, c.vars) {
+			t.Errorf("want vars: %v got %v", c.vars, vars)
+		}
+	}
+}
+
+func TestQueryWindowFunction(t *testing.T) {
+	SetupMockDatabaseBackend()
+	ResetTableID()
+
+	type TableStruct struct {
+		Id       int    `json:"id" primary:"true"`
+		Name     string `width:"16"`
+		Age      int    `nullable:"true"`
+		Category string `width:"16"`
+	}
+	table := NewTableSpecFromStruct(TableStruct{}, "testtable")
+	cases := []struct {
+		query *SQuery
+		want  string
+	}{
+		{
+			query: func() *SQuery {
+				t := table.Instance()
+				over := Over([]IQueryField{t.Field("category")}, []SWindowOrder{WindowDesc(t.Field("id"))}, "")
+				return t.Query(t.Field("id"), ROW_NUMBER("rn", over))
+			}(),
+			want: "SELECT `t1`.`id`, ROW_NUMBER() OVER (PARTITION BY `t1`.`category` ORDER BY `t1`.`id` DESC) AS `rn` FROM `testtable` AS `t1`",
+		},
+		{
+			query: func() *SQuery {
+				t := table.Instance()
+				over := Over(nil, []SWindowOrder{WindowAsc(t.Field("age"))}, "")
+				return t.Query(RANK("rank", over), DENSE_RANK("dense_rank", over))
+			}(),
+			want: "SELECT RANK() OVER (ORDER BY `t2`.`age` ASC) AS `rank`, DENSE_RANK() OVER (ORDER BY `t2`.`age` ASC) AS `dense_rank` FROM `testtable` AS `t2`",
+		},
+		{
+			query: func() *SQuery {
+				t := table.Instance()
+				over := Over([]IQueryField{t.Field("category")}, []SWindowOrder{WindowAsc(t.Field("id"))}, "")
+				return t.Query(LAG("prev", t.Field("age"), 1, over), LEAD("next", t.Field("age"), 2, over), FIRST_VALUE("first", t.Field("name"), over))
+			}(),
+			want: "SELECT LAG(`t3`.`age`, 1) OVER (PARTITION BY `t3`.`category` ORDER BY `t3`.`id` ASC) AS `prev`, LEAD(`t3`.`age`, 2) OVER (PARTITION BY `t3`.`category` ORDER BY `t3`.`id` ASC) AS `next`, FIRST_VALUE(`t3`.`name`) OVER (PARTITION BY `t3`.`category` ORDER BY `t3`.`id` ASC) AS `first` FROM `testtable` AS `t3`",
+		},
+		{
+			query: func() *SQuery {
+				t := table.Instance()
+				over := Over([]IQueryField{t.Field("category")}, []SWindowOrder{WindowAsc(t.Field("id"))}, "ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW")
+				return t.Query(SUM_OVER("running_age", t.Field("age"), over))
+			}(),
+			want: "SELECT SUM(`t4`.`age`) OVER (PARTITION BY `t4`.`category` ORDER BY `t4`.`id` ASC ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) AS `running_age` FROM `testtable` AS `t4`",
+		},
+		{
+			query: func() *SQuery {
+				t := table.Instance()
+				return t.Query(ROW_NUMBER("rn", Over(nil, nil, "")))
+			}(),
+			want: "SELECT ROW_NUMBER() OVER () AS `rn` FROM `testtable` AS `t5`",
+		},
+	}
+	for _, c := range cases {
+		got := c.query.String()
+		if got != c.want {
+			t.Errorf("want: %s got: %s", c.want, got)
+		}
+	}
+}
+
+func TestQuerySubqueryConditions(t *testing.T) {
+	SetupMockDatabaseBackend()
+	ResetTableID()
+
+	type UserStruct struct {
+		Id   int    `json:"id" primary:"true"`
+		Name string `width:"16"`
+	}
+	type OrderStruct struct {
+		Id     int `json:"id" primary:"true"`
+		UserId int `json:"user_id"`
+		Amount int `json:"amount"`
+	}
+	users := NewTableSpecFromStruct(UserStruct{}, "users")
+	orders := NewTableSpecFromStruct(OrderStruct{}, "orders")
+	cases := []struct {
+		query *SQuery
+		want  string
+		vars  []interface{}
+	}{
+		{
+			query: func() *SQuery {
+				u := users.Instance()
+				o := orders.Instance()
+				q := u.Query(u.Field("name")).Equals("name", "john")
+				subq := o.Query(o.Field("id")).Filter(Equals(o.Field("user_id"), u.Field("id"))).GT("amount", 100)
+				return q.Filter(Exists(subq)).Asc("name")
+			}(),
+			want: "SELECT `t1`.`name` FROM `users` AS `t1` WHERE (`t1`.`name` =  ? ) AND (EXISTS (SELECT `t2`.`id` FROM `orders` AS `t2` WHERE (`t2`.`user_id` = `t1`.`id`) AND (`t2`.`amount` >  ? ))) ORDER BY `t1`.`name` ASC",
+			vars: []interface{}{"john", 100},
+		},
+		{
+			query: func() *SQuery {
+				u := users.Instance()
+				o := orders.Instance()
+				subq := o.Query(o.Field("id")).Filter(Equals(o.Field("user_id"), u.Field("id")))
+				return u.Query(u.Field("name")).Filter(NotExists(subq))
+			}(),
+			want: "SELECT `t3`.`name` FROM `users` AS `t3` WHERE NOT EXISTS (SELECT `t4`.`id` FROM `orders` AS `t4` WHERE `t4`.`user_id` = `t3`.`id`)",
+			vars: []interface{}{},
+		},
+		{
+			query: func() *SQuery {
+				u := users.Instance()
+				o := orders.Instance()
+				subq := o.Query(o.Field("user_id")).GE("amount", 10)
+				return u.Query(u.Field("name")).Filter(InQuery(u.Field("id"), subq)).Equals("name", "john")
+			}(),
+			want: "SELECT `t5`.`name` FROM `users` AS `t5` WHERE (`t5`.`id` IN (SELECT `t6`.`user_id` FROM `orders` AS `t6` WHERE `t6`.`amount` >=  ? )) AND (`t5`.`name` =  ? )",
+			vars: []interface{}{10, "john"},
+		},
+		{
+			query: func() *SQuery {
+				u := users.Instance()
+				o := orders.Instance()
+				subq := o.Query(o.Field("user_id")).LT("amount", 10)
+				return u.Query(u.Field("name")).Filter(NotInQuery(u.Field("id"), subq))
+			}(),
+			want: "SELECT `t7`.`name` FROM `users` AS `t7` WHERE `t7`.`id` NOT IN (SELECT `t8`.`user_id` FROM `orders` AS `t8` WHERE `t8`.`amount` <  ? )",
+			vars: []interface{}{10},
+		},
+	}
+	for _, c := range cases {
+		got := c.query.String()
+		if got != c.want {
+			t.Errorf("want: %s got: %s", c.want, got)
+		}
+		vars := c.query.Variables()
+		if !reflect.DeepEqual(vars, c.vars) {
+			t.Errorf("want vars: %v got %v", c.vars, vars)
+		}
+	}
+}
+
+func TestQueryWithContext(t *testing.T) {
+	SetupMockDatabaseBackend()
+	ResetTableID()
+
+	type TableStruct struct {
+		Id   int    `json:"id" primary:"true"`
+		Name string `width:"16"`
+	}
+	table := NewTableSpecFromStruct(TableStruct{}, "testtable")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	q := table.Query().WithContext(ctx)
+	if q.Copy().Context() != ctx {
+		t.Errorf("context not preserved by Copy")
+	}
+	if q.CountQuery().Context() != ctx {
+		t.Errorf("context not preserved by CountQuery")
+	}
+	if table.Query().Context() == nil {
+		t.Errorf("default context should not be nil")
+	}
+
+	_, err := q.Rows()
+	if errors.Cause(err) != context.Canceled {
+		t.Errorf("Rows: want %s got %v", context.Canceled, err)
+	}
+	_, err = table.Database().TxExecContext(ctx, "DELETE FROM `testtable`")
+	if errors.Cause(err) != context.Canceled {
+		t.Errorf("TxExecContext: want %s got %v", context.Canceled, err)
+	}
+	err = table.DeleteFromContext(ctx, map[string]interface{}{"id": 1})
+	if errors.Cause(err) != context.Canceled {
+		t.Errorf("DeleteFromContext: want %s got %v", context.Canceled, err)
+	}
+}
+
+func TestQuerySoftDelete(t *testing.T) {
+	SetupMockDatabaseBackend()
+	ResetTableID()
+
+	type TableStruct struct {
+		Id        int       `json:"id" primary:"true"`
+		Name      string    `width:"16"`
+		Deleted   bool      `soft_delete:"true"`
+		DeletedAt time.Time `deleted_at:"true"`
+	}
+	table := NewTableSpecFromStruct(TableStruct{}, "testtable")
+	if !table.IsSoftDelete() {
+		t.Fatalf("table should be soft-deletable")
+	}
+	cases := []struct {
+		query *SQuery
+		want  string
+		vars  []interface{}
+	}{
+		{
+			query: table.Query(),
+			want:  "SELECT `t1`.`id`, `t1`.`name`, `t1`.`deleted`, `t1`.`deleted_at` FROM `testtable` AS `t1` WHERE `t1`.`deleted` = 0",
+			vars:  []interface{}{},
+		},
+		{
+			query: func() *SQuery {
+				ti := table.Instance()
+				return ti.Query(ti.Field("name")).Equals("id", 1)
+			}(),
+			want: "SELECT `t2`.`name` FROM `testtable` AS `t2` WHERE (`t2`.`id` =  ? ) AND (`t2`.`deleted` = 0)",
+			vars: []interface{}{1},
+		},
+		{
+			query: func() *SQuery {
+				ti := table.Instance()
+				return ti.Query(ti.Field("name")).Equals("id", 1).Unscoped().Copy()
+			}(),
+			want: "SELECT `t3`.`name` FROM `testtable` AS `t3` WHERE `t3`.`id` =  ? ",
+			vars: []interface{}{1},
+		},
+	}
+	for _, c := range cases {
+		if got := c.query.String(); got != c.want {
+			t.Errorf("want: %s\ngot:  %s", c.want, got)
+		}
+		if got := c.query.Variables(); !reflect.DeepEqual(got, c.vars) {
+			t.Errorf("vars want: %#v got: %#v", c.vars, got)
+		}
+	}
+}
+
+func TestQueryKeysetCondition(t *testing.T) {
+	SetupMockDatabaseBackend()
+	ResetTableID()
+
+	type TableStruct struct {
+		Zone string `width:"16" primary:"true"`
+		Id   int    `primary:"true"`
+		Name string `width:"16"`
+	}
+	table := NewTableSpecFromStruct(TableStruct{}, "testtable")
+	ti := table.Instance()
+	cases := []struct {
+		orders []sQueryOrder
+		values []interface{}
+		want   string
+	}{
+		{
+			orders: []sQueryOrder{{field: ti.Field("id"), order: SQL_ORDER_ASC}},
+			values: []interface{}{1},
+			want:   "`t1`.`id` >  ? ",
+		},
+		{
+			orders: []sQueryOrder{{field: ti.Field("zone"), order: SQL_ORDER_ASC}, {field: ti.Field("id"), order: SQL_ORDER_DESC}},
+			values: []interface{}{"z1", 1},
+			want:   "(`t1`.`zone` >  ? ) OR ((`t1`.`zone` =  ? ) AND (`t1`.`id` <  ? ))",
+		},
+		{
+			orders: []sQueryOrder{{field: ti.Field("zone"), order: SQL_ORDER_ASC}, {field: ti.Field("name"), order: SQL_ORDER_ASC}},
+			values: []interface{}{"z1", nil},
+			want:   "(`t1`.`zone` >  ? ) OR ((`t1`.`zone` =  ? ) AND (`t1`.`name` IS NOT NULL))",
+		},
+		{
+			orders: []sQueryOrder{{field: ti.Field("name"), order: SQL_ORDER_DESC}, {field: ti.Field("id"), order: SQL_ORDER_ASC}},
+			values: []interface{}{"n1", 1},
+			want:   "(`t1`.`name` <  ? ) OR (`t1`.`name` IS NULL) OR ((`t1`.`name` =  ? ) AND (`t1`.`id` >  ? ))",
+		},
+	}
+	for _, c := range cases {
+		cond := keysetCondition(c.orders, c.values)
+		if got := cond.WhereClause(); got != c.want {
+			t.Errorf("want: %s\ngot:  %s", c.want, got)
+		}
+	}
+}
+
+func TestQueryInTuples(t *testing.T) {
+	SetupMockDatabaseBackend()
+	ResetTableID()
+
+	type TableStruct struct {
+		Zone string `width:"16" primary:"true"`
+		Id   int    `primary:"true"`
+	}
+	table := NewTableSpecFromStruct(TableStruct{}, "testtable")
+	cases := []struct {
+		query *SQuery
+		want  string
+		vars  []interface{}
+	}{
+		{
+			query: func() *SQuery {
+				q := table.Query()
+				return q.Filter(InTuples([]IQueryField{q.Field("zone"), q.Field("id")}, [][]interface{}{{"z1", 1}, {"z2", 2}}))
+			}(),
+			want: "SELECT `t1`.`zone`, `t1`.`id` FROM `testtable` AS `t1` WHERE (`t1`.`zone` = ? AND `t1`.`id` = ?) OR (`t1`.`zone` = ? AND `t1`.`id` = ?)",
+			vars: []interface{}{"z1", 1, "z2", 2},
+		},
+		{
+			query: func() *SQuery {
+				q := table.Query()
+				return q.Filter(InTuples([]IQueryField{q.Field("id")}, [][]interface{}{{1}, {2}}))
+			}(),
+			want: "SELECT `t2`.`zone`, `t2`.`id` FROM `testtable` AS `t2` WHERE `t2`.`id` IN ( ?, ? )",
+			vars: []interface{}{1, 2},
+		},
+		{
+			query: func() *SQuery {
+				q := table.Query()
+				return q.Filter(InTuples([]IQueryField{q.Field("zone"), q.Field("id")}, nil))
+			}(),
+			want: "SELECT `t3`.`zone`, `t3`.`id` FROM `testtable` AS `t3` WHERE 0",
+			vars: []interface{}{},
+		},
+	}
+	for _, c := range cases {
+		if got := c.query.String(); got != c.want {
+			t.Errorf("want: %s\ngot:  %s", c.want, got)
+		}
+		if got := c.query.Variables(); !reflect.DeepEqual(got, c.vars) {
+			t.Errorf("vars want: %#v got: %#v", c.vars, got)
+		}
+	}
+}
@@ -17,6 +17,8 @@ package sqlchemy
 import (
 	"bytes"
 	"fmt"
+
+	"yunion.io/x/log"
 )
 
 // IQuery is an interface that reprsents a SQL query, e.g.
@@ -102,6 +104,7 @@ func queryString(tq *SQuery, tmpFields ...IQueryField) string {
 	}
 
 	var buf bytes.Buffer
+	buf.WriteString(tq.withClause())
 	buf.WriteString("SELECT ")
 	if tq.distinct {
 		buf.WriteString("DISTINCT ")
@@ -123,20 +126,20 @@ func queryString(tq *SQuery, tmpFields ...IQueryField) string {
 		buf.WriteString(fields[i].Expression())
 	}
 	buf.WriteString(" FROM ")
-	buf.WriteString(fmt.Sprintf("%s AS `%s`", tq.from.Expression(), tq.from.Alias()))
+	buf.WriteString(fmt.Sprintf("%s AS %s", tq.from.Expression(), quoteIdentifier(tq.database(), tq.from.Alias())))
 	for _, join := range tq.joins {
 		buf.WriteByte(' ')
 		buf.WriteString(string(join.jointype))
 		buf.WriteByte(' ')
-		buf.WriteString(fmt.Sprintf("%s AS `%s`", join.from.Expression(), join.from.Alias()))
+		buf.WriteString(fmt.Sprintf("%s AS %s", join.from.Expression(), quoteIdentifier(tq.database(), join.from.Alias())))
 		whereCls := join.condition.WhereClause()
 		if len(whereCls) > 0 {
 			buf.WriteString(" ON ")
 			buf.WriteString(whereCls)
 		}
 	}
-	if tq.where != nil {
-		whereCls := tq.where.WhereClause()
+	if where := tq.whereCondition(); where != nil {
+		whereCls := where.WhereClause()
 		if len(whereCls) > 0 {
 			buf.WriteString(" WHERE ")
 			buf.WriteString(whereCls)
@@ -151,10 +154,13 @@ func queryString(tq *SQuery, tmpFields ...IQueryField) string {
 			buf.WriteString(f.Reference())
 		}
 	}
-	/*if tq.having != nil {
-		buf.WriteString(" HAVING ")
-		buf.WriteString(tq.having.WhereClause())
-	}*/
+	if tq.having != nil {
+		havingCls := tq.having.WhereClause()
+		if len(havingCls) > 0 {
+			buf.WriteString(" HAVING ")
+			buf.WriteString(havingCls)
+		}
+	}
 	if tq.orderBy != nil && len(tq.orderBy) > 0 {
 		buf.WriteString(" ORDER BY ")
 		for i, f := range tq.orderBy {
@@ -170,6 +176,19 @@ func queryString(tq *SQuery, tmpFields ...IQueryField) string {
 	if tq.offset > 0 {
 		buf.WriteString(fmt.Sprintf(" OFFSET %d", tq.offset))
 	}
+	if lockCls, err := tq.lockingClause(); err != nil {
+		// keep the requested lock in the statement, so that it is rejected by the database instead of running without lock
+		log.Errorf("invalid locking clause: %s", err)
+		buf.WriteString(" FOR ")
+		buf.WriteString(string(tq.lockType))
+		if len(tq.lockWaitType) > 0 {
+			buf.WriteByte(' ')
+			buf.WriteString(string(tq.lockWaitType))
+		}
+	} else if len(lockCls) > 0 {
+		buf.WriteByte(' ')
+		buf.WriteString(lockCls)
+	}
 	return buf.String()
 }
 
@@ -82,9 +82,9 @@ func setValueBySQLString(value reflect.Value, val string) error {
 	}
 	switch value.Type() {
 	case tristate.TriStateType:
-		if val == "1" {
+		if val == "1" || val == "true" {
 			value.Set(tristate.TriStateTrueValue)
-		} else if val == "0" {
+		} else if val == "0" || val == "false" {
 			value.Set(tristate.TriStateFalseValue)
 		} else {
 			value.Set(tristate.TriStateNoneValue)
@@ -102,7 +102,7 @@ func setValueBySQLString(value reflect.Value, val string) error {
 	}
 	switch value.Kind() {
 	case reflect.Bool:
-		if val == "0" {
+		if val == "0" || val == "false" {
 			value.SetBool(false)
 		} else {
 			value.SetBool(true)
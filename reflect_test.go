@@ -194,6 +194,11 @@ func TestSetValueBySQLString(t *testing.T) {
 			sqlstr: "0",
 			want:   false,
 		},
+		{
+			field:  "bool_v2",
+			sqlstr: "false",
+			want:   false,
+		},
 		{
 			field:  "float_v",
 			sqlstr: "1.234",
@@ -219,6 +224,16 @@ func TestSetValueBySQLString(t *testing.T) {
 			sqlstr: "none",
 			want:   tristate.None,
 		},
+		{
+			field:  "tristate_v",
+			sqlstr: "true",
+			want:   tristate.True,
+		},
+		{
+			field:  "tristate_v",
+			sqlstr: "false",
+			want:   tristate.False,
+		},
 		{
 			field:  "str_v",
 			sqlstr: "abcdEF",
@@ -276,3 +291,100 @@ func TestSetValueBySQLString(t *testing.T) {
 		}
 	}
 }
+
+type ScanEmbedStruct struct {
+	Status string
+}
+
+type ScanPtrEmbedStruct struct {
+	Size int64
+}
+
+type scanTestStruct struct {
+	ScanEmbedStruct
+	*ScanPtrEmbedStruct
+
+	Id      int               `json:"id"`
+	Name    string            `name:"title"`
+	Enabled tristate.TriState `json:"enabled"`
+	IsOk    bool              `json:"is_ok"`
+	Ratio   float64           `json:"ratio"`
+	Created time.Time         `json:"created"`
+	Tags    []string          `json:"tags"`
+	Ignored string            `json:"-"`
+}
+
+func TestScanPlan(t *testing.T) {
+	names := []string{"id", "title", "status", "size", "enabled", "is_ok", "ratio", "created", "tags", "ignored", "unknown"}
+	plan := newScanPlan(reflect.TypeOf(scanTestStruct{}), names)
+	if plan == nil {
+		t.Fatalf("scan plan should be supported")
+	}
+	for i, name := range names {
+		col := plan.columns[i]
+		if (col == nil) != (name == "ignored" || name == "unknown") {
+			t.Errorf("column %s: unexpected resolved %v", name, col)
+		}
+	}
+	tm := time.Date(2022, 1, 2, 3, 4, 5, 6000, time.FixedZone("", 3600))
+	raws := []interface{}{int64(1), []byte("name"), "ok", int64(100), int64(1), int64(0), 0.123456789, tm, []byte(`["a","b"]`), "ignored", nil}
+	row := sMockRow(raws)
+	dest := scanTestStruct{IsOk: true}
+	err := plan.scanRow(row, reflect.ValueOf(&dest).Elem(), make([]interface{}, len(names)))
+	if err != nil {
+		t.Fatalf("scanRow fail: %s", err)
+	}
+	want := scanTestStruct{
+		ScanEmbedStruct:    ScanEmbedStruct{Status: "ok"},
+		ScanPtrEmbedStruct: &ScanPtrEmbedStruct{Size: 100},
+		Id:                 1,
+		Name:               "name",
+		Enabled:            tristate.True,
+		IsOk:               false,
+		Ratio:              0.123456789,
+		Created:            time.Date(2022, 1, 2, 3, 4, 5, 6000, time.UTC),
+		Tags:               []string{"a", "b"},
+	}
+	if !reflect.DeepEqual(dest, want) {
+		t.Errorf("want %#v\ngot %#v", want, dest)
+	}
+}
+
+// sMockRow is a IRowScanner that returns the raw values
+type sMockRow []interface{}
+
+func (r sMockRow) Scan(dest ...interface{}) error {
+	for i := range dest {
+		*(dest[i].(*interface{})) = r[i]
+	}
+	return nil
+}
+
+type scanNestedStruct struct {
+	Count  int64           `json:"count"`
+	Book   scanTestStruct  `alias:"b"`
+	Author *scanTestStruct `alias:"a"`
+}
+
+func TestNestedScanPlan(t *testing.T) {
+	names := []string{"id", "title", "id", "title", "a.status", "count"}
+	aliases := []string{"b", "b", "a", "a", "", ""}
+	plan := newNestedScanPlan(reflect.TypeOf(scanNestedStruct{}), names, aliases)
+	if plan == nil {
+		t.Fatalf("scan plan should be supported")
+	}
+	row := sMockRow([]interface{}{int64(1), "book", int64(2), "author", "ok", int64(3)})
+	dest := scanNestedStruct{}
+	err := plan.scanRow(row, reflect.ValueOf(&dest).Elem(), make([]interface{}, len(names)))
+	if err != nil {
+		t.Fatalf("scanRow fail: %s", err)
+	}
+	want := scanNestedStruct{
+		Count:  3,
+		Book:   scanTestStruct{Id: 1, Name: "book"},
+		Author: &scanTestStruct{Id: 2, Name: "author", ScanEmbedStruct: ScanEmbedStruct{Status: "ok"}},
+	}
+	if !reflect.DeepEqual(dest, want) {
+		t.Errorf("want %#v\ngot %#v", want, dest)
+	}
+}
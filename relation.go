@@ -0,0 +1,259 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlchemy
+
+import (
+	"reflect"
+
+	"github.com/nyl1001/pkg/errors"
+	"github.com/nyl1001/pkg/gotypes"
+	"github.com/nyl1001/pkg/util/reflectutils"
+)
+
+// RelationType is the type of a relation between tables
+type RelationType string
+
+const (
+	// RELATION_BELONGS_TO is the relation that a column of the table references the key of the target table
+	RELATION_BELONGS_TO = RelationType("belongs_to")
+	// RELATION_HAS_MANY is the relation that a column of the target table references the key of the table
+	RELATION_HAS_MANY = RelationType("has_many")
+	// RELATION_MANY_TO_MANY is the relation between the table and the target table via a joint table
+	RELATION_MANY_TO_MANY = RelationType("many_to_many")
+
+	// relationOriginKeyAlias is the alias of the origin key of the joint table when loading a many-to-many relation
+	relationOriginKeyAlias = "sqlchemy_origin_key"
+)
+
+// SRelation declares a relation from a table to the target table, which is loaded into the struct field Name
+// of the model, a pointer or a struct for a single related row, or a slice for the related rows.
+// The field should be tagged by ignore:"true" so that it is not a column.
+type SRelation struct {
+	// Name is the name of the struct field populated with the related rows
+	Name string
+	Type RelationType
+
+	Target *STableSpec
+	// ForeignKey is the referencing column, of the table for RELATION_BELONGS_TO,
+	// or of the target table for RELATION_HAS_MANY
+	ForeignKey string
+	// Key is the referenced column, of the target table for RELATION_BELONGS_TO, or of the table otherwise,
+	// the primary key by default
+	Key string
+
+	// JointModel is the joint table of RELATION_MANY_TO_MANY
+	JointModel *STableSpec
+	// OriginKey is the column of the joint table referencing the primary key of the table
+	OriginKey string
+	// RelatedKey is the column of the joint table referencing the primary key of the target table
+	RelatedKey string
+}
+
+// AddRelation declares a relation of the table
+func (ts *STableSpec) AddRelation(rel SRelation) {
+	if ts.relations == nil {
+		ts.relations = make(map[string]*SRelation)
+	}
+	ts.relations[rel.Name] = &rel
+}
+
+// BelongsTo declares that the column foreignKey of the table references the primary key of the target table
+func (ts *STableSpec) BelongsTo(name string, target *STableSpec, foreignKey string) {
+	ts.AddRelation(SRelation{Name: name, Type: RELATION_BELONGS_TO, Target: target, ForeignKey: foreignKey})
+}
+
+// HasMany declares that the column foreignKey of the target table references the primary key of the table
+func (ts *STableSpec) HasMany(name string, target *STableSpec, foreignKey string) {
+	ts.AddRelation(SRelation{Name: name, Type: RELATION_HAS_MANY, Target: target, ForeignKey: foreignKey})
+}
+
+// ManyToMany declares the relation between the table and the target table via the joint table,
+// whose columns originKey and relatedKey reference the primary keys of the table and the target table
+func (ts *STableSpec) ManyToMany(name string, target *STableSpec, joint *STableSpec, originKey, relatedKey string) {
+	ts.AddRelation(SRelation{Name: name, Type: RELATION_MANY_TO_MANY, Target: target, JointModel: joint, OriginKey: originKey, RelatedKey: relatedKey})
+}
+
+// Relation returns the relation of the name, nil if not declared
+func (ts *STableSpec) Relation(name string) *SRelation {
+	return ts.relations[name]
+}
+
+// Preload loads the relations of the queried table into the rows returned by First, All and IterateByKeyset,
+// each relation is loaded by one query, instead of one query per row. Iterator and Iterate, which decode the
+// rows one at a time, fail with ErrNotSupported on a query with Preload
+func (tq *SQuery) Preload(names ...string) *SQuery {
+	tq.preloads = append(tq.preloads, names...)
+	return tq
+}
+
+// loadPreloads loads the preloaded relations into objs, addressable struct values of the rows
+func (tq *SQuery) loadPreloads(objs []reflect.Value) error {
+	if len(tq.preloads) == 0 || len(objs) == 0 {
+		return nil
+	}
+	tbl, ok := tq.from.(*STable)
+	if !ok {
+		return errors.Wrap(ErrNotSupported, "preload of a query not from a table")
+	}
+	spec, ok := tbl.spec.(*STableSpec)
+	if !ok {
+		return errors.Wrap(ErrNotSupported, "preload of a query not from a table")
+	}
+	for _, name := range tq.preloads {
+		rel := spec.Relation(name)
+		if rel == nil {
+			return errors.Wrapf(errors.ErrNotFound, "relation %s of %s", name, spec.Name())
+		}
+		err := rel.load(tq, spec, objs)
+		if err != nil {
+			return errors.Wrapf(err, "preload %s", name)
+		}
+	}
+	return nil
+}
+
+// singlePrimaryKey returns the name of the primary key of a table of single primary key
+func singlePrimaryKey(ts *STableSpec) (string, error) {
+	primaries := ts.PrimaryColumns()
+	if len(primaries) != 1 {
+		return "", errors.Wrapf(ErrNotSupported, "table %s of %d primary keys", ts.Name(), len(primaries))
+	}
+	return primaries[0].Name(), nil
+}
+
+// relationKeys returns the distinct non-empty values of the column of objs, and the string values of each obj
+func relationKeys(objs []reflect.Value, column string) ([]interface{}, []string) {
+	keys := make([]interface{}, 0, len(objs))
+	strs := make([]string, len(objs))
+	found := make(map[string]bool)
+	for i := range objs {
+		v, _ := reflectutils.FetchStructFieldValueSet(objs[i]).GetInterface(column)
+		if gotypes.IsNil(v) || reflect.ValueOf(v).IsZero() {
+			continue
+		}
+		strs[i] = GetStringValue(v)
+		if !found[strs[i]] {
+			found[strs[i]] = true
+			keys = append(keys, v)
+		}
+	}
+	return keys, strs
+}
+
+func (rel *SRelation) load(tq *SQuery, ts *STableSpec, objs []reflect.Value) error {
+	var err error
+	localKey, targetKey := rel.ForeignKey, rel.Key
+	switch rel.Type {
+	case RELATION_BELONGS_TO:
+		if len(targetKey) == 0 {
+			targetKey, err = singlePrimaryKey(rel.Target)
+		}
+	case RELATION_HAS_MANY:
+		localKey, targetKey = rel.Key, rel.ForeignKey
+		if len(localKey) == 0 {
+			localKey, err = singlePrimaryKey(ts)
+		}
+	case RELATION_MANY_TO_MANY:
+		localKey, err = singlePrimaryKey(ts)
+		targetKey = relationOriginKeyAlias
+	default:
+		return errors.Wrapf(ErrNotSupported, "relation type %s", rel.Type)
+	}
+	if err != nil {
+		return err
+	}
+
+	keys, strs := relationKeys(objs, localKey)
+	related := make(map[string][]reflect.Value)
+	// chunk the keys so that the number of placeholders of a query does not exceed the limit of the backend
+	chunkSize := rel.Target.Database().backend.MaxInsertPlaceholders()
+	if chunkSize <= 0 {
+		chunkSize = sqlBlockLimit
+	}
+	for start := 0; start < len(keys); start += chunkSize {
+		end := start + chunkSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		var q *SQuery
+		target := rel.Target.Instance()
+		if rel.Type == RELATION_MANY_TO_MANY {
+			relatedPrimary, err := singlePrimaryKey(rel.Target)
+			if err != nil {
+				return err
+			}
+			joint := rel.JointModel.Instance()
+			fields := append(target.Fields(), joint.Field(rel.OriginKey, relationOriginKeyAlias))
+			q = target.Query(fields...).Join(joint, Equals(joint.Field(rel.RelatedKey), target.Field(relatedPrimary)))
+			q = q.Filter(In(joint.Field(rel.OriginKey), keys[start:end]))
+		} else {
+			q = target.Query().Filter(In(target.Field(targetKey), keys[start:end]))
+		}
+		results, err := q.WithContext(tq.Context()).AllStringMap()
+		if err != nil {
+			return errors.Wrap(err, "query related rows")
+		}
+		for _, result := range results {
+			elemPtrValue := reflect.New(rel.Target.DataType())
+			err = mapString2Struct(result, elemPtrValue.Elem())
+			if err != nil {
+				return errors.Wrap(err, "mapString2Struct")
+			}
+			callAfterQuery(elemPtrValue)
+			related[result[targetKey]] = append(related[result[targetKey]], elemPtrValue)
+		}
+	}
+
+	for i := range objs {
+		err = setRelationField(objs[i], rel.Name, related[strs[i]])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setRelationField sets the related rows, pointers to the target structs, to the struct field name of obj
+func setRelationField(obj reflect.Value, name string, rows []reflect.Value) error {
+	field := obj.FieldByName(name)
+	if !field.IsValid() || !field.CanSet() {
+		return errors.Wrapf(errors.ErrNotFound, "field %s of %s", name, obj.Type())
+	}
+	switch field.Kind() {
+	case reflect.Slice:
+		slice := reflect.MakeSlice(field.Type(), 0, len(rows))
+		for _, row := range rows {
+			if field.Type().Elem().Kind() == reflect.Ptr {
+				slice = reflect.Append(slice, row)
+			} else {
+				slice = reflect.Append(slice, row.Elem())
+			}
+		}
+		field.Set(slice)
+	case reflect.Ptr:
+		if len(rows) > 0 {
+			field.Set(rows[0])
+		} else {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	case reflect.Struct:
+		if len(rows) > 0 {
+			field.Set(rows[0].Elem())
+		}
+	default:
+		return errors.Wrapf(ErrNotSupported, "field %s of %s", name, field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,402 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlchemy
+
+import (
+	"database/sql"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nyl1001/pkg/errors"
+	"github.com/nyl1001/pkg/gotypes"
+	"github.com/nyl1001/pkg/tristate"
+	"github.com/nyl1001/pkg/util/reflectutils"
+	"yunion.io/x/log"
+)
+
+var sqlScannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// sStructScanInfo is the cached information of a struct type for scanning rows
+type sStructScanInfo struct {
+	// fields is the field value set of a prototype, used to resolve the column names
+	// with the same rules of reflectutils.SStructFieldValueSet
+	fields reflectutils.SStructFieldValueSet
+	// paths are the index paths of fields
+	paths [][]int
+
+	// nested are the index of the struct fields tagged by alias, which receive the columns of the table alias
+	nested map[string]int
+
+	// columns caches the resolved index of fields of a column name, -1 if not found
+	columns sync.Map
+}
+
+var structScanInfoCache sync.Map
+
+// sScanColumn is the plan of scanning a column into a struct field
+type sScanColumn struct {
+	path []int
+
+	// scanner indicates the pointer of the field implements sql.Scanner
+	scanner bool
+	// plain indicates the field is of a basic kind that could be set directly
+	plain bool
+}
+
+// sScanPlan is the plan of scanning the columns of a query into a struct type
+type sScanPlan struct {
+	dataType reflect.Type
+	names    []string
+	columns  []*sScanColumn
+}
+
+// structScanPaths enumerates the index paths of the fields in the same order of reflectutils.FetchStructFieldValueSet,
+// ok is false if the struct has fields that the scan plan does not support
+func structScanPaths(dataType reflect.Type, prefix []int) ([][]int, bool) {
+	paths := make([][]int, 0, dataType.NumField())
+	for i := 0; i < dataType.NumField(); i++ {
+		sf := dataType.Field(i)
+		if !gotypes.IsFieldExportable(sf.Name) {
+			continue
+		}
+		path := append(append([]int{}, prefix...), i)
+		if sf.Anonymous {
+			ft := sf.Type
+			switch ft.Kind() {
+			case reflect.Interface:
+				continue
+			case reflect.Ptr:
+				ft = ft.Elem()
+				if ft.Kind() != reflect.Struct || ft == gotypes.TimeType {
+					return nil, false
+				}
+			}
+			if ft.Kind() == reflect.Struct && ft != gotypes.TimeType {
+				subpaths, ok := structScanPaths(ft, path)
+				if !ok {
+					return nil, false
+				}
+				paths = append(paths, subpaths...)
+				continue
+			}
+		}
+		if reflectutils.ParseStructFieldJsonInfo(sf).Ignore {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths, true
+}
+
+// getStructScanInfo returns the cached scan information of the struct type, nil if not supported
+func getStructScanInfo(dataType reflect.Type) *sStructScanInfo {
+	if info, ok := structScanInfoCache.Load(dataType); ok {
+		return info.(*sStructScanInfo)
+	}
+	var info *sStructScanInfo
+	paths, ok := structScanPaths(dataType, nil)
+	if ok {
+		fields := reflectutils.FetchStructFieldValueSetForWrite(reflect.New(dataType).Elem())
+		if len(fields) == len(paths) {
+			info = &sStructScanInfo{
+				fields: fields,
+				paths:  paths,
+				nested: structNestedFields(dataType),
+			}
+		}
+	}
+	structScanInfoCache.Store(dataType, info)
+	return info
+}
+
+// structNestedFields returns the index of the struct or struct pointer fields tagged by alias
+func structNestedFields(dataType reflect.Type) map[string]int {
+	var nested map[string]int
+	for i := 0; i < dataType.NumField(); i++ {
+		sf := dataType.Field(i)
+		alias := sf.Tag.Get(TAG_ALIAS)
+		if len(alias) == 0 || !gotypes.IsFieldExportable(sf.Name) {
+			continue
+		}
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Struct || ft == gotypes.TimeType {
+			continue
+		}
+		if nested == nil {
+			nested = make(map[string]int)
+		}
+		nested[alias] = i
+	}
+	return nested
+}
+
+// resolve returns the index path and the type of the field of the column name from the table alias,
+// the columns of a nested alias, labelled by "<alias>.<column>" or selected from the table of the alias,
+// are resolved in the nested struct
+func (info *sStructScanInfo) resolve(dataType reflect.Type, alias, name string) ([]int, reflect.Type) {
+	if len(info.nested) > 0 {
+		if pos := strings.IndexByte(name, '.'); pos > 0 {
+			if _, ok := info.nested[name[:pos]]; ok {
+				alias, name = name[:pos], name[pos+1:]
+			}
+		}
+		if idx, ok := info.nested[alias]; ok {
+			ft := dataType.Field(idx).Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			sub := getStructScanInfo(ft)
+			if sub == nil {
+				return nil, nil
+			}
+			path, fieldType := sub.resolve(ft, "", name)
+			if path == nil {
+				return nil, nil
+			}
+			return append([]int{idx}, path...), fieldType
+		}
+	}
+	idx := info.fieldIndex(name)
+	if idx < 0 {
+		return nil, nil
+	}
+	return info.paths[idx], info.fields[idx].Value.Type()
+}
+
+func (info *sStructScanInfo) fieldIndex(name string) int {
+	if idx, ok := info.columns.Load(name); ok {
+		return idx.(int)
+	}
+	idx := info.fields.GetStructFieldIndex(name)
+	info.columns.Store(name, idx)
+	return idx
+}
+
+// newScanPlan returns the plan of scanning the named columns into the struct type, nil if not supported
+func newScanPlan(dataType reflect.Type, names []string) *sScanPlan {
+	return newNestedScanPlan(dataType, names, nil)
+}
+
+// newNestedScanPlan returns the plan of scanning the named columns from the table aliases into the struct type,
+// whose struct fields tagged by alias receive the columns of the table aliases
+func newNestedScanPlan(dataType reflect.Type, names []string, aliases []string) *sScanPlan {
+	if dataType.Kind() != reflect.Struct {
+		return nil
+	}
+	info := getStructScanInfo(dataType)
+	if info == nil {
+		return nil
+	}
+	plan := &sScanPlan{
+		dataType: dataType,
+		names:    names,
+		columns:  make([]*sScanColumn, len(names)),
+	}
+	for i, name := range names {
+		alias := ""
+		if i < len(aliases) {
+			alias = aliases[i]
+		}
+		path, fieldType := info.resolve(dataType, alias, name)
+		if path == nil {
+			continue
+		}
+		plan.columns[i] = &sScanColumn{
+			path:    path,
+			scanner: reflect.PtrTo(fieldType).Implements(sqlScannerType),
+			plain:   isPlainScanType(fieldType),
+		}
+	}
+	return plan
+}
+
+// isPlainScanType returns whether the values of the type could be set directly from the driver values
+func isPlainScanType(fieldType reflect.Type) bool {
+	if fieldType == gotypes.TimeType {
+		return true
+	}
+	if fieldType == tristate.TriStateType || fieldType.Implements(gotypes.ISerializableType) {
+		return false
+	}
+	switch fieldType.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// fieldByPath returns the field of the index path, allocating the nil embedded pointers
+func fieldByPath(value reflect.Value, path []int) reflect.Value {
+	for i, idx := range path {
+		if i > 0 && value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		value = value.Field(idx)
+	}
+	return value
+}
+
+// scanRow scans a row into destValue, a struct value, the raws are reused across rows
+func (plan *sScanPlan) scanRow(row IRowScanner, destValue reflect.Value, raws []interface{}) error {
+	targets := make([]interface{}, len(plan.columns))
+	for i, col := range plan.columns {
+		if col != nil && col.scanner {
+			targets[i] = fieldByPath(destValue, col.path).Addr().Interface()
+		} else {
+			raws[i] = nil
+			targets[i] = &raws[i]
+		}
+	}
+	if err := row.Scan(targets...); err != nil {
+		return err
+	}
+	var err error
+	for i, col := range plan.columns {
+		if col == nil || col.scanner || raws[i] == nil {
+			continue
+		}
+		if e := col.setValue(fieldByPath(destValue, col.path), raws[i]); e != nil {
+			log.Errorf("Set field %q value error %s", plan.names[i], e)
+			err = e
+		}
+	}
+	return err
+}
+
+// setValue sets the driver value to the field, values that could not be set directly
+// are converted by setValueBySQLString as the string map path does
+func (col *sScanColumn) setValue(field reflect.Value, raw interface{}) error {
+	if col.plain {
+		switch v := raw.(type) {
+		case []byte:
+			if field.Kind() == reflect.String {
+				if len(v) > 0 {
+					field.SetString(string(v))
+				}
+				return nil
+			}
+		case string:
+			if field.Kind() == reflect.String {
+				if len(v) > 0 {
+					field.SetString(v)
+				}
+				return nil
+			}
+		case int64:
+			switch field.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				field.SetInt(v)
+				return nil
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				field.SetUint(uint64(v))
+				return nil
+			case reflect.Float32, reflect.Float64:
+				field.SetFloat(float64(v))
+				return nil
+			case reflect.Bool:
+				field.SetBool(v != 0)
+				return nil
+			}
+		case float64:
+			switch field.Kind() {
+			case reflect.Float32, reflect.Float64:
+				field.SetFloat(v)
+				return nil
+			}
+		case bool:
+			if field.Kind() == reflect.Bool {
+				field.SetBool(v)
+				return nil
+			}
+		case time.Time:
+			if field.Type() == gotypes.TimeType {
+				// the datetime values are stored as UTC
+				field.Set(reflect.ValueOf(time.Date(v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(), v.Nanosecond(), time.UTC)))
+				return nil
+			}
+		}
+	}
+	var str string
+	switch v := raw.(type) {
+	case []byte:
+		str = string(v)
+	case int64:
+		str = strconv.FormatInt(v, 10)
+	default:
+		str = GetStringValue(v)
+	}
+	if len(str) == 0 {
+		return nil
+	}
+	return setValueBySQLString(field, str)
+}
+
+// queryFieldNames returns the names of the query fields
+func (tq *SQuery) queryFieldNames() []string {
+	queryFields := tq.QueryFields()
+	fields := make([]string, len(queryFields))
+	for i, f := range queryFields {
+		fields[i] = f.Name()
+	}
+	return fields
+}
+
+// newScanPlan returns the plan of scanning the query fields into the struct type, nil if not supported
+func (tq *SQuery) newScanPlan(dataType reflect.Type) *sScanPlan {
+	if dataType.Kind() != reflect.Struct {
+		return nil
+	}
+	if info := getStructScanInfo(dataType); info == nil || len(info.nested) == 0 {
+		return newScanPlan(dataType, tq.queryFieldNames())
+	}
+	queryFields := tq.QueryFields()
+	names := make([]string, len(queryFields))
+	aliases := make([]string, len(queryFields))
+	for i, f := range queryFields {
+		names[i] = f.Name()
+		if tf, ok := f.(*STableField); ok && len(tf.alias) == 0 {
+			aliases[i] = tf.table.Alias()
+		}
+	}
+	return newNestedScanPlan(dataType, names, aliases)
+}
+
+// scanRows appends the structs scanned from the rows to arrayValue, a slice of structs
+func (plan *sScanPlan) scanRows(rows *sql.Rows, arrayValue reflect.Value) error {
+	elemType := arrayValue.Type().Elem()
+	raws := make([]interface{}, len(plan.names))
+	for rows.Next() {
+		elemPtrValue := reflect.New(elemType)
+		err := plan.scanRow(rows, elemPtrValue.Elem(), raws)
+		if err != nil {
+			return errors.Wrap(err, "scanRow")
+		}
+		callAfterQuery(elemPtrValue)
+		arrayValue.Set(reflect.Append(arrayValue, elemPtrValue.Elem()))
+	}
+	return rows.Err()
+}
@@ -0,0 +1,144 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlchemy
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/nyl1001/pkg/errors"
+	"github.com/nyl1001/pkg/util/timeutils"
+	"github.com/nyl1001/pkg/utils"
+)
+
+// softDeleteColumn returns the deleted flag column tagged by soft_delete, nil if the table is not soft-deletable
+func (ts *STableSpec) softDeleteColumn() IColumnSpec {
+	for _, c := range ts.Columns() {
+		if utils.ToBool(c.Tags()[TAG_SOFT_DELETE]) {
+			return c
+		}
+	}
+	return nil
+}
+
+// IsSoftDelete returns whether the table is soft-deletable, i.e. a column is tagged by soft_delete
+func (ts *STableSpec) IsSoftDelete() bool {
+	return ts.softDeleteColumn() != nil
+}
+
+// softDeleteSetters returns the values of the columns set by a soft deletion
+func (ts *STableSpec) softDeleteSetters() map[string]interface{} {
+	set := map[string]interface{}{
+		ts.softDeleteColumn().Name(): true,
+	}
+	now := timeutils.UtcNow()
+	for _, c := range ts.Columns() {
+		if utils.ToBool(c.Tags()[TAG_DELETED_AT]) {
+			set[c.Name()] = now
+		}
+	}
+	return set
+}
+
+// Unscoped makes the query include the soft-deleted rows of the queried table
+func (tq *SQuery) Unscoped() *SQuery {
+	tq.unscoped = true
+	return tq
+}
+
+// whereCondition returns the condition of the WHERE clause, which excludes the soft-deleted rows
+// of the queried table unless the query is unscoped. The joined tables are not filtered.
+func (tq *SQuery) whereCondition() ICondition {
+	if tq.unscoped {
+		return tq.where
+	}
+	tbl, ok := tq.from.(*STable)
+	if !ok {
+		return tq.where
+	}
+	spec, ok := tbl.spec.(*STableSpec)
+	if !ok {
+		return tq.where
+	}
+	col := spec.softDeleteColumn()
+	if col == nil {
+		return tq.where
+	}
+	cond := IsFalse(tbl.Field(col.Name()))
+	if tq.where == nil {
+		return cond
+	}
+	return AND(tq.where, cond)
+}
+
+// filtersCondition converts the filters of DeleteFrom to a condition on Target()
+func (ts *STableSpec) filtersCondition(filters map[string]interface{}) ICondition {
+	target := ts.Target()
+	conds := make([]ICondition, 0, len(filters))
+	for k, v := range filters {
+		kind := reflect.TypeOf(v).Kind()
+		if kind == reflect.Slice || kind == reflect.Array {
+			if reflect.ValueOf(v).Len() == 0 {
+				continue
+			}
+			conds = append(conds, In(target.Field(k), v))
+		} else {
+			conds = append(conds, Equals(target.Field(k), v))
+		}
+	}
+	if len(conds) == 0 {
+		return nil
+	}
+	return AND(conds...)
+}
+
+// HardDelete deletes the rows matching the filters physically, even if the table is soft-deletable
+func (ts *STableSpec) HardDelete(filters map[string]interface{}) error {
+	return ts.HardDeleteContext(context.Background(), filters)
+}
+
+// HardDeleteContext is the context-aware version of HardDelete
+func (ts *STableSpec) HardDeleteContext(ctx context.Context, filters map[string]interface{}) error {
+	if err := ts.checkNoHooks("HardDelete"); err != nil {
+		return err
+	}
+	return ts.hardDeleteFrom(ctx, filters)
+}
+
+// HardDeleteWhere deletes the rows matching cond physically, even if the table is soft-deletable
+func (ts *STableSpec) HardDeleteWhere(cond ICondition) (int64, error) {
+	return ts.HardDeleteWhereContext(context.Background(), cond)
+}
+
+// HardDeleteWhereContext is the context-aware version of HardDeleteWhere
+func (ts *STableSpec) HardDeleteWhereContext(ctx context.Context, cond ICondition) (int64, error) {
+	if err := ts.checkNoHooks("HardDeleteWhere"); err != nil {
+		return 0, err
+	}
+	return ts.hardDeleteWhere(ctx, cond)
+}
+
+func (ts *STableSpec) hardDeleteWhere(ctx context.Context, cond ICondition) (int64, error) {
+	sql, vars := ts.DeleteWhereSql(cond)
+	return ts.execWhereSql(ctx, sql, vars)
+}
+
+func (ts *STableSpec) softDeleteWhere(ctx context.Context, cond ICondition) (int64, error) {
+	cnt, err := ts.updateWhere(ctx, cond, ts.softDeleteSetters())
+	if err != nil {
+		return 0, errors.Wrap(err, "soft delete")
+	}
+	return cnt, nil
+}
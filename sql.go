@@ -15,6 +15,7 @@
 package sqlchemy
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/nyl1001/pkg/errors"
@@ -29,6 +30,9 @@ type SDatabase struct {
 	db      *sql.DB
 	name    DBName
 	backend IBackend
+
+	// maxInsertPacketSize overrides MaxInsertPacketSize of the backend if it is positive
+	maxInsertPacketSize int
 }
 
 // DefaultDB is the name for the default database instance
@@ -145,7 +149,33 @@ func Exec(sql string, args ...interface{}) (sql.Result, error) {
 
 // Exec execute a raw SQL query for a db instance
 func (db *SDatabase) Exec(sql string, args ...interface{}) (sql.Result, error) {
-	return db.db.Exec(sql, args...)
+	return db.ExecContext(context.Background(), sql, args...)
+}
+
+// ExecContext execute a raw SQL query for a db instance with the given context,
+// the query is executed within the transaction carried by the context if any
+func (db *SDatabase) ExecContext(ctx context.Context, sql string, args ...interface{}) (sql.Result, error) {
+	sql = db.Rebind(sql)
+	if tx := db.txFromContext(ctx); tx != nil {
+		return tx.tx.ExecContext(ctx, sql, args...)
+	}
+	return db.db.ExecContext(ctx, sql, args...)
+}
+
+func (db *SDatabase) queryContext(ctx context.Context, sqlstr string, args ...interface{}) (*sql.Rows, error) {
+	sqlstr = db.Rebind(sqlstr)
+	if tx := db.txFromContext(ctx); tx != nil {
+		return tx.tx.QueryContext(ctx, sqlstr, args...)
+	}
+	return db.db.QueryContext(ctx, sqlstr, args...)
+}
+
+func (db *SDatabase) queryRowContext(ctx context.Context, sqlstr string, args ...interface{}) *sql.Row {
+	sqlstr = db.Rebind(sqlstr)
+	if tx := db.txFromContext(ctx); tx != nil {
+		return tx.tx.QueryRowContext(ctx, sqlstr, args...)
+	}
+	return db.db.QueryRowContext(ctx, sqlstr, args...)
 }
 
 type SSqlResult struct {
@@ -154,12 +184,38 @@ type SSqlResult struct {
 }
 
 func (db *SDatabase) TxBatchExec(sqlstr string, varsList [][]interface{}) ([]SSqlResult, error) {
-	tx, err := db.db.Begin()
+	return db.TxBatchExecContext(context.Background(), sqlstr, varsList)
+}
+
+// TxBatchExecContext executes a SQL statement with each set of variables in varsList within a single transaction.
+// If the context carries a transaction of the database, the statements are executed within that transaction
+// and the transaction is left to be committed by its owner.
+func (db *SDatabase) TxBatchExecContext(ctx context.Context, sqlstr string, varsList [][]interface{}) ([]SSqlResult, error) {
+	sqlstr = db.Rebind(sqlstr)
+	if stx := db.txFromContext(ctx); stx != nil {
+		return batchExec(ctx, stx.tx, sqlstr, varsList)
+	}
+	tx, err := db.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "Begin transaction")
 	}
 	defer tx.Rollback()
-	stmt, err := tx.Prepare(sqlstr)
+
+	results, err := batchExec(ctx, tx, sqlstr, varsList)
+	if err != nil {
+		return nil, errors.Wrap(err, "batchExec")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.Wrap(err, "Commit transaction")
+	}
+
+	return results, nil
+}
+
+func batchExec(ctx context.Context, tx *sql.Tx, sqlstr string, varsList [][]interface{}) ([]SSqlResult, error) {
+	stmt, err := tx.PrepareContext(ctx, sqlstr)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Prepare sql %s", sqlstr)
 	}
@@ -168,23 +224,22 @@ func (db *SDatabase) TxBatchExec(sqlstr string, varsList [][]interface{}) ([]SSq
 	results := make([]SSqlResult, len(varsList))
 	for i := range varsList {
 		vars := varsList[i]
-		result, err := stmt.Exec(vars...)
+		result, err := stmt.ExecContext(ctx, vars...)
 		results[i] = SSqlResult{
 			Result: result,
 			Error:  err,
 		}
 	}
-
-	err = tx.Commit()
-	if err != nil {
-		return nil, errors.Wrap(err, "Commit transaction")
-	}
-
 	return results, nil
 }
 
 func (db *SDatabase) TxExec(sqlstr string, vars ...interface{}) (sql.Result, error) {
-	results, err := db.TxBatchExec(sqlstr, [][]interface{}{
+	return db.TxExecContext(context.Background(), sqlstr, vars...)
+}
+
+// TxExecContext executes a SQL statement within a transaction with the given context
+func (db *SDatabase) TxExecContext(ctx context.Context, sqlstr string, vars ...interface{}) (sql.Result, error) {
+	results, err := db.TxBatchExecContext(ctx, sqlstr, [][]interface{}{
 		vars,
 	})
 	if err != nil {
@@ -196,3 +251,18 @@ func (db *SDatabase) TxExec(sqlstr string, vars ...interface{}) (sql.Result, err
 func (db *SDatabase) DB() *sql.DB {
 	return db.db
 }
+
+// SetMaxInsertPacketSize sets the max size in bytes of a multi-row INSERT statement of the database,
+// which overrides MaxInsertPacketSize of the backend, e.g. the max_allowed_packet configured on a MySQL server.
+// A non-positive size restores the default of the backend
+func (db *SDatabase) SetMaxInsertPacketSize(size int) {
+	db.maxInsertPacketSize = size
+}
+
+// MaxInsertPacketSize returns the max size in bytes of a multi-row INSERT statement of the database, 0 means unlimited
+func (db *SDatabase) MaxInsertPacketSize() int {
+	if db.maxInsertPacketSize > 0 {
+		return db.maxInsertPacketSize
+	}
+	return db.backend.MaxInsertPacketSize()
+}
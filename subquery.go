@@ -28,10 +28,11 @@ type SSubQueryField struct {
 
 // Expression implementation of SSubQueryField for IQueryField
 func (sqf *SSubQueryField) Expression() string {
+	db := sqf.database()
 	if len(sqf.alias) > 0 {
-		return fmt.Sprintf("`%s`.`%s` AS `%s`", sqf.query.alias, sqf.field.Name(), sqf.alias)
+		return fmt.Sprintf("%s.%s AS %s", quoteIdentifier(db, sqf.query.alias), quoteIdentifier(db, sqf.field.Name()), quoteIdentifier(db, sqf.alias))
 	}
-	return fmt.Sprintf("`%s`.`%s`", sqf.query.alias, sqf.field.Name())
+	return fmt.Sprintf("%s.%s", quoteIdentifier(db, sqf.query.alias), quoteIdentifier(db, sqf.field.Name()))
 }
 
 // Name implementation of SSubQueryField for IQueryField
@@ -44,7 +45,8 @@ func (sqf *SSubQueryField) Name() string {
 
 // Reference implementation of SSubQueryField for IQueryField
 func (sqf *SSubQueryField) Reference() string {
-	return fmt.Sprintf("`%s`.`%s`", sqf.query.alias, sqf.Name())
+	db := sqf.database()
+	return fmt.Sprintf("%s.%s", quoteIdentifier(db, sqf.query.alias), quoteIdentifier(db, sqf.Name()))
 }
 
 // Label implementation of SSubQueryField for IQueryField
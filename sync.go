@@ -134,7 +134,7 @@ func (ts *STableSpec) DropForeignKeySQL() []string {
 		}
 
 		for _, constraint := range constraints {
-			sql := fmt.Sprintf("ALTER TABLE `%s` DROP FOREIGN KEY `%s`", ts.name, constraint.name)
+			sql := fmt.Sprintf("ALTER TABLE %s DROP FOREIGN KEY %s", ts.quoteIdentifier(ts.name), ts.quoteIdentifier(constraint.name))
 			ret = append(ret, sql)
 			log.Infof("%s;", sql)
 		}
@@ -15,6 +15,7 @@
 package sqlchemy
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"sort"
@@ -93,7 +94,29 @@ type ITableSpec interface {
 	SetExtraOptions(opts TableExtraOptions)
 }
 
-// STableSpec defines the table specification, which implements ITableSpec
+// ITableSpecContext is the interface of the context-aware operations of a table, which is
+// separated from ITableSpec to keep the existing implementations of ITableSpec valid
+type ITableSpecContext interface {
+	// InsertContext is the context-aware version of Insert
+	InsertContext(ctx context.Context, dt interface{}) error
+
+	// InsertOrUpdateContext is the context-aware version of InsertOrUpdate
+	InsertOrUpdateContext(ctx context.Context, dt interface{}) error
+
+	// UpdateContext is the context-aware version of Update
+	UpdateContext(ctx context.Context, dt interface{}, onUpdate func() error) (UpdateDiffs, error)
+
+	// IncrementContext is the context-aware version of Increment
+	IncrementContext(ctx context.Context, diff, target interface{}) error
+
+	// DecrementContext is the context-aware version of Decrement
+	DecrementContext(ctx context.Context, diff, target interface{}) error
+
+	// FetchContext is the context-aware version of Fetch
+	FetchContext(ctx context.Context, dt interface{}) error
+}
+
+// STableSpec defines the table specification, which implements ITableSpec and ITableSpecContext
 type STableSpec struct {
 	structType  reflect.Type
 	name        string
@@ -103,6 +126,12 @@ type STableSpec struct {
 
 	extraOptions TableExtraOptions
 
+	// hooks are called on Insert, Update and Delete of the records, see AddHook
+	hooks []ITableHook
+
+	// relations are the relations to other tables, by the name of the struct field
+	relations map[string]*SRelation
+
 	sDBReferer
 }
 
@@ -157,9 +186,14 @@ func (ts *STableSpec) Name() string {
 	return ts.name
 }
 
+// quoteIdentifier quotes the name of a table or column with the quoter of the backend of the table
+func (ts *STableSpec) quoteIdentifier(name string) string {
+	return quoteIdentifier(ts.Database(), name)
+}
+
 // Expression implementation of STableSpec for ITableSpec
 func (ts *STableSpec) Expression() string {
-	return fmt.Sprintf("`%s`", ts.name)
+	return ts.quoteIdentifier(ts.name)
 }
 
 func (ts *STableSpec) SyncColumnIndexes() error {
@@ -238,6 +272,8 @@ func (ts *STableSpec) CloneWithSyncColumnOrder(name string, autoIncOffset int64,
 		_columns:    newCols,
 		_contraints: ts._contraints,
 		sDBReferer:  ts.sDBReferer,
+		hooks:       ts.hooks,
+		relations:   ts.relations,
 	}
 	newIndexes := make([]STableIndex, len(ts._indexes))
 	for i := range ts._indexes {
@@ -294,6 +330,18 @@ func (ts *STableSpec) Instance() *STable {
 	return NewTableInstance(ts)
 }
 
+// InstanceWithAlias return an new table instance of the alias, which is the alias tag
+// of the nested struct receiving its columns when scanning the rows of a joined query
+func (ts *STableSpec) InstanceWithAlias(alias string) *STable {
+	return &STable{spec: ts, alias: alias}
+}
+
+// Target returns the table instance referenced by the name of the table, which is the target of
+// UpdateWhere and DeleteWhere, the fields in their conditions and SET expressions should come from it
+func (ts *STableSpec) Target() *STable {
+	return &STable{spec: ts, alias: ts.name}
+}
+
 // ColumnSpec implementation of STableSpec for ITableSpec
 func (ts *STableSpec) ColumnSpec(name string) IColumnSpec {
 	for _, c := range ts.Columns() {
@@ -351,10 +399,11 @@ func (tbl *STable) Variables() []interface{} {
 
 // Expression implementation of STableField for IQueryField
 func (c *STableField) Expression() string {
+	db := c.database()
 	if len(c.alias) > 0 {
-		return fmt.Sprintf("`%s`.`%s` as `%s`", c.table.Alias(), c.spec.Name(), c.alias)
+		return fmt.Sprintf("%s.%s as %s", quoteIdentifier(db, c.table.Alias()), quoteIdentifier(db, c.spec.Name()), quoteIdentifier(db, c.alias))
 	}
-	return fmt.Sprintf("`%s`.`%s`", c.table.Alias(), c.spec.Name())
+	return fmt.Sprintf("%s.%s", quoteIdentifier(db, c.table.Alias()), quoteIdentifier(db, c.spec.Name()))
 }
 
 // Name implementation of STableField for IQueryField
@@ -367,7 +416,8 @@ func (c *STableField) Name() string {
 
 // Reference implementation of STableField for IQueryField
 func (c *STableField) Reference() string {
-	return fmt.Sprintf("`%s`.`%s`", c.table.Alias(), c.Name())
+	db := c.database()
+	return fmt.Sprintf("%s.%s", quoteIdentifier(db, c.table.Alias()), quoteIdentifier(db, c.Name()))
 }
 
 // Label implementation of STableField for IQueryField
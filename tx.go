@@ -0,0 +1,200 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlchemy
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/nyl1001/pkg/errors"
+	"yunion.io/x/log"
+)
+
+// sTxContextKey is the key of the context value that carries the transaction of a database
+type sTxContextKey struct {
+	db *SDatabase
+}
+
+// STx represents a database transaction, or a savepoint nested in a transaction
+//
+// All operations that take the context returned by STx.Context(), e.g.
+// STableSpec.InsertContext or SQuery.WithContext, are executed within the transaction
+type STx struct {
+	db  *SDatabase
+	tx  *sql.Tx
+	ctx context.Context
+
+	// savepoint is the name of the savepoint, empty for the outermost transaction
+	savepoint string
+	// depth is the nesting level of the transaction, 0 for the outermost transaction
+	depth int
+
+	done bool
+}
+
+// Begin starts a transaction on the database
+func (db *SDatabase) Begin() (*STx, error) {
+	return db.BeginContext(context.Background())
+}
+
+// BeginContext starts a transaction on the database with the given context.
+// If the context already carries a transaction of the database, a savepoint
+// nested in that transaction is created instead.
+func (db *SDatabase) BeginContext(ctx context.Context) (*STx, error) {
+	if parent := db.txFromContext(ctx); parent != nil {
+		return parent.begin()
+	}
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "BeginTx")
+	}
+	stx := &STx{
+		db: db,
+		tx: tx,
+	}
+	stx.ctx = context.WithValue(ctx, sTxContextKey{db: db}, stx)
+	return stx, nil
+}
+
+// RunInTx executes fn within a transaction of the database. The transaction is
+// committed if fn returns nil, otherwise it is rolled back. If fn panics, the
+// transaction is rolled back and the panic is propagated.
+func (db *SDatabase) RunInTx(fn func(tx *STx) error) error {
+	return db.RunInTxContext(context.Background(), fn)
+}
+
+// RunInTxContext is the context-aware version of RunInTx. If the context already
+// carries a transaction of the database, fn is executed within a savepoint
+// of that transaction.
+func (db *SDatabase) RunInTxContext(ctx context.Context, fn func(tx *STx) error) (err error) {
+	tx, err := db.BeginContext(ctx)
+	if err != nil {
+		return errors.Wrap(err, "BeginContext")
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if rerr := tx.Rollback(); rerr != nil {
+				log.Errorf("rollback on panic fail: %s", rerr)
+			}
+			panic(r)
+		}
+	}()
+	err = fn(tx)
+	if err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			log.Errorf("rollback fail: %s", rerr)
+		}
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "Commit")
+	}
+	return nil
+}
+
+// txFromContext returns the transaction of the database carried by the context
+func (db *SDatabase) txFromContext(ctx context.Context) *STx {
+	if ctx == nil {
+		return nil
+	}
+	if tx, ok := ctx.Value(sTxContextKey{db: db}).(*STx); ok {
+		return tx
+	}
+	return nil
+}
+
+// TxFromContext returns the transaction of the database carried by the context, nil if none
+func (db *SDatabase) TxFromContext(ctx context.Context) *STx {
+	return db.txFromContext(ctx)
+}
+
+func (tx *STx) begin() (*STx, error) {
+	savepoint := fmt.Sprintf("sqlchemy_sp_%d", tx.depth+1)
+	_, err := tx.tx.ExecContext(tx.ctx, "SAVEPOINT "+savepoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "create savepoint %s", savepoint)
+	}
+	stx := &STx{
+		db:        tx.db,
+		tx:        tx.tx,
+		savepoint: savepoint,
+		depth:     tx.depth + 1,
+	}
+	stx.ctx = context.WithValue(tx.ctx, sTxContextKey{db: tx.db}, stx)
+	return stx, nil
+}
+
+// Context returns the context that binds operations to the transaction
+func (tx *STx) Context() context.Context {
+	return tx.ctx
+}
+
+// Database returns the database of the transaction
+func (tx *STx) Database() *SDatabase {
+	return tx.db
+}
+
+// Tx returns the underlying sql.Tx
+func (tx *STx) Tx() *sql.Tx {
+	return tx.tx
+}
+
+// IsNested returns whether the transaction is a savepoint nested in another transaction
+func (tx *STx) IsNested() bool {
+	return len(tx.savepoint) > 0
+}
+
+// Exec executes a raw SQL statement within the transaction
+func (tx *STx) Exec(sqlstr string, args ...interface{}) (sql.Result, error) {
+	return tx.db.ExecContext(tx.ctx, sqlstr, args...)
+}
+
+// Commit commits the transaction, or releases the savepoint for a nested transaction
+func (tx *STx) Commit() error {
+	if tx.done {
+		return sql.ErrTxDone
+	}
+	tx.done = true
+	if tx.IsNested() {
+		_, err := tx.tx.ExecContext(tx.ctx, "RELEASE SAVEPOINT "+tx.savepoint)
+		if err != nil {
+			return errors.Wrapf(err, "release savepoint %s", tx.savepoint)
+		}
+		return nil
+	}
+	return tx.tx.Commit()
+}
+
+// Rollback aborts the transaction, or rolls back to the savepoint for a nested transaction
+func (tx *STx) Rollback() error {
+	if tx.done {
+		return sql.ErrTxDone
+	}
+	tx.done = true
+	if tx.IsNested() {
+		_, err := tx.tx.ExecContext(tx.ctx, "ROLLBACK TO SAVEPOINT "+tx.savepoint)
+		if err != nil {
+			return errors.Wrapf(err, "rollback to savepoint %s", tx.savepoint)
+		}
+		_, err = tx.tx.ExecContext(tx.ctx, "RELEASE SAVEPOINT "+tx.savepoint)
+		if err != nil {
+			return errors.Wrapf(err, "release savepoint %s", tx.savepoint)
+		}
+		return nil
+	}
+	return tx.tx.Rollback()
+}
@@ -32,10 +32,11 @@ type SUnionQueryField struct {
 
 // Expression implementation of SUnionQueryField for IQueryField
 func (sqf *SUnionQueryField) Expression() string {
+	db := sqf.database()
 	if len(sqf.alias) > 0 {
-		return fmt.Sprintf("`%s`.`%s` as `%s`", sqf.union.Alias(), sqf.name, sqf.alias)
+		return fmt.Sprintf("%s.%s as %s", quoteIdentifier(db, sqf.union.Alias()), quoteIdentifier(db, sqf.name), quoteIdentifier(db, sqf.alias))
 	}
-	return fmt.Sprintf("`%s`.`%s`", sqf.union.Alias(), sqf.name)
+	return fmt.Sprintf("%s.%s", quoteIdentifier(db, sqf.union.Alias()), quoteIdentifier(db, sqf.name))
 }
 
 // Name implementation of SUnionQueryField for IQueryField
@@ -48,7 +49,8 @@ func (sqf *SUnionQueryField) Name() string {
 
 // Reference implementation of SUnionQueryField for IQueryField
 func (sqf *SUnionQueryField) Reference() string {
-	return fmt.Sprintf("`%s`.`%s`", sqf.union.Alias(), sqf.Name())
+	db := sqf.database()
+	return fmt.Sprintf("%s.%s", quoteIdentifier(db, sqf.union.Alias()), quoteIdentifier(db, sqf.Name()))
 }
 
 // Label implementation of SUnionQueryField for IQueryField
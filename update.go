@@ -15,6 +15,7 @@
 package sqlchemy
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"strings"
@@ -113,6 +114,21 @@ type SUpdateSQLResult struct {
 	Vars      []interface{}
 	setters   []SUpdateDiff
 	primaries []sPrimaryKeyValue
+
+	// versionChecked indicates the old auto_version values are part of the conditions
+	versionChecked bool
+}
+
+// isOptimisticLock returns whether the auto_version column c is checked on update,
+// either by the optimistic_lock tag of the column or by the table extra option
+func (ts *STableSpec) isOptimisticLock(c IColumnSpec) bool {
+	if !c.IsAutoVersion() {
+		return false
+	}
+	if v, ok := c.Tags()[TAG_OPTIMISTIC_LOCK]; ok {
+		return utils.ToBool(v)
+	}
+	return utils.ToBool(ts.extraOptions.Get(EXTRA_OPTION_OPTIMISTIC_LOCK_KEY))
 }
 
 func (us *SUpdateSession) SaveUpdateSql(dt interface{}) (*SUpdateSQLResult, error) {
@@ -127,6 +143,7 @@ func (us *SUpdateSession) SaveUpdateSql(dt interface{}) (*SUpdateSQLResult, erro
 	fields := reflectutils.FetchStructFieldValueSet(dataValue)
 
 	versionFields := make([]string, 0)
+	versionChecks := make([]sPrimaryKeyValue, 0)
 	updatedFields := make([]string, 0)
 	primaries := make([]sPrimaryKeyValue, 0)
 	setters := make([]SUpdateDiff, 0)
@@ -159,6 +176,12 @@ func (us *SUpdateSession) SaveUpdateSql(dt interface{}) (*SUpdateSQLResult, erro
 		}
 		if c.IsAutoVersion() {
 			versionFields = append(versionFields, k)
+			if us.tableSpec.isOptimisticLock(c) {
+				versionChecks = append(versionChecks, sPrimaryKeyValue{
+					key:   k,
+					value: of,
+				})
+			}
 			continue
 		}
 		if c.IsUpdatedAt() {
@@ -194,29 +217,37 @@ func (us *SUpdateSession) SaveUpdateSql(dt interface{}) (*SUpdateSQLResult, erro
 	conditions := make([]string, 0)
 	for _, udif := range setters {
 		if gotypes.IsNil(udif.new) {
-			colsets = append(colsets, fmt.Sprintf("`%s` = NULL", udif.col.Name()))
+			colsets = append(colsets, fmt.Sprintf("%s = NULL", us.tableSpec.quoteIdentifier(udif.col.Name())))
 		} else {
-			colsets = append(colsets, fmt.Sprintf("`%s` = ?", udif.col.Name()))
+			colsets = append(colsets, fmt.Sprintf("%s = ?", us.tableSpec.quoteIdentifier(udif.col.Name())))
 			vars = append(vars, udif.col.ConvertFromValue(udif.new))
 		}
 	}
 	for _, versionField := range versionFields {
-		colsets = append(colsets, fmt.Sprintf("`%s` = `%s` + 1", versionField, versionField))
+		colsets = append(colsets, fmt.Sprintf("%s = %s + 1", us.tableSpec.quoteIdentifier(versionField), us.tableSpec.quoteIdentifier(versionField)))
 	}
 	for _, updatedField := range updatedFields {
-		colsets = append(colsets, fmt.Sprintf("`%s` = %s", updatedField, us.tableSpec.Database().backend.CurrentUTCTimeStampString()))
+		colsets = append(colsets, fmt.Sprintf("%s = %s", us.tableSpec.quoteIdentifier(updatedField), us.tableSpec.Database().backend.CurrentUTCTimeStampString()))
 	}
 	for _, pkv := range primaries {
-		conditions = append(conditions, fmt.Sprintf("`%s` = ?", pkv.key))
+		conditions = append(conditions, fmt.Sprintf("%s = ?", us.tableSpec.quoteIdentifier(pkv.key)))
 		vars = append(vars, pkv.value)
 	}
+	for _, vkv := range versionChecks {
+		if gotypes.IsNil(vkv.value) {
+			conditions = append(conditions, fmt.Sprintf("%s IS NULL", us.tableSpec.quoteIdentifier(vkv.key)))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("%s = ?", us.tableSpec.quoteIdentifier(vkv.key)))
+			vars = append(vars, vkv.value)
+		}
+	}
 
 	updateSql := templateEval(us.tableSpec.Database().backend.UpdateSQLTemplate(), struct {
 		Table      string
 		Columns    string
 		Conditions string
 	}{
-		Table:      us.tableSpec.name,
+		Table:      us.tableSpec.quoteIdentifier(us.tableSpec.name),
 		Columns:    strings.Join(colsets, ", "),
 		Conditions: strings.Join(conditions, " AND "),
 	})
@@ -230,16 +261,18 @@ func (us *SUpdateSession) SaveUpdateSql(dt interface{}) (*SUpdateSQLResult, erro
 		Vars:      vars,
 		setters:   setters,
 		primaries: primaries,
+
+		versionChecked: len(versionChecks) > 0,
 	}, nil
 }
 
-func (us *SUpdateSession) saveUpdate(dt interface{}) (UpdateDiffs, error) {
+func (us *SUpdateSession) saveUpdate(ctx context.Context, dt interface{}) (UpdateDiffs, error) {
 	sqlResult, err := us.SaveUpdateSql(dt)
 	if err != nil {
 		return nil, errors.Wrap(err, "saveUpateSql")
 	}
 
-	err = us.tableSpec.execUpdateSql(dt, sqlResult)
+	err = us.tableSpec.execUpdateSql(ctx, dt, sqlResult)
 	if err != nil {
 		return nil, errors.Wrap(err, "execUpdateSql")
 	}
@@ -247,8 +280,8 @@ func (us *SUpdateSession) saveUpdate(dt interface{}) (UpdateDiffs, error) {
 	return updateDiffList2Map(sqlResult.setters), nil
 }
 
-func (ts *STableSpec) execUpdateSql(dt interface{}, result *SUpdateSQLResult) error {
-	results, err := ts.Database().TxExec(result.Sql, result.Vars...)
+func (ts *STableSpec) execUpdateSql(ctx context.Context, dt interface{}, result *SUpdateSQLResult) error {
+	results, err := ts.Database().TxExecContext(ctx, result.Sql, result.Vars...)
 	if err != nil {
 		return errors.Wrap(err, "TxExec")
 	}
@@ -261,8 +294,11 @@ func (ts *STableSpec) execUpdateSql(dt interface{}, result *SUpdateSQLResult) er
 		if aCnt > 1 {
 			return errors.Wrapf(ErrUnexpectRowCount, "affected rows %d != 1", aCnt)
 		}
+		if aCnt == 0 && result.versionChecked {
+			return errors.Wrap(ErrConcurrentModification, "version mismatch")
+		}
 	}
-	q := ts.Query()
+	q := ts.Query().Unscoped().WithContext(ctx)
 	for _, pkv := range result.primaries {
 		q = q.Equals(pkv.key, pkv.value)
 	}
@@ -277,6 +313,11 @@ func (ts *STableSpec) execUpdateSql(dt interface{}, result *SUpdateSQLResult) er
 // dt is the point to the struct storing the record
 // doUpdate provides method to update the field of the record
 func (ts *STableSpec) Update(dt interface{}, doUpdate func() error) (UpdateDiffs, error) {
+	return ts.UpdateContext(context.Background(), dt, doUpdate)
+}
+
+// UpdateContext is the context-aware version of Update
+func (ts *STableSpec) UpdateContext(ctx context.Context, dt interface{}, doUpdate func() error) (UpdateDiffs, error) {
 	if !ts.Database().backend.CanUpdate() {
 		return nil, errors.ErrNotSupported
 	}
@@ -288,13 +329,23 @@ func (ts *STableSpec) Update(dt interface{}, doUpdate func() error) (UpdateDiffs
 	if err != nil {
 		return nil, errors.Wrap(err, "")
 	}
-	uds, err := session.saveUpdate(dt)
-	if err != nil && errors.Cause(err) == ErrNoDataToUpdate {
-		return nil, nil
-	} else if err == nil {
+	var uds UpdateDiffs
+	err = ts.runWithHooks(ctx, dt, func(ctx context.Context) error {
+		err := ts.callBeforeUpdateHooks(ctx, dt)
+		if err != nil {
+			return err
+		}
+		uds, err = session.saveUpdate(ctx, dt)
+		if err != nil {
+			return err
+		}
 		if DEBUG_SQLCHEMY {
 			log.Debugf("Update diff: %s", uds)
 		}
+		return ts.callAfterUpdateHooks(ctx, dt, uds)
+	})
+	if err != nil && errors.Cause(err) == ErrNoDataToUpdate {
+		return nil, nil
 	}
 	return uds, errors.Wrap(err, "saveUpdate")
 }
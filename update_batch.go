@@ -15,13 +15,21 @@
 package sqlchemy
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strings"
 
+	"github.com/nyl1001/pkg/errors"
 	"yunion.io/x/log"
 )
 
 func (ts *STableSpec) UpdateBatch(data map[string]interface{}, filter map[string]interface{}) error {
+	return ts.UpdateBatchContext(context.Background(), data, filter)
+}
+
+// UpdateBatchContext is the context-aware version of UpdateBatch
+func (ts *STableSpec) UpdateBatchContext(ctx context.Context, data map[string]interface{}, filter map[string]interface{}) error {
 	if len(data) <= 0 {
 		return nil
 	}
@@ -29,17 +37,17 @@ func (ts *STableSpec) UpdateBatch(data map[string]interface{}, filter map[string
 	params := make([]interface{}, 0, len(data))
 	setter := make([]string, 0, len(data))
 	for k, v := range data {
-		setter = append(setter, fmt.Sprintf("`%s` = ?", k))
+		setter = append(setter, fmt.Sprintf("%s = ?", ts.quoteIdentifier(k)))
 		params = append(params, v)
 	}
-	conds, condparams := getSQLFilters(filter)
+	conds, condparams := ts.getSQLFilters(filter)
 	params = append(params, condparams...)
 
 	buf := strings.Builder{}
 
-	buf.WriteString("UPDATE `")
-	buf.WriteString(ts.Name())
-	buf.WriteString("` SET ")
+	buf.WriteString("UPDATE ")
+	buf.WriteString(ts.quoteIdentifier(ts.Name()))
+	buf.WriteString(" SET ")
 	buf.WriteString(strings.Join(setter, ", "))
 
 	if len(conds) > 0 {
@@ -51,6 +59,119 @@ func (ts *STableSpec) UpdateBatch(data map[string]interface{}, filter map[string
 		log.Infof("Update: %s %s", buf.String(), params)
 	}
 
-	_, err := ts.Database().Exec(buf.String(), params...)
+	_, err := ts.Database().ExecContext(ctx, buf.String(), params...)
 	return err
 }
+
+// UpdateWhere updates the rows matching cond with the values of set, whose key is the column name and
+// value is either a value or an IQueryField, e.g. MUL("", ts.Target().Field("count"), NewConstField(2)).
+// The fields in cond and set should come from ts.Target(). The auto_version columns are incremented and
+// the updated_at columns are refreshed. It returns the number of affected rows, which is always 0 if the
+// backend does not support RowsAffected, e.g. the mutations of ClickHouse.
+func (ts *STableSpec) UpdateWhere(cond ICondition, set map[string]interface{}) (int64, error) {
+	return ts.UpdateWhereContext(context.Background(), cond, set)
+}
+
+// UpdateWhereContext is the context-aware version of UpdateWhere
+func (ts *STableSpec) UpdateWhereContext(ctx context.Context, cond ICondition, set map[string]interface{}) (int64, error) {
+	if err := ts.checkNoHooks("UpdateWhere"); err != nil {
+		return 0, err
+	}
+	return ts.updateWhere(ctx, cond, set)
+}
+
+func (ts *STableSpec) updateWhere(ctx context.Context, cond ICondition, set map[string]interface{}) (int64, error) {
+	if !ts.Database().backend.CanUpdate() {
+		return 0, errors.ErrNotSupported
+	}
+	result, err := ts.UpdateWhereSql(cond, set)
+	if err != nil {
+		return 0, errors.Wrap(err, "UpdateWhereSql")
+	}
+	return ts.execWhereSql(ctx, result.Sql, result.Vars)
+}
+
+// UpdateWhereSql returns the SQL of UpdateWhere
+func (ts *STableSpec) UpdateWhereSql(cond ICondition, set map[string]interface{}) (*SUpdateSQLResult, error) {
+	if len(set) == 0 {
+		return nil, ErrNoDataToUpdate
+	}
+	names := make([]string, 0, len(set))
+	for k := range set {
+		if ts.ColumnSpec(k) == nil {
+			return nil, errors.Wrapf(errors.ErrNotFound, "column %s", k)
+		}
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	vars := make([]interface{}, 0)
+	colsets := make([]string, 0)
+	for _, k := range names {
+		switch v := set[k].(type) {
+		case IQueryField:
+			colsets = append(colsets, fmt.Sprintf("%s = %s", ts.quoteIdentifier(k), v.Reference()))
+			vars = append(vars, v.Variables()...)
+		case nil:
+			colsets = append(colsets, fmt.Sprintf("%s = NULL", ts.quoteIdentifier(k)))
+		default:
+			colsets = append(colsets, fmt.Sprintf("%s = ?", ts.quoteIdentifier(k)))
+			vars = append(vars, ts.ColumnSpec(k).ConvertFromValue(v))
+		}
+	}
+	for _, col := range ts.Columns() {
+		if _, ok := set[col.Name()]; ok || col.IsPrimary() {
+			continue
+		}
+		if col.IsAutoVersion() {
+			colsets = append(colsets, fmt.Sprintf("%s = %s + 1", ts.quoteIdentifier(col.Name()), ts.quoteIdentifier(col.Name())))
+		} else if col.IsUpdatedAt() {
+			colsets = append(colsets, fmt.Sprintf("%s = %s", ts.quoteIdentifier(col.Name()), ts.Database().backend.CurrentUTCTimeStampString()))
+		}
+	}
+
+	conditions, condVars := ts.whereSqlConditions(cond)
+	vars = append(vars, condVars...)
+
+	updateSql := templateEval(ts.Database().backend.UpdateSQLTemplate(), struct {
+		Table      string
+		Columns    string
+		Conditions string
+	}{
+		Table:      ts.quoteIdentifier(ts.name),
+		Columns:    strings.Join(colsets, ", "),
+		Conditions: conditions,
+	})
+
+	if DEBUG_SQLCHEMY {
+		log.Infof("Update: %s %s", updateSql, vars)
+	}
+
+	return &SUpdateSQLResult{
+		Sql:  updateSql,
+		Vars: vars,
+	}, nil
+}
+
+// whereSqlConditions returns the conditions and variables of the WHERE clause of UpdateWhere and DeleteWhere
+func (ts *STableSpec) whereSqlConditions(cond ICondition) (string, []interface{}) {
+	if cond == nil {
+		cond = &STrueCondition{db: ts.Database()}
+	}
+	return cond.WhereClause(), cond.Variables()
+}
+
+func (ts *STableSpec) execWhereSql(ctx context.Context, sql string, vars []interface{}) (int64, error) {
+	results, err := ts.Database().TxExecContext(ctx, sql, vars...)
+	if err != nil {
+		return 0, errors.Wrap(err, "TxExec")
+	}
+	if !ts.Database().backend.CanSupportRowAffected() {
+		return 0, nil
+	}
+	aCnt, err := results.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, "results.RowsAffected")
+	}
+	return aCnt, nil
+}
@@ -0,0 +1,205 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlchemy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nyl1001/pkg/errors"
+	"yunion.io/x/log"
+)
+
+// UpdateJoinStyle is the syntax of an UPDATE statement whose values come from joined tables
+type UpdateJoinStyle string
+
+const (
+	// UPDATE_JOIN_NOT_SUPPORTED means the backend cannot update with joined tables
+	UPDATE_JOIN_NOT_SUPPORTED UpdateJoinStyle = ""
+	// UPDATE_JOIN_ON represents UPDATE a JOIN b ON ... SET ... WHERE ...
+	UPDATE_JOIN_ON UpdateJoinStyle = "JOIN"
+	// UPDATE_FROM represents UPDATE a SET ... FROM b WHERE ...
+	UPDATE_FROM UpdateJoinStyle = "FROM"
+)
+
+type sUpdateJoinSetter struct {
+	name  string
+	value interface{}
+}
+
+// SUpdateJoin is an UPDATE statement of a table whose values come from the joined tables,
+// the fields of the updated table should come from its Target()
+type SUpdateJoin struct {
+	table *STableSpec
+	joins []sQueryJoin
+	sets  []sUpdateJoinSetter
+	where ICondition
+}
+
+// UpdateJoin returns an UPDATE statement of the table whose values come from joined tables,
+// which requires MySQL, PostgreSQL or SQLite 3.33+, otherwise the statement fails with ErrNotSupported
+func (ts *STableSpec) UpdateJoin() *SUpdateJoin {
+	return &SUpdateJoin{table: ts}
+}
+
+// Join joins a table or subquery on the condition
+func (uj *SUpdateJoin) Join(from IQuerySource, on ICondition) *SUpdateJoin {
+	uj.joins = append(uj.joins, sQueryJoin{jointype: INNERJOIN, from: from, condition: on})
+	return uj
+}
+
+// Set sets the column of the updated table to a value or an IQueryField, e.g. a field of a joined table
+func (uj *SUpdateJoin) Set(name string, value interface{}) *SUpdateJoin {
+	uj.sets = append(uj.sets, sUpdateJoinSetter{name: name, value: value})
+	return uj
+}
+
+// Filter adds a condition to the rows to update, conditions are combined with AND
+func (uj *SUpdateJoin) Filter(cond ICondition) *SUpdateJoin {
+	if uj.where == nil {
+		uj.where = cond
+	} else {
+		uj.where = AND(uj.where, cond)
+	}
+	return uj
+}
+
+// Exec executes the statement and returns the number of affected rows
+func (uj *SUpdateJoin) Exec() (int64, error) {
+	return uj.ExecContext(context.Background())
+}
+
+// ExecContext is the context-aware version of Exec
+func (uj *SUpdateJoin) ExecContext(ctx context.Context) (int64, error) {
+	if err := uj.table.checkNoHooks("UpdateJoin"); err != nil {
+		return 0, err
+	}
+	sql, vars, err := uj.Sql()
+	if err != nil {
+		return 0, errors.Wrap(err, "Sql")
+	}
+	return uj.table.execWhereSql(ctx, sql, vars)
+}
+
+// Sql returns the SQL and variables of the statement
+//
+//	MySQL: UPDATE a JOIN b AS t1 ON ... SET a.col = t1.col WHERE ...
+//	Sqlite(3.33+), PostgreSQL: UPDATE a SET col = t1.col FROM b AS t1 WHERE ...
+func (uj *SUpdateJoin) Sql() (string, []interface{}, error) {
+	ts := uj.table
+	backend := ts.Database().backend
+	style := backend.UpdateJoinStyle()
+	if style == UPDATE_JOIN_NOT_SUPPORTED || !backend.CanUpdate() {
+		return "", nil, errors.Wrapf(ErrNotSupported, "update join of backend %s", backend.Name())
+	}
+	if len(uj.sets) == 0 {
+		return "", nil, ErrNoDataToUpdate
+	}
+	if len(uj.joins) == 0 {
+		return "", nil, errors.Wrap(ErrEmptyQuery, "no joined table")
+	}
+
+	// the columns of the updated table are qualified in JOIN style, which may be ambiguous otherwise
+	column := func(name string) string {
+		if style == UPDATE_JOIN_ON {
+			return fmt.Sprintf("%s.%s", ts.quoteIdentifier(ts.name), ts.quoteIdentifier(name))
+		}
+		return ts.quoteIdentifier(name)
+	}
+	colsets := make([]string, 0)
+	setVars := make([]interface{}, 0)
+	for _, s := range uj.sets {
+		col := ts.ColumnSpec(s.name)
+		if col == nil {
+			return "", nil, errors.Wrapf(errors.ErrNotFound, "column %s", s.name)
+		}
+		switch v := s.value.(type) {
+		case IQueryField:
+			colsets = append(colsets, fmt.Sprintf("%s = %s", column(s.name), v.Reference()))
+			setVars = append(setVars, v.Variables()...)
+		case nil:
+			colsets = append(colsets, fmt.Sprintf("%s = NULL", column(s.name)))
+		default:
+			colsets = append(colsets, fmt.Sprintf("%s = ?", column(s.name)))
+			setVars = append(setVars, col.ConvertFromValue(v))
+		}
+	}
+	for _, col := range ts.Columns() {
+		if uj.isSet(col.Name()) || col.IsPrimary() {
+			continue
+		}
+		if col.IsAutoVersion() {
+			colsets = append(colsets, fmt.Sprintf("%s = %s + 1", column(col.Name()), column(col.Name())))
+		} else if col.IsUpdatedAt() {
+			colsets = append(colsets, fmt.Sprintf("%s = %s", column(col.Name()), backend.CurrentUTCTimeStampString()))
+		}
+	}
+
+	var buf bytes.Buffer
+	vars := make([]interface{}, 0)
+	buf.WriteString("UPDATE ")
+	buf.WriteString(ts.quoteIdentifier(ts.name))
+	where := uj.where
+	if style == UPDATE_JOIN_ON {
+		for _, join := range uj.joins {
+			buf.WriteString(fmt.Sprintf(" %s %s AS %s ON %s", join.jointype, join.from.Expression(), ts.quoteIdentifier(join.from.Alias()), join.condition.WhereClause()))
+			vars = append(vars, join.from.Variables()...)
+			vars = append(vars, join.condition.Variables()...)
+		}
+		buf.WriteString(" SET ")
+		buf.WriteString(strings.Join(colsets, ", "))
+		vars = append(vars, setVars...)
+	} else {
+		buf.WriteString(" SET ")
+		buf.WriteString(strings.Join(colsets, ", "))
+		vars = append(vars, setVars...)
+		buf.WriteString(" FROM ")
+		conds := make([]ICondition, 0)
+		for i, join := range uj.joins {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(fmt.Sprintf("%s AS %s", join.from.Expression(), ts.quoteIdentifier(join.from.Alias())))
+			vars = append(vars, join.from.Variables()...)
+			conds = append(conds, join.condition)
+		}
+		if where != nil {
+			conds = append(conds, where)
+		}
+		where = AND(conds...)
+	}
+	if where != nil {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(where.WhereClause())
+		vars = append(vars, where.Variables()...)
+	}
+
+	if DEBUG_SQLCHEMY {
+		log.Infof("Update: %s %s", buf.String(), vars)
+	}
+
+	return buf.String(), vars, nil
+}
+
+func (uj *SUpdateJoin) isSet(name string) bool {
+	for _, s := range uj.sets {
+		if s.name == name {
+			return true
+		}
+	}
+	return false
+}
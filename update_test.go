@@ -15,6 +15,7 @@
 package sqlchemy
 
 import (
+	"reflect"
 	"testing"
 	"time"
 )
@@ -106,3 +107,195 @@ func TestUpdatePrimaryKey(t *testing.T) {
 		t.Fatalf("Vars want %d got %d", wantVars, len(result.Vars))
 	}
 }
+
+func TestUpdateOptimisticLock(t *testing.T) {
+	SetupMockDatabaseBackend()
+
+	type LockedStruct struct {
+		Id        int       `json:"id" primary:"true"`
+		Name      string    `width:"16"`
+		UpdatedAt time.Time `updated_at:"true"`
+		Version   int64     `auto_version:"true" optimistic_lock:"true"`
+	}
+	cases := []struct {
+		name     string
+		table    *STableSpec
+		dt       interface{}
+		update   func(dt interface{})
+		want     string
+		wantVars []interface{}
+	}{
+		{
+			name:  "tag",
+			table: NewTableSpecFromStruct(LockedStruct{}, "testtable"),
+			dt:    &LockedStruct{Id: 12345, Name: "john", Version: 3},
+			update: func(dt interface{}) {
+				dt.(*LockedStruct).Name = "johny"
+			},
+			want:     "UPDATE `testtable` SET `name` = ?, `version` = `version` + 1, `updated_at` = UTC_NOW() WHERE `id` = ? AND `version` = ?",
+			wantVars: []interface{}{"johny", 12345, int64(3)},
+		},
+		{
+			name: "table option",
+			table: func() *STableSpec {
+				ts := NewTableSpecFromStruct(TableStruct{}, "testtable")
+				ts.SetExtraOptions(TableExtraOptions{EXTRA_OPTION_OPTIMISTIC_LOCK_KEY: "true"})
+				return ts
+			}(),
+			dt: &TableStruct{Id: 12345, Name: "john", Version: 7},
+			update: func(dt interface{}) {
+				dt.(*TableStruct).Name = "johny"
+			},
+			want:     "UPDATE `testtable` SET `name` = ?, `version` = `version` + 1, `updated_at` = UTC_NOW() WHERE `id` = ? AND `version` = ?",
+			wantVars: []interface{}{"johny", 12345, int64(7)},
+		},
+	}
+	for _, c := range cases {
+		session, err := c.table.PrepareUpdate(c.dt)
+		if err != nil {
+			t.Fatalf("%s: prepareUpdate fail %s", c.name, err)
+		}
+		c.update(c.dt)
+		result, err := session.SaveUpdateSql(c.dt)
+		if err != nil {
+			t.Fatalf("%s: saveUpdateSql fail %s", c.name, err)
+		}
+		if c.want != result.Sql {
+			t.Errorf("%s: SQL want %s got %s", c.name, c.want, result.Sql)
+		}
+		if !reflect.DeepEqual(c.wantVars, result.Vars) {
+			t.Errorf("%s: Vars want %#v got %#v", c.name, c.wantVars, result.Vars)
+		}
+	}
+}
+
+func TestUpdateWhereSQL(t *testing.T) {
+	SetupMockDatabaseBackend()
+
+	table := NewTableSpecFromStruct(TableStruct{}, "testtable")
+	target := table.Target()
+	cases := []struct {
+		cond     ICondition
+		set      map[string]interface{}
+		want     string
+		wantVars []interface{}
+	}{
+		{
+			cond: OR(LT(target.Field("age"), 10), Like(target.Field("name"), "j%")),
+			set: map[string]interface{}{
+				"name":    "john",
+				"is_male": nil,
+			},
+			want:     "UPDATE `testtable` SET `is_male` = NULL, `name` = ?, `updated_at` = UTC_NOW(), `version` = `version` + 1 WHERE (`testtable`.`age` <  ? ) OR (`testtable`.`name` LIKE  ? )",
+			wantVars: []interface{}{"john", 10, "j%"},
+		},
+		{
+			cond: Between(target.Field("id"), 1, 100),
+			set: map[string]interface{}{
+				"age": MUL("", target.Field("age"), NewConstField(2)),
+			},
+			want:     "UPDATE `testtable` SET `age` = `testtable`.`age` * 2, `updated_at` = UTC_NOW(), `version` = `version` + 1 WHERE `testtable`.`id` BETWEEN  ?  AND  ? ",
+			wantVars: []interface{}{1, 100},
+		},
+	}
+	for _, c := range cases {
+		result, err := table.UpdateWhereSql(c.cond, c.set)
+		if err != nil {
+			t.Fatalf("UpdateWhereSql fail %s", err)
+		}
+		if result.Sql != c.want {
+			t.Errorf("SQL: want %s got %s", c.want, result.Sql)
+		}
+		if !reflect.DeepEqual(result.Vars, c.wantVars) {
+			t.Errorf("Vars: want %#v got %#v", c.wantVars, result.Vars)
+		}
+	}
+
+	_, err := table.UpdateWhereSql(nil, map[string]interface{}{"no_such_column": 1})
+	if err == nil {
+		t.Errorf("unknown column should fail")
+	}
+}
+
+func TestDeleteWhereSQL(t *testing.T) {
+	SetupMockDatabaseBackend()
+	ResetTableID()
+
+	type TableStruct struct {
+		Id   int    `json:"id" primary:"true"`
+		Name string `width:"16"`
+		Age  int    `nullable:"true"`
+	}
+	table := NewTableSpecFromStruct(TableStruct{}, "testtable")
+	other := NewTableSpecFromStruct(TableStruct{}, "othertable").Instance()
+	target := table.Target()
+	cases := []struct {
+		cond     ICondition
+		want     string
+		wantVars []interface{}
+	}{
+		{
+			cond:     AND(GE(target.Field("age"), 18), In(target.Field("name"), []string{"a", "b"})),
+			want:     "DELETE FROM `testtable` WHERE (`testtable`.`age` >=  ? ) AND (`testtable`.`name` IN ( ?, ? ))",
+			wantVars: []interface{}{18, "a", "b"},
+		},
+		{
+			cond:     Exists(other.Query(other.Field("id")).Filter(Equals(other.Field("id"), target.Field("id")))),
+			want:     "DELETE FROM `testtable` WHERE EXISTS (SELECT `t1`.`id` FROM `othertable` AS `t1` WHERE `t1`.`id` = `testtable`.`id`)",
+			wantVars: []interface{}{},
+		},
+		{
+			want: "DELETE FROM `testtable` WHERE 1",
+		},
+	}
+	for _, c := range cases {
+		sql, vars := table.DeleteWhereSql(c.cond)
+		if sql != c.want {
+			t.Errorf("SQL: want %s got %s", c.want, sql)
+		}
+		if !reflect.DeepEqual(vars, c.wantVars) {
+			t.Errorf("Vars: want %#v got %#v", c.wantVars, vars)
+		}
+	}
+}
+
+func TestUpdateJoinSQL(t *testing.T) {
+	SetupMockDatabaseBackend()
+	ResetTableID()
+
+	type ProjectStruct struct {
+		Id   string `width:"36" primary:"true"`
+		Name string `width:"64"`
+	}
+	type ResourceStruct struct {
+		Id          string    `width:"36" primary:"true"`
+		ProjectId   string    `width:"36"`
+		ProjectName string    `width:"64"`
+		UpdatedAt   time.Time `updated_at:"true"`
+	}
+	projects := NewTableSpecFromStruct(ProjectStruct{}, "projects_tbl")
+	resources := NewTableSpecFromStruct(ResourceStruct{}, "resources_tbl")
+
+	p := projects.Instance()
+	target := resources.Target()
+	sql, vars, err := resources.UpdateJoin().
+		Join(p, Equals(p.Field("id"), target.Field("project_id"))).
+		Set("project_name", p.Field("name")).
+		Filter(NotEquals(p.Field("name"), "")).
+		Sql()
+	if err != nil {
+		t.Fatalf("UpdateJoin fail %s", err)
+	}
+	want := "UPDATE `resources_tbl` JOIN `projects_tbl` AS `t1` ON `t1`.`id` = `resources_tbl`.`project_id` SET `resources_tbl`.`project_name` = `t1`.`name`, `resources_tbl`.`updated_at` = UTC_NOW() WHERE `t1`.`name` <>  ? "
+	if sql != want {
+		t.Errorf("SQL: want %s got %s", want, sql)
+	}
+	if !reflect.DeepEqual(vars, []interface{}{""}) {
+		t.Errorf("Vars: want [\"\"] got %#v", vars)
+	}
+
+	_, _, err = resources.UpdateJoin().Set("project_name", "x").Sql()
+	if err == nil {
+		t.Errorf("update join without joined table should fail")
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlchemy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SWindowOrder is an item of the ORDER BY list in the OVER clause of a window function
+type SWindowOrder struct {
+	field IQueryField
+	order QueryOrderType
+}
+
+// WindowAsc orders the rows of a window partition in ascending order of the field
+func WindowAsc(field IQueryField) SWindowOrder {
+	return SWindowOrder{field: field, order: SQL_ORDER_ASC}
+}
+
+// WindowDesc orders the rows of a window partition in descending order of the field
+func WindowDesc(field IQueryField) SWindowOrder {
+	return SWindowOrder{field: field, order: SQL_ORDER_DESC}
+}
+
+// SWindow represents the OVER clause of a window function, i.e.
+//
+//	OVER (PARTITION BY ... ORDER BY ... frame)
+type SWindow struct {
+	partitionBy []IQueryField
+	orderBy     []SWindowOrder
+	frame       string
+}
+
+// Over returns a window specification, any of partitionBy, orderBy and frame can be empty.
+// frame is the frame clause, e.g. ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW
+func Over(partitionBy []IQueryField, orderBy []SWindowOrder, frame string) *SWindow {
+	return &SWindow{
+		partitionBy: partitionBy,
+		orderBy:     orderBy,
+		frame:       frame,
+	}
+}
+
+// Frame returns the frame clause of the window
+func (w *SWindow) Frame() string {
+	return w.frame
+}
+
+// WithFrame returns a copy of the window with the given frame clause
+func (w *SWindow) WithFrame(frame string) *SWindow {
+	nw := *w
+	nw.frame = frame
+	return &nw
+}
+
+func (w *SWindow) fields() []IQueryField {
+	fields := make([]IQueryField, 0, len(w.partitionBy)+len(w.orderBy))
+	fields = append(fields, w.partitionBy...)
+	for i := range w.orderBy {
+		fields = append(fields, w.orderBy[i].field)
+	}
+	return fields
+}
+
+// clause returns the format string of the OVER clause, the fields are referenced by %s in the order of fields()
+func (w *SWindow) clause() string {
+	parts := make([]string, 0, 3)
+	if len(w.partitionBy) > 0 {
+		refs := make([]string, len(w.partitionBy))
+		for i := range refs {
+			refs[i] = "%s"
+		}
+		parts = append(parts, "PARTITION BY "+strings.Join(refs, ", "))
+	}
+	if len(w.orderBy) > 0 {
+		refs := make([]string, len(w.orderBy))
+		for i := range w.orderBy {
+			refs[i] = fmt.Sprintf("%%s %s", w.orderBy[i].order)
+		}
+		parts = append(parts, "ORDER BY "+strings.Join(refs, ", "))
+	}
+	if len(w.frame) > 0 {
+		parts = append(parts, strings.ReplaceAll(w.frame, "%", "%%"))
+	}
+	return "OVER (" + strings.Join(parts, " ") + ")"
+}
+
+// NewWindowFunctionField returns an instance of query field by calling a SQL window function over the window,
+// the fields are referenced by %s in funcexp
+func NewWindowFunctionField(name string, funcexp string, over *SWindow, fields ...IQueryField) IQueryField {
+	if over == nil {
+		over = Over(nil, nil, "")
+	}
+	allFields := make([]IQueryField, 0, len(fields)+len(over.partitionBy)+len(over.orderBy))
+	allFields = append(allFields, fields...)
+	allFields = append(allFields, over.fields()...)
+	return NewFunctionField(name, funcexp+" "+over.clause(), allFields...)
+}
+
+func getWindowBackend(over *SWindow, fields ...IQueryField) IBackend {
+	if over != nil {
+		fields = append(fields, over.fields()...)
+	}
+	return getFieldBackend(fields...)
+}
+
+// ROW_NUMBER represents the SQL window function ROW_NUMBER
+func ROW_NUMBER(name string, over *SWindow) IQueryField {
+	return getWindowBackend(over).ROW_NUMBER(name, over)
+}
+
+// RANK represents the SQL window function RANK
+func RANK(name string, over *SWindow) IQueryField {
+	return getWindowBackend(over).RANK(name, over)
+}
+
+// DENSE_RANK represents the SQL window function DENSE_RANK
+func DENSE_RANK(name string, over *SWindow) IQueryField {
+	return getWindowBackend(over).DENSE_RANK(name, over)
+}
+
+// LAG represents the SQL window function LAG, which returns the value of the field offset rows before the current row
+func LAG(name string, field IQueryField, offset int, over *SWindow) IQueryField {
+	return getWindowBackend(over, field).LAG(name, field, offset, over)
+}
+
+// LEAD represents the SQL window function LEAD, which returns the value of the field offset rows after the current row
+func LEAD(name string, field IQueryField, offset int, over *SWindow) IQueryField {
+	return getWindowBackend(over, field).LEAD(name, field, offset, over)
+}
+
+// FIRST_VALUE represents the SQL window function FIRST_VALUE
+func FIRST_VALUE(name string, field IQueryField, over *SWindow) IQueryField {
+	return getWindowBackend(over, field).FIRST_VALUE(name, field, over)
+}
+
+// SUM_OVER represents the SQL aggregate function SUM over a window, e.g. a running sum
+func SUM_OVER(name string, field IQueryField, over *SWindow) IQueryField {
+	return getWindowBackend(over, field).SUM_OVER(name, field, over)
+}